@@ -13,7 +13,22 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const UploadCmdName = "upload"
+const (
+	UploadCmdName   = "upload"
+	SimulateCmdName = "simulate" // simulates an upload, with the upload flags
+)
+
+// DefaultBannedFiles lists the file name patterns excluded by default from the import process.
+var DefaultBannedFiles = []string{
+	`@eaDir/`,
+	`@__thumb/`,          // QNAP
+	`SYNOFILE_THUMB_*.*`, // SYNOLOGY
+	`Lightroom Catalog/`, // LR
+	`thumbnails/`,        // Android photo
+	`.DS_Store/`,         // Mac OS custom attributes
+	`/._*`,               // MacOS resource files
+	`.photostructure/`,   // PhotoStructure
+}
 
 // ImportFolderOptions represents the flags used for importing assets from a file system.
 type ImportFolderOptions struct {
@@ -95,16 +110,7 @@ func (o *ImportFolderOptions) AddFromFolderFlags(cmd *cobra.Command, parent *cob
 	o.Recursive = true
 	o.SupportedMedia = filetypes.DefaultSupportedMedia
 	o.UsePathAsAlbumName = FolderModeNone
-	o.BannedFiles, _ = namematcher.New(
-		`@eaDir/`,
-		`@__thumb/`,          // QNAP
-		`SYNOFILE_THUMB_*.*`, // SYNOLOGY
-		`Lightroom Catalog/`, // LR
-		`thumbnails/`,        // Android photo
-		`.DS_Store/`,         // Mac OS custom attributes
-		`/._*`,               // MacOS resource files
-		`.photostructure/`,   // PhotoStructure
-	)
+	o.BannedFiles, _ = namematcher.New(DefaultBannedFiles...)
 
 	o.ICloudTakeout = false
 	o.PicasaAlbum = false
@@ -125,7 +131,7 @@ func (o *ImportFolderOptions) AddFromFolderFlags(cmd *cobra.Command, parent *cob
 	// exif.AddExifToolFlags(cmd, &o.ExifToolFlags) // disabled for now
 
 	// upload specific flags, not for archive to folder
-	if parent != nil && parent.Name() == UploadCmdName {
+	if parent != nil && (parent.Name() == UploadCmdName || parent.Name() == SimulateCmdName) {
 		cmd.Flags().Var(&o.ManageHEICJPG, "manage-heic-jpeg", "Manage coupled HEIC and JPEG files. Possible values: NoStack, KeepHeic, KeepJPG, StackCoverHeic, StackCoverJPG")
 		cmd.Flags().Var(&o.ManageRawJPG, "manage-raw-jpeg", "Manage coupled RAW and JPEG files. Possible values: NoStack, KeepRaw, KeepJPG, StackCoverRaw, StackCoverJPG")
 		cmd.Flags().Var(&o.ManageBurst, "manage-burst", "Manage burst photos. Possible values: NoStack, Stack, StackKeepRaw, StackKeepJPEG")
@@ -142,16 +148,7 @@ func (o *ImportFolderOptions) AddFromICloudFlags(cmd *cobra.Command, parent *cob
 	o.Recursive = true
 	o.SupportedMedia = filetypes.DefaultSupportedMedia
 	o.UsePathAsAlbumName = FolderModeNone
-	o.BannedFiles, _ = namematcher.New(
-		`@eaDir/`,
-		`@__thumb/`,          // QNAP
-		`SYNOFILE_THUMB_*.*`, // SYNOLOGY
-		`Lightroom Catalog/`, // LR
-		`thumbnails/`,        // Android photo
-		`.DS_Store/`,         // Mac OS custom attributes
-		`/._*`,               // MacOS resource files
-		`.photostructure/`,   // PhotoStructure
-	)
+	o.BannedFiles, _ = namematcher.New(DefaultBannedFiles...)
 
 	o.ICloudTakeout = true
 	o.PicasaAlbum = false
@@ -178,16 +175,7 @@ func (o *ImportFolderOptions) AddFromPicasaFlags(cmd *cobra.Command, parent *cob
 	o.Recursive = true
 	o.SupportedMedia = filetypes.DefaultSupportedMedia
 	o.UsePathAsAlbumName = FolderModeNone
-	o.BannedFiles, _ = namematcher.New(
-		`@eaDir/`,
-		`@__thumb/`,          // QNAP
-		`SYNOFILE_THUMB_*.*`, // SYNOLOGY
-		`Lightroom Catalog/`, // LR
-		`thumbnails/`,        // Android photo
-		`.DS_Store/`,         // Mac OS custom attributes
-		`/._*`,               // MacOS resource files
-		`.photostructure/`,   // PhotoStructure
-	)
+	o.BannedFiles, _ = namematcher.New(DefaultBannedFiles...)
 
 	o.ICloudTakeout = false
 	o.PicasaAlbum = true
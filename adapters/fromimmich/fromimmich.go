@@ -27,7 +27,15 @@ type FromImmich struct {
 
 func NewFromImmich(ctx context.Context, app *app.Application, jnl *fileevent.Recorder, flags *FromImmichFlags) (*FromImmich, error) {
 	client := &flags.client
-	err := client.Initialize(ctx, app)
+	changed := flags.changed
+	if changed == nil {
+		changed = func(string) bool { return false }
+	}
+	err := client.ApplyProfile(changed)
+	if err != nil {
+		return nil, err
+	}
+	err = client.Initialize(ctx, app)
 	if err != nil {
 		return nil, err
 	}
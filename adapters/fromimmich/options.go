@@ -19,6 +19,7 @@ type FromImmichFlags struct {
 	Make           string                  // get only assets with this make
 	Model          string                  // get only assets with this model
 	client         app.Client              // client to use for the import
+	changed        func(flag string) bool  // tells if a flag of the client is given on the command line
 	InclusionFlags cliflags.InclusionFlags // controls the file extensions to be included in the import process.
 }
 
@@ -36,7 +37,11 @@ func (o *FromImmichFlags) AddFromImmichFlags(cmd *cobra.Command, parent *cobra.C
 	cmd.Flags().StringVar(&o.client.Server, "from-server", o.client.Server, "Immich server address (example http://your-ip:2283 or https://your-domain)")
 	cmd.Flags().StringVar(&o.client.APIKey, "from-api-key", "", "API Key")
 	cmd.Flags().BoolVar(&o.client.APITrace, "from-api-trace", false, "Enable trace of api calls")
+	cmd.Flags().StringVar(&o.client.APIRecord, "from-api-record", "", "Record the API calls and the server's responses in this file, to replay them with --from-api-replay")
+	cmd.Flags().StringVar(&o.client.APIReplay, "from-api-replay", "", "Replay the server's responses recorded in this file, without connecting to the server")
 	cmd.Flags().BoolVar(&o.client.SkipSSL, "from-skip-verify-ssl", false, "Skip SSL verification")
 	cmd.Flags().DurationVar(&o.client.ClientTimeout, "from-client-timeout", 5*time.Minute, "Set server calls timeout")
+	o.client.AddNetworkFlags(cmd.Flags(), "from-", "")
+	o.changed = func(flag string) bool { return cmd.Flags().Changed("from-" + flag) }
 	cliflags.AddInclusionFlags(cmd, &o.InclusionFlags)
 }
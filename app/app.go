@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"log/slog"
 	"time"
 
 	"github.com/simulot/immich-go/internal/fileevent"
@@ -35,6 +36,17 @@ func New(ctx context.Context, cmd *cobra.Command) *Application {
 	return app
 }
 
+// NewWithLogger creates an application context without command line flags.
+// It's used when immich-go is embedded into another program.
+func NewWithLogger(logger *slog.Logger) *Application {
+	app := &Application{
+		log: &Log{Logger: logger},
+		tz:  time.Local,
+	}
+	app.jnl = fileevent.NewRecorder(logger)
+	return app
+}
+
 func (app *Application) GetTZ() *time.Location {
 	if app.tz == nil {
 		app.tz = time.Local
@@ -15,34 +15,64 @@ import (
 	cliflags "github.com/simulot/immich-go/internal/cliFlags"
 	"github.com/simulot/immich-go/internal/configuration"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // add server flags to the command cmd
 func AddClientFlags(ctx context.Context, cmd *cobra.Command, app *Application, dryRun bool) {
+	addClientFlags(cmd, app, dryRun)
+	cmd.PersistentPreRunE = ChainRunEFunctions(cmd.PersistentPreRunE, OpenClient, ctx, cmd, app)
+	cmd.PersistentPostRunE = ChainRunEFunctions(cmd.PersistentPostRunE, CloseClient, ctx, cmd, app)
+}
+
+// AddClientFlagsWithoutConnection adds the server flags to the command cmd, but leaves the
+// connection to the command. Used by the commands diagnosing the connection.
+func AddClientFlagsWithoutConnection(ctx context.Context, cmd *cobra.Command, app *Application) {
+	addClientFlags(cmd, app, false)
+	cmd.PersistentPreRunE = ChainRunEFunctions(cmd.PersistentPreRunE, PrepareClient, ctx, cmd, app)
+	cmd.PersistentPostRunE = ChainRunEFunctions(cmd.PersistentPostRunE, CloseClient, ctx, cmd, app)
+}
+
+func addClientFlags(cmd *cobra.Command, app *Application, dryRun bool) {
 	client := app.Client()
 	client.DeviceUUID, _ = os.Hostname()
 
 	cmd.PersistentFlags().StringVarP(&client.Server, "server", "s", client.Server, "Immich server address (example http://your-ip:2283 or https://your-domain)")
 	cmd.PersistentFlags().StringVarP(&client.APIKey, "api-key", "k", "", "API Key")
 	cmd.PersistentFlags().BoolVar(&client.APITrace, "api-trace", false, "Enable trace of api calls")
+	cmd.PersistentFlags().StringVar(&client.APIRecord, "api-record", "", "Record the API calls and the server's responses in this file, to replay them with --api-replay")
+	cmd.PersistentFlags().StringVar(&client.APIReplay, "api-replay", "", "Replay the server's responses recorded in this file, without connecting to the server")
 	cmd.PersistentFlags().BoolVar(&client.SkipSSL, "skip-verify-ssl", false, "Skip SSL verification")
 	cmd.PersistentFlags().DurationVar(&client.ClientTimeout, "client-timeout", 5*time.Minute, "Set server calls timeout")
 	cmd.PersistentFlags().StringVar(&client.DeviceUUID, "device-uuid", client.DeviceUUID, "Set a device UUID")
 	cmd.PersistentFlags().BoolVar(&client.DryRun, "dry-run", dryRun, "Simulate all actions")
 	cmd.PersistentFlags().StringVar(&client.TimeZone, "time-zone", client.TimeZone, "Override the system time zone")
 	cmd.PersistentFlags().Var(&client.OnServerErrors, "on-server-errors", "Action to take on server errors, (stop|continue| <n> errors)")
+	client.AddNetworkFlags(cmd.PersistentFlags(), "", configuration.DefaultProfile)
+}
 
-	cmd.PersistentPreRunE = ChainRunEFunctions(cmd.PersistentPreRunE, OpenClient, ctx, cmd, app)
-	cmd.PersistentPostRunE = ChainRunEFunctions(cmd.PersistentPostRunE, CloseClient, ctx, cmd, app)
+// AddNetworkFlags adds the flags of the profile, and of the network settings. The prefix tells apart the flags of a second client.
+// No profile is used when the default profile is empty.
+func (client *Client) AddNetworkFlags(flags *pflag.FlagSet, prefix string, profile string) {
+	client.ConfigFile = configuration.DefaultConfigFile()
+	flags.StringVar(&client.Profile, prefix+"profile", profile, "Profile of the configuration file giving the connection settings")
+	flags.StringVar(&client.ConfigFile, prefix+"config-file", client.ConfigFile, "Configuration file holding the profiles")
+	flags.StringArrayVar(&client.Headers, prefix+"header", nil, "Header added to each request, as \"Name: value\". Can be specified multiple times")
+	flags.StringVar(&client.Proxy, prefix+"proxy", "", "Proxy address (example http://proxy:3128 or socks5://localhost:1080)")
+	flags.StringVar(&client.CACert, prefix+"ca-cert", "", "PEM file of certificate authorities to trust in addition to the system ones")
+	flags.StringVar(&client.ClientCert, prefix+"client-cert", "", "PEM file of the client certificate for the mutual TLS authentication")
+	flags.StringVar(&client.ClientKey, prefix+"client-key", "", "PEM file of the client certificate's key")
 }
 
-func OpenClient(ctx context.Context, cmd *cobra.Command, app *Application) error {
+// PrepareClient checks the client flags and plugs the journal on the log, without connecting the server
+func PrepareClient(ctx context.Context, cmd *cobra.Command, app *Application) error {
 	var err error
 	client := app.Client()
 	log := app.Log()
 
-	if client.Server != "" {
-		client.Server = strings.TrimSuffix(client.Server, "/")
+	err = client.ApplyProfile(cmd.Flags().Changed)
+	if err != nil {
+		return err
 	}
 	if client.TimeZone != "" {
 		// Load the specified timezone
@@ -72,6 +102,16 @@ func OpenClient(ctx context.Context, cmd *cobra.Command, app *Application) error
 			client.APITraceWriterName = strings.TrimSuffix(log.File, filepath.Ext(log.File)) + ".trace.log"
 		}
 	}
+	return nil
+}
+
+func OpenClient(ctx context.Context, cmd *cobra.Command, app *Application) error {
+	client := app.Client()
+
+	err := PrepareClient(ctx, cmd, app)
+	if err != nil {
+		return err
+	}
 
 	err = client.Initialize(ctx, app)
 	if err != nil {
@@ -96,12 +136,29 @@ func OpenClient(ctx context.Context, cmd *cobra.Command, app *Application) error
 	return nil
 }
 
+// RequireCapabilities returns a hook checking that the server has the capabilities used by the command,
+// to report an old server before doing anything
+func RequireCapabilities(capabilities ...immich.Capability) RunEAdaptor {
+	return func(ctx context.Context, cmd *cobra.Command, app *Application) error {
+		for _, c := range capabilities {
+			if err := app.Client().Immich.Require(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
 func CloseClient(ctx context.Context, cmd *cobra.Command, app *Application) error {
 	if app.Client() != nil {
 		if app.Client().APITraceWriter != nil {
 			app.Client().APITraceWriter.Close()
 			app.log.Message("Check the API-TRACE file: %s", app.Client().APITraceWriterName)
 		}
+		if app.Client().APIRecordWriter != nil {
+			app.Client().APIRecordWriter.Close()
+			app.log.Message("The API calls are recorded in the file: %s", app.Client().APIRecord)
+		}
 		return app.Client().Close()
 	}
 	return nil
@@ -124,18 +181,84 @@ type Client struct {
 	ClientLog          *slog.Logger                // Logger
 	OnServerErrors     cliflags.OnServerErrorsFlag // Behavior on server errors
 	User               immich.User                 // User info corresponding to the API key
+	Profile            string                      // Profile giving the connection settings
+	ConfigFile         string                      // Configuration file holding the profiles
+	Headers            []string                    // Headers added to each request, as "Name: value"
+	Proxy              string                      // Proxy address
+	CACert             string                      // Certificate authorities to trust
+	ClientCert         string                      // Client certificate for the mutual TLS authentication
+	ClientKey          string                      // Client certificate's key
+	AccessToken        string                      // Session token of the profile, used without API key
+	APIRecord          string                      // File recording the API calls
+	APIRecordWriter    io.WriteCloser              // API recorder
+	APIReplay          string                      // File of recorded API calls to replay instead of calling the server
+}
+
+// ApplyProfile uses the settings of the profile for the flags not given.
+// The function changed tells if a flag is given on the command line.
+func (client *Client) ApplyProfile(changed func(flag string) bool) error {
+	if client.ConfigFile != "" && client.Profile != "" {
+		p, err := configuration.ReadProfile(client.ConfigFile, client.Profile)
+		if err != nil {
+			return err
+		}
+		set := func(flag string, v *string, pv string) {
+			if !changed(flag) && pv != "" {
+				*v = pv
+			}
+		}
+		set("server", &client.Server, p.Server)
+		set("api-key", &client.APIKey, p.APIKey)
+		set("proxy", &client.Proxy, p.Proxy)
+		set("ca-cert", &client.CACert, p.CACert)
+		set("client-cert", &client.ClientCert, p.ClientCert)
+		set("client-key", &client.ClientKey, p.ClientKey)
+		client.AccessToken = p.AccessToken
+		if !changed("skip-verify-ssl") && p.SkipSSL {
+			client.SkipSSL = true
+		}
+		// the headers of the profile come first, the ones of the command line replace them
+		headers := make([]string, 0, len(p.Headers)+len(client.Headers))
+		for k, v := range p.Headers {
+			headers = append(headers, k+": "+v)
+		}
+		client.Headers = append(headers, client.Headers...)
+	}
+	client.Server = strings.TrimSuffix(client.Server, "/")
+	_, err := client.TransportOptions()
+	return err
+}
+
+// TransportOptions gives the network settings of the client
+func (client *Client) TransportOptions() (immich.TransportOptions, error) {
+	o := immich.TransportOptions{
+		SkipVerify: client.SkipSSL,
+		CACert:     client.CACert,
+		ClientCert: client.ClientCert,
+		ClientKey:  client.ClientKey,
+		Proxy:      client.Proxy,
+		Headers:    map[string]string{},
+	}
+	for _, h := range client.Headers {
+		k, v, err := immich.ParseHeader(h)
+		if err != nil {
+			return o, err
+		}
+		o.Headers[k] = v
+	}
+	return o, nil
 }
 
 func (client *Client) Initialize(ctx context.Context, app *Application) error {
 	var joinedErr error
 
-	// If the client isn't yet initialized
-	if client.Immich == nil {
+	// If the client isn't yet initialized. The replay doesn't need the server.
+	if client.Immich == nil && client.APIReplay == "" {
 		if client.Server == "" {
 			joinedErr = errors.Join(joinedErr, errors.New("missing the parameter --server, Immich server address (http://<your-ip>:2283 or https://<your-domain>)"))
 		}
-		if client.APIKey == "" {
-			joinedErr = errors.Join(joinedErr, errors.New("missing the parameter --api-key, Immich API key"))
+		if client.APIKey == "" && client.AccessToken == "" {
+			joinedErr = errors.Join(joinedErr, errors.New("missing the parameter --api-key, Immich API key, or a session opened with the login command"))
 		}
 
 		if client.APITrace {
@@ -149,17 +272,42 @@ func (client *Client) Initialize(ctx context.Context, app *Application) error {
 	return nil
 }
 
-func (client *Client) Open(ctx context.Context) error {
+// Connect creates the Immich client, without calling the server
+func (client *Client) Connect() error {
 	var err error
 
 	client.ClientLog.Info("Connection to the server " + client.Server)
-	client.Immich, err = immich.NewImmichClient(
-		client.Server,
-		client.APIKey,
-		immich.OptionVerifySSL(client.SkipSSL),
+	transport, err := client.TransportOptions()
+	if err != nil {
+		return err
+	}
+	options := []immich.ClientOption{
+		immich.OptionTransport(transport),
+		immich.OptionAccessToken(client.AccessToken),
 		immich.OptionConnectionTimeout(client.ClientTimeout),
 		immich.OptionDryRun(client.DryRun),
-	)
+	}
+	server := client.Server
+	if client.APIReplay != "" {
+		f, err := os.Open(client.APIReplay)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		options = append(options, immich.OptionAPIReplay(f))
+		if server == "" {
+			server = "http://api-replay"
+		}
+		client.ClientLog.Info("Replay of the API calls recorded in " + client.APIReplay)
+	}
+	if client.APIRecord != "" && client.APIRecordWriter == nil {
+		client.APIRecordWriter, err = os.Create(client.APIRecord)
+		if err != nil {
+			return err
+		}
+		options = append(options, immich.OptionAPIRecord(client.APIRecordWriter))
+	}
+	client.Immich, err = immich.NewImmichClient(server, client.APIKey, options...)
 	if err != nil {
 		return err
 	}
@@ -177,6 +325,14 @@ func (client *Client) Open(ctx context.Context) error {
 			client.Immich.EnableAppTrace(client.APITraceWriter)
 		}
 	}
+	return nil
+}
+
+func (client *Client) Open(ctx context.Context) error {
+	err := client.Connect()
+	if err != nil {
+		return err
+	}
 
 	err = client.Immich.PingServer(ctx)
 	if err != nil {
@@ -190,11 +346,14 @@ func (client *Client) Open(ctx context.Context) error {
 	}
 	client.User = user
 
-	about, err := client.Immich.GetAboutInfo(ctx)
+	about, err := client.Immich.NegotiateCapabilities(ctx)
 	if err != nil {
 		return err
 	}
 	client.ClientLog.Info("Server information:", "version", about.Version)
+	if client.Immich.ServerVersion().IsZero() {
+		client.ClientLog.Warn("unknown server version, the API of the latest Immich server is used", "version", about.Version)
+	}
 
 	client.ClientLog.Info(fmt.Sprintf("Connected, user: %s, ID: %s", user.Email, user.ID))
 	if client.DryRun {
@@ -204,7 +363,7 @@ func (client *Client) Open(ctx context.Context) error {
 }
 
 func (client *Client) Close() error {
-	if client.DryRun {
+	if client.DryRun && client.ClientLog != nil {
 		client.ClientLog.Info("Dry-run mode enabled. No changes were made to the server.")
 	}
 	return nil
@@ -0,0 +1,375 @@
+package album
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/spf13/cobra"
+)
+
+// NewAlbumCommand adds the album command, to manage the albums of the server
+func NewAlbumCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "album",
+		Short: "Manage the albums of the server",
+	}
+	app.AddClientFlags(ctx, cmd, a, false)
+	cmd.TraverseChildren = true
+
+	cmd.AddCommand(newListCommand(ctx, a))
+	cmd.AddCommand(newRenameCommand(ctx, a))
+	cmd.AddCommand(newMergeCommand(ctx, a))
+	cmd.AddCommand(newDeleteEmptyCommand(ctx, a))
+	cmd.AddCommand(newExportCommand(ctx, a))
+	cmd.AddCommand(newImportCommand(ctx, a))
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		return errors.New("you must specify a subcommand to the album command")
+	}
+	return cmd
+}
+
+func newListCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the albums with their number of assets",
+		Args:  cobra.NoArgs,
+	}
+	cmd.SetContext(ctx)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		albums, err := getAlbums(cmd.Context(), a.Client().Immich)
+		if err != nil {
+			return err
+		}
+		for _, al := range albums {
+			fmt.Printf("%s %8d  %s\n", al.ID, al.AssetCount, al.AlbumName)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func newRenameCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rename <album> <new name>",
+		Short: "Rename an album",
+		Args:  cobra.ExactArgs(2),
+	}
+	cmd.SetContext(ctx)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		al, err := findAlbum(ctx, client, args[0])
+		if err != nil {
+			return err
+		}
+		_, err = client.UpdateAlbum(ctx, al.ID, immich.UpdAlbumField{AlbumName: args[1]})
+		if err != nil {
+			return fmt.Errorf("can't rename the album %q: %w", al.AlbumName, err)
+		}
+		a.Log().Message("Album %q renamed into %q", al.AlbumName, args[1])
+		return nil
+	}
+	return cmd
+}
+
+func newMergeCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge <album A> <album B>",
+		Short: "Move the assets of the album A into the album B, then delete the album A",
+		Args:  cobra.ExactArgs(2),
+	}
+	cmd.SetContext(ctx)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		from, err := findAlbum(ctx, client, args[0])
+		if err != nil {
+			return err
+		}
+		to, err := findAlbum(ctx, client, args[1])
+		if err != nil {
+			return err
+		}
+		if from.ID == to.ID {
+			return errors.New("can't merge an album with itself")
+		}
+		info, err := client.GetAlbumInfo(ctx, from.ID, false)
+		if err != nil {
+			return fmt.Errorf("can't get the album %q: %w", from.AlbumName, err)
+		}
+		ids := make([]string, len(info.Assets))
+		for i, as := range info.Assets {
+			ids[i] = as.ID
+		}
+		if len(ids) > 0 {
+			_, err = client.AddAssetToAlbum(ctx, to.ID, ids)
+			if err != nil {
+				return fmt.Errorf("can't add the assets to the album %q: %w", to.AlbumName, err)
+			}
+		}
+		err = client.DeleteAlbum(ctx, from.ID)
+		if err != nil {
+			return fmt.Errorf("can't delete the album %q: %w", from.AlbumName, err)
+		}
+		a.Log().Message("%d assets moved from the album %q to %q, album %q deleted", len(ids), from.AlbumName, to.AlbumName, from.AlbumName)
+		return nil
+	}
+	return cmd
+}
+
+func newDeleteEmptyCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete-empty",
+		Short: "Delete the albums without asset",
+		Args:  cobra.NoArgs,
+	}
+	cmd.SetContext(ctx)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		albums, err := getAlbums(ctx, client)
+		if err != nil {
+			return err
+		}
+		n := 0
+		for _, al := range albums {
+			if al.AssetCount > 0 {
+				continue
+			}
+			if err := client.DeleteAlbum(ctx, al.ID); err != nil {
+				a.Log().Error("can't delete the album", "album", al.AlbumName, "err", err)
+				continue
+			}
+			a.Log().Message("Album %q deleted", al.AlbumName)
+			n++
+		}
+		a.Log().Message("%d empty albums deleted", n)
+		return nil
+	}
+	return cmd
+}
+
+func newExportCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	var output, format string
+	cmd := &cobra.Command{
+		Use:   "export [album...]",
+		Short: "Export the list of the assets of the albums, with their file names and checksums",
+		Long:  `Export the list of the assets of the given albums, or of all albums, in CSV or JSON format`,
+	}
+	cmd.SetContext(ctx)
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the list into the file")
+	_ = cmd.MarkFlagRequired("output")
+	cmd.Flags().StringVar(&format, "format", "", "Format of the list: csv or json (default: guessed from the file extension, csv otherwise)")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		f, err := fileFormat(format, output)
+		if err != nil {
+			return err
+		}
+
+		var albums []immich.AlbumSimplified
+		if len(args) == 0 {
+			albums, err = getAlbums(ctx, client)
+			if err != nil {
+				return err
+			}
+		} else {
+			for _, name := range args {
+				al, err := findAlbum(ctx, client, name)
+				if err != nil {
+					return err
+				}
+				albums = append(albums, al)
+			}
+		}
+
+		list := make([]albumExport, 0, len(albums))
+		for _, al := range albums {
+			info, err := client.GetAlbumInfo(ctx, al.ID, false)
+			if err != nil {
+				return fmt.Errorf("can't get the album %q: %w", al.AlbumName, err)
+			}
+			e := albumExport{Album: info.AlbumName, Description: info.Description, Assets: []assetExport{}}
+			for _, as := range info.Assets {
+				e.Assets = append(e.Assets, assetExport{
+					ID:        as.ID,
+					FileName:  as.OriginalFileName,
+					Checksum:  as.Checksum,
+					DateTaken: as.ExifInfo.DateTimeOriginal.Time,
+				})
+			}
+			list = append(list, e)
+		}
+
+		fo, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		err = writeAlbums(fo, f, list)
+		err = errors.Join(err, fo.Close())
+		if err != nil {
+			return err
+		}
+		a.Log().Message("%d albums exported into %s", len(list), output)
+		return nil
+	}
+	return cmd
+}
+
+func newImportCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Recreate the albums listed in an export file, matching the assets by checksum",
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.SetContext(ctx)
+	cmd.Flags().StringVar(&format, "format", "", "Format of the list: csv or json (default: guessed from the file content)")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		log := a.Log()
+
+		if format != "" {
+			f, err := fileFormat(format, "")
+			if err != nil {
+				return err
+			}
+			format = f
+		}
+		fi, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		list, err := readAlbums(fi, format)
+		fi.Close()
+		if err != nil {
+			return err
+		}
+
+		// index the server's assets by checksum
+		byChecksum := map[string]string{}
+		err = client.GetAllAssetsWithFilter(ctx, &immich.SearchMetadataQuery{WithArchived: true}, func(as *immich.Asset) error {
+			if !as.IsTrashed && as.Checksum != "" {
+				byChecksum[as.Checksum] = as.ID
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		albums, err := getAlbums(ctx, client)
+		if err != nil {
+			return err
+		}
+		byName := map[string]immich.AlbumSimplified{}
+		for _, al := range albums {
+			if _, exists := byName[al.AlbumName]; !exists {
+				byName[al.AlbumName] = al
+			}
+		}
+
+		for _, e := range list {
+			ids := []string{}
+			missing := 0
+			seen := map[string]bool{}
+			for _, as := range e.Assets {
+				id, ok := byChecksum[as.Checksum]
+				if !ok {
+					missing++
+					log.Warn("asset not found on the server", "album", e.Album, "file", as.FileName, "checksum", as.Checksum)
+					continue
+				}
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+
+			al, exists := byName[e.Album]
+			if !exists {
+				_, err = client.CreateAlbum(ctx, e.Album, e.Description, ids)
+				if err != nil {
+					log.Error("can't create the album", "album", e.Album, "err", err)
+					continue
+				}
+				log.Message("Album %q created with %d assets, %d not found on the server", e.Album, len(ids), missing)
+				continue
+			}
+
+			info, err := client.GetAlbumInfo(ctx, al.ID, false)
+			if err != nil {
+				log.Error("can't get the album", "album", e.Album, "err", err)
+				continue
+			}
+			// skip the assets already in the album
+			for _, as := range info.Assets {
+				seen[as.ID] = false
+			}
+			toAdd := []string{}
+			for _, id := range ids {
+				if seen[id] {
+					toAdd = append(toAdd, id)
+				}
+			}
+			if len(toAdd) > 0 {
+				_, err = client.AddAssetToAlbum(ctx, al.ID, toAdd)
+				if err != nil {
+					log.Error("can't add the assets to the album", "album", e.Album, "err", err)
+					continue
+				}
+			}
+			log.Message("Album %q updated with %d assets, %d not found on the server", e.Album, len(toAdd), missing)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// getAlbums returns the albums sorted by name
+func getAlbums(ctx context.Context, client immich.ImmichInterface) ([]immich.AlbumSimplified, error) {
+	albums, err := client.GetAllAlbums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get the albums: %w", err)
+	}
+	sort.Slice(albums, func(i, j int) bool {
+		if albums[i].AlbumName != albums[j].AlbumName {
+			return albums[i].AlbumName < albums[j].AlbumName
+		}
+		return albums[i].ID < albums[j].ID
+	})
+	return albums, nil
+}
+
+// findAlbum finds an album by its ID or by its name. The name must be unique.
+func findAlbum(ctx context.Context, client immich.ImmichInterface, nameOrID string) (immich.AlbumSimplified, error) {
+	albums, err := getAlbums(ctx, client)
+	if err != nil {
+		return immich.AlbumSimplified{}, err
+	}
+	found := []immich.AlbumSimplified{}
+	for _, al := range albums {
+		if al.ID == nameOrID {
+			return al, nil
+		}
+		if al.AlbumName == nameOrID {
+			found = append(found, al)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return immich.AlbumSimplified{}, fmt.Errorf("album %q not found", nameOrID)
+	case 1:
+		return found[0], nil
+	}
+	return immich.AlbumSimplified{}, fmt.Errorf("%d albums are named %q, use the album ID instead", len(found), nameOrID)
+}
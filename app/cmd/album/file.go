@@ -0,0 +1,146 @@
+package album
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// albumExport is an album with the list of its assets, as written by the export sub-command
+type albumExport struct {
+	Album       string        `json:"album"`
+	Description string        `json:"description,omitempty"`
+	Assets      []assetExport `json:"assets"`
+}
+
+type assetExport struct {
+	ID        string    `json:"id"`
+	FileName  string    `json:"fileName"`
+	Checksum  string    `json:"checksum"` // base64 encoded SHA1 of the file, as given by the server
+	DateTaken time.Time `json:"dateTaken,omitzero"`
+}
+
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+var csvHeader = []string{"album", "description", "id", "fileName", "checksum", "dateTaken"}
+
+// fileFormat returns the format given by the flag, or guessed from the file name
+func fileFormat(flag string, name string) (string, error) {
+	switch strings.ToLower(flag) {
+	case formatCSV:
+		return formatCSV, nil
+	case formatJSON:
+		return formatJSON, nil
+	case "":
+		if strings.EqualFold(filepath.Ext(name), ".json") {
+			return formatJSON, nil
+		}
+		return formatCSV, nil
+	}
+	return "", fmt.Errorf("invalid format: %q, expected csv or json", flag)
+}
+
+// writeAlbums writes the albums in CSV or JSON format.
+// In CSV format, the album fields are repeated on each line. An empty album is written as a line without asset.
+func writeAlbums(w io.Writer, format string, albums []albumExport) error {
+	if format == formatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(albums)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, al := range albums {
+		if len(al.Assets) == 0 {
+			if err := cw.Write([]string{al.Album, al.Description, "", "", "", ""}); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, as := range al.Assets {
+			date := ""
+			if !as.DateTaken.IsZero() {
+				date = as.DateTaken.Format(time.RFC3339)
+			}
+			if err := cw.Write([]string{al.Album, al.Description, as.ID, as.FileName, as.Checksum, date}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readAlbums reads a file written by writeAlbums. When the format is empty,
+// it's guessed from the first character of the file.
+func readAlbums(r io.Reader, format string) ([]albumExport, error) {
+	br := bufio.NewReader(r)
+	if format == "" {
+		format = formatCSV
+		for {
+			b, err := br.Peek(1)
+			if err != nil {
+				break
+			}
+			if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+				_, _ = br.ReadByte()
+				continue
+			}
+			if b[0] == '[' {
+				format = formatJSON
+			}
+			break
+		}
+	}
+
+	if format == formatJSON {
+		var albums []albumExport
+		if err := json.NewDecoder(br).Decode(&albums); err != nil {
+			return nil, fmt.Errorf("can't read the album list: %w", err)
+		}
+		return albums, nil
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = len(csvHeader)
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("can't read the album list: %w", err)
+	}
+	albums := []albumExport{}
+	index := map[string]int{}
+	for i, rec := range records {
+		if i == 0 && rec[0] == csvHeader[0] {
+			continue
+		}
+		n, ok := index[rec[0]]
+		if !ok {
+			n = len(albums)
+			index[rec[0]] = n
+			albums = append(albums, albumExport{Album: rec[0], Description: rec[1], Assets: []assetExport{}})
+		}
+		if rec[2] == "" && rec[4] == "" {
+			continue
+		}
+		as := assetExport{ID: rec[2], FileName: rec[3], Checksum: rec[4]}
+		if rec[5] != "" {
+			as.DateTaken, err = time.Parse(time.RFC3339, rec[5])
+			if err != nil {
+				return nil, fmt.Errorf("can't read the album list, line %d: %w", i+1, err)
+			}
+		}
+		albums[n].Assets = append(albums[n].Assets, as)
+	}
+	return albums, nil
+}
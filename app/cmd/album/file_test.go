@@ -0,0 +1,65 @@
+package album
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlbumFileRoundTrip(t *testing.T) {
+	albums := []albumExport{
+		{
+			Album:       "Holidays, 2023",
+			Description: "Summer",
+			Assets: []assetExport{
+				{ID: "id1", FileName: "IMG_0001.JPG", Checksum: "c2hhMQ==", DateTaken: time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)},
+				{ID: "id2", FileName: "IMG_0002.JPG", Checksum: "c2hhMg=="},
+			},
+		},
+		{Album: "Empty", Assets: []assetExport{}},
+	}
+
+	for _, format := range []string{formatCSV, formatJSON} {
+		b := bytes.NewBuffer(nil)
+		if err := writeAlbums(b, format, albums); err != nil {
+			t.Fatal(err)
+		}
+		// the format is guessed when reading
+		got, err := readAlbums(b, "")
+		if err != nil {
+			t.Fatalf("%s: %s", format, err)
+		}
+		if !reflect.DeepEqual(got, albums) {
+			t.Errorf("%s: got %+v, want %+v", format, got, albums)
+		}
+	}
+}
+
+func TestReadAlbumsCSVErrors(t *testing.T) {
+	_, err := readAlbums(strings.NewReader("album,description,id\nA,,id1\n"), formatCSV)
+	if err == nil {
+		t.Error("expected an error for a line with missing fields")
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	tests := []struct {
+		flag, name, want string
+	}{
+		{"", "", formatCSV},
+		{"", "albums.JSON", formatJSON},
+		{"", "albums.csv", formatCSV},
+		{"json", "albums.csv", formatJSON},
+	}
+	for _, tt := range tests {
+		got, err := fileFormat(tt.flag, tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("fileFormat(%q, %q) = %q, %v, want %q", tt.flag, tt.name, got, err, tt.want)
+		}
+	}
+	if _, err := fileFormat("xml", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
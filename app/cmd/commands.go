@@ -4,8 +4,19 @@ import (
 	"context"
 
 	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/app/cmd/album"
 	"github.com/simulot/immich-go/app/cmd/archive"
+	"github.com/simulot/immich-go/app/cmd/debugbundle"
+	"github.com/simulot/immich-go/app/cmd/dedupe"
+	"github.com/simulot/immich-go/app/cmd/doctor"
+	"github.com/simulot/immich-go/app/cmd/fixdates"
+	"github.com/simulot/immich-go/app/cmd/geotag"
+	"github.com/simulot/immich-go/app/cmd/jobs"
+	"github.com/simulot/immich-go/app/cmd/login"
+	"github.com/simulot/immich-go/app/cmd/session"
 	"github.com/simulot/immich-go/app/cmd/stack"
+	"github.com/simulot/immich-go/app/cmd/stats"
+	"github.com/simulot/immich-go/app/cmd/tag"
 	"github.com/simulot/immich-go/app/cmd/upload"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -31,8 +42,23 @@ func RootImmichGoCommand(ctx context.Context) (*cobra.Command, *app.Application)
 	c.AddCommand(
 		app.NewVersionCommand(ctx, a),
 		upload.NewUploadCommand(ctx, a),
+		upload.NewUndoAfterUploadCommand(ctx, a),
+		upload.NewAuditCommand(ctx, a),
 		archive.NewArchiveCommand(ctx, a),
 		stack.NewStackCommand(ctx, a),
+		session.NewSessionCommand(ctx, a),
+		dedupe.NewDedupeCommand(ctx, a),
+		album.NewAlbumCommand(ctx, a),
+		tag.NewTagCommand(ctx, a),
+		fixdates.NewFixDatesCommand(ctx, a),
+		geotag.NewGeotagCommand(ctx, a),
+		jobs.NewJobsCommand(ctx, a),
+		stats.NewStatsCommand(ctx, a),
+		doctor.NewDoctorCommand(ctx, a),
+		login.NewLoginCommand(ctx, a),
+		login.NewLogoutCommand(ctx, a),
+		debugbundle.NewDebugBundleCommand(ctx, a),
+		upload.NewSimulateCommand(ctx, a),
 	)
 
 	return c, a
@@ -0,0 +1,223 @@
+package debugbundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/configuration"
+	"github.com/spf13/cobra"
+)
+
+type DebugBundleCmd struct {
+	Output    string // Name of the bundle
+	LogFile   string // Log of the run, the latest one when empty
+	APITrace  string // API trace of the run, the one of the log when empty
+	HashNames bool   // Replace the file names by hashes
+	NoLog     bool   // Don't add the log and the API trace
+	hasher    *nameHasher
+}
+
+// NewDebugBundleCommand adds the debug-bundle command, gathering in one archive what's needed to investigate an issue
+func NewDebugBundleCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug-bundle [flags] <takeout zips or folders...>",
+		Short: "Make an archive with the log, the API trace, and the list of the files of the sources, to share with the developers",
+		Long: `Make a zip archive with the log of a run, its API trace, the version of immich-go, the flags of the run without the secrets,
+and the list of the files of the zips, tgz, and folders given as arguments. The file contents aren't included.
+The list is read by the fakefs package to simulate the sources. The option --hash-names replaces the names of the files by hashes.`,
+	}
+	o := &DebugBundleCmd{}
+	cmd.Flags().StringVarP(&o.Output, "output", "o", time.Now().Format("immich-go-debug_2006-01-02_15-04-05.zip"), "Name of the bundle")
+	cmd.Flags().StringVar(&o.LogFile, "log", "", "Log file of the run to investigate (default: the latest log file)")
+	cmd.Flags().StringVar(&o.APITrace, "api-trace-file", "", "API trace file of the run (default: the trace of the log file, when present)")
+	cmd.Flags().BoolVar(&o.HashNames, "hash-names", false, "Replace the words of the file and folder names by hashes, in the list and in the log")
+	cmd.Flags().BoolVar(&o.NoLog, "no-log", false, "Don't add the log and the API trace to the bundle")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		if !o.NoLog && o.LogFile == "" {
+			o.LogFile = latestLog(a.Log().File)
+		}
+		if !o.NoLog && o.LogFile != "" && o.APITrace == "" {
+			o.APITrace = strings.TrimSuffix(o.LogFile, filepath.Ext(o.LogFile)) + ".trace.log"
+			if _, err := os.Stat(o.APITrace); err != nil {
+				o.APITrace = ""
+			}
+		}
+		sources := []string{}
+		for _, arg := range args {
+			matches, err := filepath.Glob(arg)
+			if err != nil || len(matches) == 0 {
+				matches = []string{arg}
+			}
+			sources = append(sources, matches...)
+		}
+		err := o.write(sources)
+		if err != nil {
+			return err
+		}
+		a.Log().Message("Debug bundle: %s", o.Output)
+		if o.LogFile != "" {
+			a.Log().Message("  log: %s", o.LogFile)
+		}
+		if o.APITrace != "" {
+			a.Log().Message("  API trace: %s", o.APITrace)
+		}
+		a.Log().Message("  %d sources listed", len(sources))
+		return nil
+	}
+	return cmd
+}
+
+// latestLog gives the latest log file of the log folder, other than the current one
+func latestLog(current string) string {
+	dir := filepath.Dir(configuration.DefaultLogFile())
+	if current != "" {
+		dir = filepath.Dir(current)
+	}
+	logs, _ := filepath.Glob(filepath.Join(dir, "immich-go_*.log"))
+	logs = filterLogs(logs, current)
+	if len(logs) == 0 {
+		return ""
+	}
+	sort.Strings(logs)
+	return logs[len(logs)-1]
+}
+
+func filterLogs(logs []string, current string) []string {
+	out := logs[:0]
+	for _, l := range logs {
+		if strings.HasSuffix(l, ".trace.log") || filepath.Clean(l) == filepath.Clean(current) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// write makes the bundle. The listing is the first entry, as expected by fakefs.ScanFileList.
+func (o *DebugBundleCmd) write(sources []string) (err error) {
+	o.hasher = newNameHasher(o.HashNames)
+	listing := bytes.NewBuffer(nil)
+	for _, s := range sources {
+		list, err := listSource(s)
+		if err != nil {
+			return err
+		}
+		if err = writeListing(listing, partName(s), list, o.hasher); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(o.Output)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, f.Close())
+	}()
+	z := zip.NewWriter(f)
+	defer func() {
+		err = errors.Join(err, z.Close())
+	}()
+
+	if err = addEntry(z, "listing.lst", listing); err != nil {
+		return err
+	}
+	version := fmt.Sprintf("%s\nos: %s, architecture: %s, go: %s\n", app.GetVersion(), runtime.GOOS, runtime.GOARCH, runtime.Version())
+	if err = addEntry(z, "version.txt", strings.NewReader(version)); err != nil {
+		return err
+	}
+	if o.LogFile != "" {
+		log, err := o.readRedacted(o.LogFile)
+		if err != nil {
+			return err
+		}
+		if err = addEntry(z, "flags.txt", bytes.NewReader(runFlags(log))); err != nil {
+			return err
+		}
+		if err = addEntry(z, "immich-go.log", bytes.NewReader(log)); err != nil {
+			return err
+		}
+	}
+	if o.APITrace != "" {
+		trace, err := o.readRedacted(o.APITrace)
+		if err != nil {
+			return err
+		}
+		if err = addEntry(z, "immich-go.trace.log", bytes.NewReader(trace)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addEntry(z *zip.Writer, name string, r io.Reader) error {
+	w, err := z.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// readRedacted reads a log file, without the secrets and with the hashed names
+func (o *DebugBundleCmd) readRedacted(name string) ([]byte, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	b = redactSecrets(b)
+	if o.HashNames {
+		b = []byte(o.hasher.replacer().Replace(string(b)))
+	}
+	return b, nil
+}
+
+var (
+	// --api-key=xxxx in the text logs, "--api-key":"xxxx" in the JSON logs
+	reSecretFlag = regexp.MustCompile(`(?i)("?--[\w-]*(?:api-key|password|token|secret|client-key)[\w-]*"?\s*[=:]\s*)("[^"]*"|\S+)`)
+	// headers of the API trace
+	reSecretHeader = regexp.MustCompile(`(?i)((?:x-api-key|authorization)"?\s*[:= ]\s*"?)(?:Bearer\s+)?[^\s",]+`)
+	// secrets of the JSON bodies
+	reSecretJSON = regexp.MustCompile(`(?i)("(?:password|accessToken|secret|apiKey)"\s*:\s*)"[^"]*"`)
+)
+
+// redactSecrets removes the API keys, passwords, and tokens of a log
+func redactSecrets(b []byte) []byte {
+	b = reSecretFlag.ReplaceAll(b, []byte("${1}redacted"))
+	b = reSecretHeader.ReplaceAll(b, []byte("${1}redacted"))
+	b = reSecretJSON.ReplaceAll(b, []byte(`${1}"redacted"`))
+	return b
+}
+
+// runFlags extracts the command, the flags, and the arguments from the log of a run
+func runFlags(log []byte) []byte {
+	out := bytes.NewBuffer(nil)
+	inFlags := false
+	for _, l := range bytes.Split(log, []byte("\n")) {
+		s := string(l)
+		switch {
+		case strings.Contains(s, "Command: "):
+			inFlags = true
+		case inFlags && !strings.Contains(s, "--") && !strings.Contains(s, "Flags:") && !strings.Contains(s, "Arguments:") && !strings.Contains(s, `  "`):
+			inFlags = false
+		}
+		if inFlags {
+			out.Write(l)
+			out.WriteByte('\n')
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,149 @@
+package debugbundle
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/internal/fakefs"
+)
+
+func writeFile(t *testing.T, name string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func makeSources(t *testing.T) (string, string, string) {
+	dir := t.TempDir()
+	folder := filepath.Join(dir, "Holidays")
+	writeFile(t, filepath.Join(folder, "Paris 2018", "IMG_20180725_102133.jpg"), "photo content")
+	writeFile(t, filepath.Join(folder, "Paris 2018", "IMG_20180725_102133.jpg.json"), "{}")
+
+	zipName := filepath.Join(dir, "takeout-001.zip")
+	f, err := os.Create(zipName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for _, name := range []string{"Takeout/Google Photos/Eiffel Tower/metadata.json", "Takeout/Google Photos/Eiffel Tower/sunset.jpg"} {
+		w, _ := z.Create(name)
+		_, _ = w.Write([]byte("content of " + name))
+	}
+	_ = z.Close()
+	_ = f.Close()
+
+	logName := filepath.Join(dir, "immich-go_2024-01-01_00-00-00.log")
+	writeFile(t, logName, `2024-01-01 00:00:00 INF Command: immich-go upload from-google-photos
+2024-01-01 00:00:00 INF Flags:
+2024-01-01 00:00:00 INF  --api-key=****cdef
+2024-01-01 00:00:00 INF  --password=hunter2
+2024-01-01 00:00:00 INF  --server=http://localhost:2283
+2024-01-01 00:00:00 INF Arguments:
+2024-01-01 00:00:00 INF   "takeout-001.zip"
+2024-01-01 00:00:01 INF uploaded file=takeout-001.zip:Takeout/Google Photos/Eiffel Tower/sunset.jpg
+`)
+	writeFile(t, filepath.Join(dir, "immich-go_2024-01-01_00-00-00.trace.log"), `PUT http://localhost:2283/api/assets
+   X-Api-Key abcdef
+   Authorization: Bearer eyJsecret
+`)
+	return folder, zipName, logName
+}
+
+func readEntries(t *testing.T, name string) ([]string, map[string]string) {
+	t.Helper()
+	z, err := zip.OpenReader(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Close()
+	names := []string{}
+	contents := map[string]string{}
+	for _, f := range z.File {
+		r, _ := f.Open()
+		b, _ := io.ReadAll(r)
+		r.Close()
+		names = append(names, f.Name)
+		contents[f.Name] = string(b)
+	}
+	return names, contents
+}
+
+func TestDebugBundle(t *testing.T) {
+	for _, hash := range []bool{false, true} {
+		t.Run(map[bool]string{false: "clear", true: "hashed"}[hash], func(t *testing.T) {
+			folder, zipName, logName := makeSources(t)
+			o := &DebugBundleCmd{
+				Output:    filepath.Join(t.TempDir(), "bundle.zip"),
+				LogFile:   logName,
+				APITrace:  strings.TrimSuffix(logName, ".log") + ".trace.log",
+				HashNames: hash,
+			}
+			if err := o.write([]string{folder, zipName}); err != nil {
+				t.Fatal(err)
+			}
+
+			names, contents := readEntries(t, o.Output)
+			if names[0] != "listing.lst" {
+				t.Errorf("the first entry is %s, want listing.lst", names[0])
+			}
+			for _, e := range []string{"version.txt", "flags.txt", "immich-go.log", "immich-go.trace.log"} {
+				if _, ok := contents[e]; !ok {
+					t.Errorf("the bundle has no %s", e)
+				}
+			}
+			for _, secret := range []string{"cdef", "hunter2", "abcdef", "eyJsecret"} {
+				for e, c := range contents {
+					if strings.Contains(c, secret) {
+						t.Errorf("%s contains the secret %q", e, secret)
+					}
+				}
+			}
+			if !strings.Contains(contents["flags.txt"], "--server=http://localhost:2283") || strings.Contains(contents["flags.txt"], "uploaded") {
+				t.Errorf("unexpected flags.txt:\n%s", contents["flags.txt"])
+			}
+
+			// the bundle is read by fakefs
+			fsyss, err := fakefs.ScanFileList(o.Output, listingDateFormat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := []string{}
+			for _, fsys := range fsyss {
+				_ = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+					if err == nil && !d.IsDir() {
+						files = append(files, p)
+					}
+					return nil
+				})
+			}
+			if len(files) != 4 {
+				t.Fatalf("fakefs has %d files, want 4: %v", len(files), files)
+			}
+			listing := contents["listing.lst"]
+			for _, private := range []string{"Holidays", "Paris", "Eiffel", "sunset"} {
+				if strings.Contains(listing, private) == hash {
+					t.Errorf("listing with hash=%v and the name %q:\n%s", hash, private, listing)
+				}
+				if hash && strings.Contains(contents["immich-go.log"], private) {
+					t.Errorf("the log contains the name %q", private)
+				}
+			}
+			if hash {
+				for _, kept := range []string{"Takeout/Google Photos/", "/metadata.json", "IMG_20180725_102133.jpg", ".jpg.json"} {
+					if !strings.Contains(listing, kept) {
+						t.Errorf("the hashed listing lost %q:\n%s", kept, listing)
+					}
+				}
+			}
+		})
+	}
+}
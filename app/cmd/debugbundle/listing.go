@@ -0,0 +1,245 @@
+package debugbundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+	The listing has the format of `unzip -l`, read by the fakefs package:
+
+	Part: takeout-001.zip
+	   425704  2024-05-25 22:15   Takeout/Google Photos/Guillaumes 2018/IMG_8213.JPG
+*/
+
+// listingDateFormat is the date format of the listing, to give to fakefs.ScanFileList
+const listingDateFormat = "2006-01-02 15:04"
+
+type listedFile struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// listSource lists the files of a zip, a tgz, or a folder
+func listSource(name string) ([]listedFile, error) {
+	s, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.IsDir() {
+		return listFolder(name)
+	}
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return listZip(name)
+	case strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".tar.gz"):
+		return listTgz(name)
+	}
+	return nil, fmt.Errorf("%s: not a folder, a zip or a tgz file", name)
+}
+
+func listFolder(dir string) ([]listedFile, error) {
+	list := []listedFile{}
+	err := fs.WalkDir(os.DirFS(dir), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		i, err := d.Info()
+		if err != nil {
+			return err
+		}
+		list = append(list, listedFile{name: p, size: i.Size(), modTime: i.ModTime()})
+		return nil
+	})
+	return list, err
+}
+
+func listZip(name string) ([]listedFile, error) {
+	z, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+	list := []listedFile{}
+	for _, f := range z.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		list = append(list, listedFile{name: f.Name, size: int64(f.UncompressedSize64), modTime: f.Modified})
+	}
+	return list, nil
+}
+
+func listTgz(name string) ([]listedFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	list := []listedFile{}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return list, nil
+		}
+		if err != nil {
+			return list, err
+		}
+		if h.Typeflag == tar.TypeReg {
+			list = append(list, listedFile{name: h.Name, size: h.Size, modTime: h.ModTime})
+		}
+	}
+}
+
+// writeListing writes the files of a source in the listing
+func writeListing(w io.Writer, part string, list []listedFile, h *nameHasher) error {
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	if _, err := fmt.Fprintf(w, "Part: %s\n", h.path(part)); err != nil {
+		return err
+	}
+	for _, f := range list {
+		if _, err := fmt.Fprintf(w, "%9d  %s   %s\n", f.size, f.modTime.Local().Format(listingDateFormat), h.path(f.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nameHasher replaces the words of the file names by hashes, keeping the extensions,
+// the numbers, and the names needed to simulate a takeout.
+// The same name gives the same hash in a bundle, so the JSON files still match their photos.
+type nameHasher struct {
+	enabled  bool
+	salt     []byte
+	replaced map[string]string // hashed names, to hash them in the log too
+}
+
+func newNameHasher(enabled bool) *nameHasher {
+	h := &nameHasher{enabled: enabled, replaced: map[string]string{}}
+	if enabled {
+		h.salt = make([]byte, 16)
+		_, _ = rand.Read(h.salt)
+	}
+	return h
+}
+
+// keptNames are the file names not hashed
+var keptNames = map[string]bool{
+	"takeout":                           true,
+	"google photos":                     true,
+	"google foto":                       true,
+	"metadata.json":                     true,
+	"métadonnées.json":                  true,
+	"metadati.json":                     true,
+	"metadáta.json":                     true,
+	"metadaten.json":                    true,
+	"print-subscriptions.json":          true,
+	"shared_album_comments.json":        true,
+	"user-generated-memory-titles.json": true,
+	"archive_browser.html":              true,
+}
+
+// keptWords are the words of the file names not hashed
+var keptWords = map[string]bool{
+	"img": true, "vid": true, "pxl": true, "mvimg": true, "dsc": true, "dscn": true, "dcim": true,
+	"pano": true, "burst": true, "cover": true, "portrait": true, "edited": true, "original": true,
+	"photo": true, "photos": true, "from": true, "screenshot": true, "mp": true,
+}
+
+var reWord = regexp.MustCompile(`\p{L}{2,}`)
+
+// path hashes the names of a path
+func (h *nameHasher) path(p string) string {
+	if !h.enabled {
+		return p
+	}
+	p = filepath.ToSlash(p)
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = h.name(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// name hashes the words of a name before its first dot
+func (h *nameHasher) name(n string) string {
+	if n == "" || n == "." || n == ".." || keptNames[strings.ToLower(n)] {
+		return n
+	}
+	stem, rest := n, ""
+	if i := strings.Index(n, "."); i > 0 {
+		stem, rest = n[:i], n[i:]
+	}
+	hashed := reWord.ReplaceAllStringFunc(stem, func(w string) string {
+		if keptWords[strings.ToLower(w)] {
+			return w
+		}
+		return h.word(w)
+	})
+	if hashed != stem && len(stem) >= 4 {
+		h.replaced[stem] = hashed
+	}
+	return hashed + rest
+}
+
+// word gives a hash of the word made of letters, not to change the numbers of the name
+func (h *nameHasher) word(w string) string {
+	s := sha256.New()
+	s.Write(h.salt)
+	s.Write([]byte(w))
+	sum := s.Sum(nil)
+	b := make([]byte, 8)
+	for i := range b {
+		b[i] = 'a' + sum[i]%26
+	}
+	return string(b)
+}
+
+// replacer hashes the names found in a text, like the log
+func (h *nameHasher) replacer() *strings.Replacer {
+	stems := make([]string, 0, len(h.replaced))
+	for s := range h.replaced {
+		stems = append(stems, s)
+	}
+	// the longest names first
+	sort.Slice(stems, func(i, j int) bool {
+		if len(stems[i]) != len(stems[j]) {
+			return len(stems[i]) > len(stems[j])
+		}
+		return stems[i] < stems[j]
+	})
+	pairs := make([]string, 0, 2*len(stems))
+	for _, s := range stems {
+		pairs = append(pairs, s, h.replaced[s])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// partName gives the name of a source in the listing
+func partName(source string) string {
+	return path.Base(filepath.ToSlash(filepath.Clean(source)))
+}
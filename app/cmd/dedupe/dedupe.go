@@ -0,0 +1,235 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	cliflags "github.com/simulot/immich-go/internal/cliFlags"
+	"github.com/simulot/immich-go/internal/duplicates"
+	"github.com/spf13/cobra"
+)
+
+// Action is what to do with the copies of lesser quality
+// Implement the interface pflag.Value
+type Action string
+
+const (
+	ActionReport Action = "report" // list the duplicates
+	ActionStack  Action = "stack"  // stack the duplicates with the best copy as cover
+	ActionTrash  Action = "trash"  // move the copies of lesser quality to the trash
+)
+
+func (a Action) String() string {
+	return string(a)
+}
+
+func (a *Action) Set(v string) error {
+	switch Action(strings.ToLower(strings.TrimSpace(v))) {
+	case ActionReport:
+		*a = ActionReport
+	case ActionStack:
+		*a = ActionStack
+	case ActionTrash:
+		*a = ActionTrash
+	default:
+		return fmt.Errorf("invalid action: %q, expected report, stack or trash", v)
+	}
+	return nil
+}
+
+func (a Action) Type() string {
+	return "action"
+}
+
+type DedupeCmd struct {
+	DateRange   cliflags.DateRange // Set capture date range
+	Action      Action             // What to do with the duplicates
+	Rule        duplicates.Rule    // How to choose the asset to keep
+	MergeAlbums bool               // Add the keeper to the albums of its copies
+
+	out io.Writer // report of the groups
+}
+
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+func NewDedupeCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dedupe [flags]",
+		Short: "Find the duplicated assets of the server",
+		Long:  `Find the assets having the same name and date, or the same date and dimensions, then report, stack or trash the copies of lesser quality`,
+	}
+
+	o := &DedupeCmd{
+		Action:      ActionReport,
+		Rule:        append(duplicates.Rule{}, duplicates.DefaultRule...),
+		MergeAlbums: true,
+	}
+	app.AddClientFlags(ctx, cmd, a, false)
+	cmd.TraverseChildren = true
+	cmd.Flags().Var(&o.DateRange, "date-range", "photos must be taken in the date range")
+	cmd.Flags().Var(&o.Action, "action", "What to do with the duplicates: report, stack or trash")
+	cmd.Flags().Var(&o.Rule, "keep", "Criteria used to choose the asset to keep, by order of priority: resolution, size, metadata, favorite, oldest, newest")
+	cmd.Flags().BoolVar(&o.MergeAlbums, "merge-albums", true, "Add the kept asset to the albums of the removed or stacked copies")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+
+		// the hidden motion parts of the live photos aren't candidates
+		query := &immich.SearchMetadataQuery{
+			WithExif:  true,
+			IsVisible: true,
+		}
+		if o.DateRange.IsSet() {
+			query.TakenAfter = o.DateRange.After.Format(timeFormat)
+			query.TakenBefore = o.DateRange.Before.Format(timeFormat)
+		}
+		list := []*immich.Asset{}
+		err := client.GetAllAssetsWithFilter(ctx, query, func(as *immich.Asset) error {
+			list = append(list, as)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		groups := duplicates.Find(list, o.Rule)
+
+		albums, err := getAssetAlbums(ctx, client)
+		if err != nil {
+			return err
+		}
+		o.out = cmd.OutOrStdout()
+		return o.process(ctx, a, groups, albums)
+	}
+	return cmd
+}
+
+// getAssetAlbums returns the albums of each asset
+func getAssetAlbums(ctx context.Context, client immich.ImmichInterface) (map[string][]immich.AlbumSimplified, error) {
+	albums, err := client.GetAllAlbums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get the albums: %w", err)
+	}
+	byAsset := map[string][]immich.AlbumSimplified{}
+	for _, al := range albums {
+		info, err := client.GetAlbumInfo(ctx, al.ID, false)
+		if err != nil {
+			return nil, fmt.Errorf("can't get the album %q: %w", al.AlbumName, err)
+		}
+		for _, as := range info.Assets {
+			byAsset[as.ID] = append(byAsset[as.ID], al)
+		}
+	}
+	return byAsset, nil
+}
+
+func (o *DedupeCmd) process(ctx context.Context, a *app.Application, groups []duplicates.Group, albums map[string][]immich.AlbumSimplified) error {
+	log := a.Log()
+	client := a.Client().Immich
+	copies := 0
+
+	for i, g := range groups {
+		// the trash is used only for the copies that can't be frames of a burst
+		removed := g.Others()
+		if o.Action == ActionTrash {
+			removed = g.Trashable()
+		}
+		fmt.Fprintf(o.out, "Group %d (%s):\n", i+1, g.Reason)
+		for j, as := range g.Assets {
+			verb := "keep "
+			switch {
+			case j == 0:
+			case o.Action == ActionReport:
+				verb = "copy "
+			case slices.Contains(removed, as):
+				verb = string(o.Action)
+			default:
+				verb = "spare" // not enough evidence to trash it
+			}
+			fmt.Fprintf(o.out, "  %-6s %s  %s  %dx%d  %s  %s%s\n", verb, as.ID,
+				as.ExifInfo.DateTimeOriginal.Format("2006-01-02 15:04:05"),
+				as.ExifInfo.ExifImageWidth, as.ExifInfo.ExifImageHeight,
+				formatSize(as.ExifInfo.FileSizeInByte), as.OriginalFileName, albumList(albums[as.ID]))
+		}
+		copies += len(g.Others())
+
+		if o.Action == ActionReport || len(removed) == 0 {
+			continue
+		}
+
+		keeper := g.Keeper()
+		if o.MergeAlbums {
+			o.mergeAlbums(ctx, a, keeper, removed, albums)
+		}
+
+		ids := make([]string, 0, len(removed))
+		for _, as := range removed {
+			ids = append(ids, as.ID)
+		}
+		switch o.Action {
+		case ActionStack:
+			if _, err := client.CreateStack(ctx, append([]string{keeper.ID}, ids...)); err != nil {
+				log.Error("can't create the stack", "file", keeper.OriginalFileName, "err", err)
+				continue
+			}
+			log.Info("Duplicates stacked", "file", keeper.OriginalFileName, "copies", len(ids))
+		case ActionTrash:
+			if err := client.DeleteAssets(ctx, ids, false); err != nil {
+				log.Error("can't trash the duplicates", "file", keeper.OriginalFileName, "err", err)
+				continue
+			}
+			log.Info("Duplicates moved to the trash", "file", keeper.OriginalFileName, "copies", len(ids))
+		}
+	}
+	log.Message("%d groups of duplicates found, %d copies", len(groups), copies)
+	return nil
+}
+
+// mergeAlbums adds the keeper to the albums of its copies
+func (o *DedupeCmd) mergeAlbums(ctx context.Context, a *app.Application, keeper *immich.Asset, others []*immich.Asset, albums map[string][]immich.AlbumSimplified) {
+	log := a.Log()
+	done := map[string]bool{}
+	for _, al := range albums[keeper.ID] {
+		done[al.ID] = true
+	}
+	for _, as := range others {
+		for _, al := range albums[as.ID] {
+			if done[al.ID] {
+				continue
+			}
+			done[al.ID] = true
+			if _, err := a.Client().Immich.AddAssetToAlbum(ctx, al.ID, []string{keeper.ID}); err != nil {
+				log.Error("can't add the asset to the album", "file", keeper.OriginalFileName, "album", al.AlbumName, "err", err)
+				continue
+			}
+			albums[keeper.ID] = append(albums[keeper.ID], al)
+			log.Info("Asset added to the album of its copy", "file", keeper.OriginalFileName, "album", al.AlbumName)
+		}
+	}
+}
+
+func albumList(albums []immich.AlbumSimplified) string {
+	if len(albums) == 0 {
+		return ""
+	}
+	names := make([]string, len(albums))
+	for i, al := range albums {
+		names[i] = al.AlbumName
+	}
+	return "  [" + strings.Join(names, ", ") + "]"
+}
+
+func formatSize(s int64) string {
+	switch {
+	case s >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(s)/(1<<20))
+	case s >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(s)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", s)
+}
@@ -0,0 +1,134 @@
+package dedupe
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+	"github.com/simulot/immich-go/internal/fakeImmich/cmdtest"
+)
+
+// newServer gives a server with:
+//   - a photo and its resized copy, having the same name and date,
+//   - three frames of a burst, having the same date and dimensions, but different sizes,
+//   - a photo and the hidden motion part of a live photo, having the same name and date.
+func newServer(t *testing.T) (*fakeimmich.Server, map[string]string) {
+	s := cmdtest.NewServer(t)
+	d1 := time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)
+	d2 := time.Date(2023, 7, 15, 10, 0, 0, 0, time.UTC)
+	d3 := time.Date(2023, 7, 16, 10, 0, 0, 0, time.UTC)
+	ids := map[string]string{
+		"original": s.AddAsset("IMG_0001.JPG", bytes.Repeat([]byte("o"), 3000), d1),
+		"resized":  s.AddAsset("IMG_0001.JPG", bytes.Repeat([]byte("r"), 1000), d1),
+		"burst1":   s.AddAsset("BURST_0001.JPG", bytes.Repeat([]byte("1"), 2000), d2),
+		"burst2":   s.AddAsset("BURST_0002.JPG", bytes.Repeat([]byte("2"), 2100), d2),
+		"burst3":   s.AddAsset("BURST_0003.JPG", bytes.Repeat([]byte("3"), 2200), d2),
+		"live":     s.AddAsset("IMG_0002.HEIC", []byte("live photo"), d3),
+		"motion":   s.AddAsset("IMG_0002.HEIC", []byte("motion part"), d3),
+	}
+	s.SetDimensions(ids["original"], 4000, 3000)
+	s.SetDimensions(ids["resized"], 2000, 1500)
+	for _, k := range []string{"burst1", "burst2", "burst3"} {
+		s.SetDimensions(ids[k], 4000, 3000)
+	}
+	s.SetHidden(ids["motion"])
+	s.AddAlbum("Holidays", ids["resized"])
+	return s, ids
+}
+
+func runDedupe(t *testing.T, s *fakeimmich.Server, action string, args ...string) string {
+	out, err := cmdtest.Run(s, NewDedupeCommand, append([]string{"--action", action}, args...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestDedupeReport(t *testing.T) {
+	s, ids := newServer(t)
+	out := runDedupe(t, s, "report")
+
+	for _, want := range []string{
+		"Group 1 (",
+		"  keep   " + ids["original"],
+		"  copy   " + ids["resized"],
+		"  keep   " + ids["burst3"],
+		"  copy   " + ids["burst1"],
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the report should contain %q:\n%s", want, out)
+		}
+	}
+	for k, id := range ids {
+		as, _ := s.Asset(id)
+		if as.Trashed || as.StackCover != "" {
+			t.Errorf("%s: the report must not change the assets", k)
+		}
+	}
+	if as, _ := s.Asset(ids["original"]); len(as.Albums) != 0 {
+		t.Errorf("the report must not change the albums: %v", as.Albums)
+	}
+}
+
+func TestDedupeTrash(t *testing.T) {
+	s, ids := newServer(t)
+	runDedupe(t, s, "trash")
+
+	for k, wantTrashed := range map[string]bool{
+		"original": false,
+		"resized":  true,
+		"burst1":   false,
+		"burst2":   false,
+		"burst3":   false,
+		"live":     false,
+		"motion":   false,
+	} {
+		as, _ := s.Asset(ids[k])
+		if as.Trashed != wantTrashed {
+			t.Errorf("%s: trashed %v, want %v", k, as.Trashed, wantTrashed)
+		}
+	}
+	if as, _ := s.Asset(ids["original"]); len(as.Albums) != 1 || as.Albums[0] != "Holidays" {
+		t.Errorf("the kept asset should be in the album of its copy: %v", as.Albums)
+	}
+}
+
+func TestDedupeTrashWithoutMergeAlbums(t *testing.T) {
+	s, ids := newServer(t)
+	runDedupe(t, s, "trash", "--merge-albums=false")
+
+	if as, _ := s.Asset(ids["resized"]); !as.Trashed {
+		t.Error("the resized copy should be trashed")
+	}
+	if as, _ := s.Asset(ids["original"]); len(as.Albums) != 0 {
+		t.Errorf("the kept asset should stay out of the album of its copy: %v", as.Albums)
+	}
+}
+
+func TestDedupeStack(t *testing.T) {
+	s, ids := newServer(t)
+	runDedupe(t, s, "stack")
+
+	for k, wantCover := range map[string]string{
+		"original": "IMG_0001.JPG",
+		"resized":  "IMG_0001.JPG",
+		"burst1":   "BURST_0003.JPG",
+		"burst2":   "BURST_0003.JPG",
+		"burst3":   "BURST_0003.JPG",
+		"live":     "",
+		"motion":   "",
+	} {
+		as, _ := s.Asset(ids[k])
+		if as.Trashed {
+			t.Errorf("%s: stacked assets must not be trashed", k)
+		}
+		if as.StackCover != wantCover {
+			t.Errorf("%s: stack cover %q, want %q", k, as.StackCover, wantCover)
+		}
+	}
+	if as, _ := s.Asset(ids["original"]); len(as.Albums) != 1 || as.Albums[0] != "Holidays" {
+		t.Errorf("the stack cover should be in the album of its copy: %v", as.Albums)
+	}
+}
@@ -0,0 +1,474 @@
+package doctor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/fshelper"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusOK   Status = "OK"
+	StatusWarn Status = "WARN"
+	StatusFail Status = "FAIL"
+	StatusSkip Status = "SKIP"
+)
+
+// Result is the outcome of a check, with the way to fix the problem
+type Result struct {
+	Check  string
+	Status Status
+	Detail string
+	Remedy string
+}
+
+// capabilities lists the capabilities of the server needed by some commands
+var capabilities = []struct {
+	capability immich.Capability
+	commands   string
+}{
+	{immich.CapabilityTags, "tag, session, upload --tag, --session-tag"},
+}
+
+// probes are the read-only calls made by the commands, with the permission of the API key they need
+var probes = []struct {
+	name       string
+	permission string
+	commands   string
+	required   bool
+	call       func(ctx context.Context, ic immich.ImmichInterface) error
+}{
+	{"search assets", "asset.read", "upload, audit, archive, stack, dedupe, fix-dates, geotag, stats", true, func(ctx context.Context, ic immich.ImmichInterface) error {
+		err := ic.GetAllAssetsWithFilter(ctx, &immich.SearchMetadataQuery{}, func(*immich.Asset) error { return errStop })
+		if errors.Is(err, errStop) {
+			return nil
+		}
+		return err
+	}},
+	{"list albums", "album.read", "upload, album, stats", true, func(ctx context.Context, ic immich.ImmichInterface) error {
+		_, err := ic.GetAllAlbums(ctx)
+		return err
+	}},
+	{"list tags", "tag.read", "upload, tag, session, stats", true, func(ctx context.Context, ic immich.ImmichInterface) error {
+		_, err := ic.GetAllTags(ctx)
+		return err
+	}},
+	{"asset statistics", "asset.statistics", "upload", false, func(ctx context.Context, ic immich.ImmichInterface) error {
+		_, err := ic.GetAssetStatistics(ctx)
+		return err
+	}},
+	{"list jobs", "job.read (administrator)", "jobs, upload --pause-jobs", false, func(ctx context.Context, ic immich.ImmichInterface) error {
+		_, err := ic.GetJobs(ctx)
+		return err
+	}},
+}
+
+var errStop = errors.New("stop")
+
+// doctor runs the checks one after the other. A failing check skips the ones depending on it.
+type doctor struct {
+	client    *app.Client
+	probeSize int64 // Size of the upload probe, 0 to skip it
+	maxSkew   time.Duration
+	results   []Result
+	now       func() time.Time
+}
+
+func (d *doctor) add(check string, status Status, detail, remedy string) {
+	d.results = append(d.results, Result{Check: check, Status: status, Detail: detail, Remedy: remedy})
+}
+
+func (d *doctor) skip(checks ...string) {
+	for _, c := range checks {
+		d.add(c, StatusSkip, "a previous check has failed", "")
+	}
+}
+
+func (d *doctor) failed() int {
+	n := 0
+	for _, r := range d.results {
+		if r.Status == StatusFail {
+			n++
+		}
+	}
+	return n
+}
+
+func (d *doctor) run(ctx context.Context) {
+	u, ok := d.checkConfiguration()
+	if !ok {
+		d.skip("connectivity", "TLS", "server", "API key", "server version", "permissions", "upload size", "clock")
+		return
+	}
+	if !d.checkConnectivity(ctx, u) {
+		d.skip("TLS", "server", "API key", "server version", "permissions", "upload size", "clock")
+		return
+	}
+	if !d.checkTLS(ctx, u) {
+		d.skip("server", "API key", "server version", "permissions", "upload size", "clock")
+		return
+	}
+	if !d.checkServer(ctx) {
+		d.skip("API key", "server version", "permissions", "upload size")
+		d.checkClock(ctx)
+		return
+	}
+	if d.checkAPIKey(ctx) {
+		d.checkVersion(ctx)
+		d.checkPermissions(ctx)
+		d.checkUploadSize(ctx)
+	} else {
+		d.skip("server version", "permissions", "upload size")
+	}
+	d.checkClock(ctx)
+}
+
+func (d *doctor) checkConfiguration() (*url.URL, bool) {
+	const check = "configuration"
+	var missing []string
+	if d.client.Server == "" {
+		missing = append(missing, "--server")
+	}
+	if d.client.APIKey == "" && d.client.AccessToken == "" {
+		missing = append(missing, "--api-key")
+	}
+	if len(missing) > 0 {
+		d.add(check, StatusFail, "missing "+strings.Join(missing, " and "), "Give the server address like http://your-ip:2283 or https://your-domain, and an API key created in the Immich account settings, or open a session with immich-go login")
+		return nil, false
+	}
+	u, err := url.Parse(d.client.Server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		d.add(check, StatusFail, fmt.Sprintf("invalid server address %q", d.client.Server), "Give the server address with its scheme, like http://your-ip:2283 or https://your-domain")
+		return nil, false
+	}
+	if strings.HasSuffix(u.Path, "/api") {
+		d.add(check, StatusFail, "the server address ends with /api", "Remove /api from the server address, immich-go adds it")
+		return nil, false
+	}
+	o, err := d.client.TransportOptions()
+	if err == nil {
+		err = o.Apply(&http.Transport{})
+	}
+	if err != nil {
+		d.add(check, StatusFail, err.Error(), "Check the options --header, --proxy, --ca-cert, --client-cert and --client-key, or the profile")
+		return nil, false
+	}
+	d.add(check, StatusOK, "server "+d.client.Server, "")
+	return u, true
+}
+
+// hostPort gives the address of the server with the default port of the scheme
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+func (d *doctor) checkConnectivity(ctx context.Context, u *url.URL) bool {
+	const check = "connectivity"
+	target := u
+	if d.client.Proxy != "" {
+		target, _ = url.Parse(d.client.Proxy)
+	}
+	if net.ParseIP(target.Hostname()) == nil {
+		_, err := net.DefaultResolver.LookupHost(ctx, target.Hostname())
+		if err != nil {
+			d.add(check, StatusFail, fmt.Sprintf("can't resolve %s: %s", target.Hostname(), err), "Check the server name, or use its IP address")
+			return false
+		}
+	}
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", hostPort(target))
+	if err != nil {
+		d.add(check, StatusFail, fmt.Sprintf("can't connect to %s: %s", hostPort(target), err), "Check the port of the server or of the proxy, the firewall, and that the server is running")
+		return false
+	}
+	conn.Close()
+	if target != u {
+		d.add(check, StatusOK, "connected to the proxy "+hostPort(target), "")
+		return true
+	}
+	d.add(check, StatusOK, "connected to "+hostPort(u), "")
+	return true
+}
+
+// get calls the ping API with the network settings of the client, without the immich client.
+// The server's certificate is verified when verify is true.
+func (d *doctor) get(ctx context.Context, verify bool) (*http.Response, error) {
+	o, err := d.client.TransportOptions()
+	if err != nil {
+		return nil, err
+	}
+	if verify {
+		o.SkipVerify = false
+	}
+	t := &http.Transport{}
+	if err := o.Apply(t); err != nil {
+		return nil, err
+	}
+	defer t.CloseIdleConnections()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.client.Server+"/api/server/ping", http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range o.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second, Transport: t}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+func (d *doctor) checkTLS(ctx context.Context, u *url.URL) bool {
+	const check = "TLS"
+	if u.Scheme != "https" {
+		d.add(check, StatusWarn, "the connection isn't encrypted", "Use https when the server is reachable from the internet, the API key is sent in clear text")
+		return true
+	}
+	resp, err := d.get(ctx, true)
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		switch {
+		case errors.As(err, &certErr) && d.client.SkipSSL:
+			d.add(check, StatusWarn, "the certificate isn't verified: "+certErr.Err.Error(), "Give the certificate authority of the server with --ca-cert to remove --skip-verify-ssl")
+			return true
+		case errors.As(err, &certErr):
+			d.add(check, StatusFail, certErr.Err.Error(), "Give the certificate authority of the server with --ca-cert, or use --skip-verify-ssl")
+		case strings.Contains(err.Error(), "certificate required") || strings.Contains(err.Error(), "bad certificate"):
+			d.add(check, StatusFail, "the server refuses the client certificate: "+err.Error(), "Give the client certificate and its key accepted by the server with --client-cert and --client-key")
+		default:
+			d.add(check, StatusFail, err.Error(), "Check the TLS settings of the server and of the reverse proxy")
+		}
+		return false
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		cert := resp.TLS.PeerCertificates[0]
+		if cert.NotAfter.Sub(d.now()) < 14*24*time.Hour {
+			d.add(check, StatusWarn, fmt.Sprintf("the certificate expires on %s", cert.NotAfter.Format(time.DateOnly)), "Renew the certificate of the server")
+			return true
+		}
+		d.add(check, StatusOK, fmt.Sprintf("certificate valid until %s", cert.NotAfter.Format(time.DateOnly)), "")
+		return true
+	}
+	d.add(check, StatusOK, "certificate verified", "")
+	return true
+}
+
+func (d *doctor) checkServer(ctx context.Context) bool {
+	const check = "server"
+	err := d.client.Connect()
+	if err == nil {
+		err = d.client.Immich.PingServer(ctx)
+	}
+	if err != nil {
+		d.add(check, StatusFail, err.Error(), "Check that the address is the one of the Immich web page, and the reverse proxy forwards /api to the server")
+		return false
+	}
+	d.add(check, StatusOK, "the server answers the ping", "")
+	return true
+}
+
+func (d *doctor) checkAPIKey(ctx context.Context) bool {
+	const check = "API key"
+	user, err := d.client.Immich.ValidateConnection(ctx)
+	if err != nil {
+		switch immich.StatusCode(err) {
+		case http.StatusUnauthorized:
+			if d.client.APIKey == "" {
+				d.add(check, StatusFail, "the session is expired", "Open a new session with immich-go login")
+				break
+			}
+			d.add(check, StatusFail, "the API key is refused", "Create a new API key in the account settings of the Immich web page")
+		case http.StatusForbidden:
+			d.add(check, StatusFail, "the API key can't read the user and the server's media types", "Give the permissions user.read and server.about to the API key, or use a key with all permissions")
+		default:
+			d.add(check, StatusFail, err.Error(), "Check the API key")
+		}
+		return false
+	}
+	d.client.User = user
+	detail := "user " + user.Email
+	if user.IsAdmin {
+		detail += " (administrator)"
+	}
+	d.add(check, StatusOK, detail, "")
+	return true
+}
+
+func (d *doctor) checkVersion(ctx context.Context) {
+	const check = "server version"
+	about, err := d.client.Immich.NegotiateCapabilities(ctx)
+	if err != nil {
+		if _, perr := immich.ParseVersion(about.Version); about.Version != "" && perr == nil {
+			d.add(check, StatusFail, err.Error(), "Upgrade the Immich server")
+			return
+		}
+		d.add(check, StatusWarn, firstLine(err), "Give the permission server.about to the API key")
+		return
+	}
+	v := d.client.Immich.ServerVersion()
+	if v.IsZero() {
+		d.add(check, StatusWarn, fmt.Sprintf("unknown version %q, the API of the latest Immich server is used", about.Version), "")
+		return
+	}
+	var missing []string
+	for _, c := range capabilities {
+		if err := d.client.Immich.Require(c.capability); err != nil {
+			missing = append(missing, fmt.Sprintf("%s, used by %s", err, c.commands))
+		}
+	}
+	if len(missing) > 0 {
+		d.add(check, StatusWarn, fmt.Sprintf("version %s: %s", v, strings.Join(missing, "; ")), "Upgrade the Immich server to use those features")
+		return
+	}
+	d.add(check, StatusOK, "version "+v.String(), "")
+}
+
+func (d *doctor) checkPermissions(ctx context.Context) {
+	for _, p := range probes {
+		check := "permission " + p.permission
+		err := p.call(ctx, d.client.Immich)
+		if err == nil {
+			d.add(check, StatusOK, p.name, "")
+			continue
+		}
+		if errors.Is(err, &immich.UnsupportedError{}) {
+			d.add(check, StatusSkip, err.Error(), "")
+			continue
+		}
+		status := StatusWarn
+		if p.required {
+			status = StatusFail
+		}
+		switch immich.StatusCode(err) {
+		case http.StatusForbidden:
+			remedy := "Give the permission " + p.permission + " to the API key"
+			if strings.Contains(p.permission, "administrator") && !d.client.User.IsAdmin {
+				remedy = "Use the API key of an administrator"
+			}
+			d.add(check, status, fmt.Sprintf("%s is refused, used by %s", p.name, p.commands), remedy)
+		default:
+			d.add(check, status, fmt.Sprintf("%s: %s", p.name, firstLine(err)), "Check the server's log")
+		}
+	}
+}
+
+// checkUploadSize uploads a probe of random data, and deletes it. When the request
+// is too large for the server or its reverse proxy, smaller probes tell the limit.
+func (d *doctor) checkUploadSize(ctx context.Context) {
+	const check = "upload size"
+	if d.probeSize <= 0 {
+		d.add(check, StatusSkip, "disabled by --upload-probe-size 0", "")
+		return
+	}
+	if d.client.DryRun {
+		d.add(check, StatusSkip, "dry-run mode", "")
+		return
+	}
+	var refused int64
+	for size := d.probeSize; size >= 1<<20; size /= 2 {
+		err := d.uploadProbe(ctx, size)
+		switch {
+		case err == nil && refused == 0:
+			d.add(check, StatusOK, fmt.Sprintf("a request of %s is accepted", formatSize(size)), "")
+			return
+		case err == nil:
+			d.add(check, StatusFail, fmt.Sprintf("a request of %s is refused, %s is accepted", formatSize(refused), formatSize(size)),
+				"Raise the request size limit of the reverse proxy, like client_max_body_size for nginx, or the upload limit of the tunnel")
+			return
+		case immich.StatusCode(err) == http.StatusRequestEntityTooLarge:
+			refused = size
+		case immich.StatusCode(err) == http.StatusForbidden:
+			d.add(check, StatusFail, "the upload is refused", "Give the permissions asset.upload and asset.delete to the API key")
+			return
+		default:
+			d.add(check, StatusFail, fmt.Sprintf("upload of %s: %s", formatSize(size), firstLine(err)),
+				"Check the request size limit and the timeouts of the reverse proxy")
+			return
+		}
+	}
+	d.add(check, StatusFail, fmt.Sprintf("a request of %s is refused", formatSize(refused)),
+		"Raise the request size limit of the reverse proxy, like client_max_body_size for nginx, or the upload limit of the tunnel")
+}
+
+func (d *doctor) uploadProbe(ctx context.Context, size int64) error {
+	const name = "immich-go-doctor-probe.jpg"
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	fsys := fstest.MapFS{name: &fstest.MapFile{Data: data, ModTime: d.now()}}
+	a := &assets.Asset{
+		File:             fshelper.FSName(fsys, name),
+		OriginalFileName: name,
+		FileSize:         int(size),
+		CaptureDate:      d.now(),
+	}
+	r, err := d.client.Immich.AssetUpload(ctx, a)
+	if err != nil {
+		return err
+	}
+	if r.Status == immich.UploadDuplicate {
+		return nil
+	}
+	err = d.client.Immich.DeleteAssets(ctx, []string{r.ID}, true)
+	if err != nil {
+		return fmt.Errorf("the probe %s can't be deleted: %w", r.ID, err)
+	}
+	return nil
+}
+
+// checkClock compares the date given by the server with the local clock
+func (d *doctor) checkClock(ctx context.Context) {
+	const check = "clock"
+	start := d.now()
+	resp, err := d.get(ctx, false)
+	if err != nil {
+		d.add(check, StatusWarn, err.Error(), "")
+		return
+	}
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		d.add(check, StatusWarn, "the server doesn't give its date", "")
+		return
+	}
+	skew := date.Sub(start).Round(time.Second)
+	if skew.Abs() > d.maxSkew {
+		d.add(check, StatusWarn, fmt.Sprintf("the server's clock differs by %s", skew),
+			"Synchronize the clocks of the computer and the server with NTP, the dates of the files without metadata and the session tags depend on it")
+		return
+	}
+	d.add(check, StatusOK, fmt.Sprintf("the clocks differ by %s", skew), "")
+}
+
+func firstLine(err error) string {
+	s, _, _ := strings.Cut(strings.TrimSpace(err.Error()), "\n")
+	return s
+}
+
+func formatSize(s int64) string {
+	if s >= 1<<20 {
+		return fmt.Sprintf("%dMB", s>>20)
+	}
+	return fmt.Sprintf("%dKB", s>>10)
+}
@@ -0,0 +1,70 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/spf13/cobra"
+)
+
+type DoctorCmd struct {
+	ProbeSize int           // Size of the upload probe in MB, 0 to skip it
+	MaxSkew   time.Duration // Maximum difference between the clocks
+}
+
+// NewDoctorCommand adds the doctor command, checking the connection to the server
+func NewDoctorCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor [flags]",
+		Short: "Check the connection to the server, and tell how to fix the problems",
+		Long: `Check the connection to the server step by step: the address, the network, the TLS certificate, the API key and its permissions,
+the server version, the request size accepted by the server and its reverse proxy, and the clocks.
+The request size is checked by uploading a probe of random data, deleted right after.`,
+		Args: cobra.NoArgs,
+	}
+	o := &DoctorCmd{}
+	app.AddClientFlagsWithoutConnection(ctx, cmd, a)
+	cmd.TraverseChildren = true
+	cmd.Flags().IntVar(&o.ProbeSize, "upload-probe-size", 32, "Size of the upload probe in MB, 0 to skip the upload check")
+	cmd.Flags().DurationVar(&o.MaxSkew, "max-clock-skew", time.Minute, "Maximum difference between the clocks of the computer and the server")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client()
+		client.ClientLog = a.Log().Logger
+
+		d := &doctor{
+			client:    client,
+			probeSize: int64(o.ProbeSize) << 20,
+			maxSkew:   o.MaxSkew,
+			now:       time.Now,
+		}
+		d.run(ctx)
+		for _, r := range d.results {
+			a.Log().Info("Doctor", "check", r.Check, "status", r.Status, "detail", r.Detail)
+		}
+		writeResults(os.Stdout, d.results)
+		if n := d.failed(); n > 0 {
+			return fmt.Errorf("%d check(s) failed", n)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func writeResults(w io.Writer, results []Result) {
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.Check))
+	}
+	for _, r := range results {
+		fmt.Fprintf(w, "[%-4s] %-*s  %s\n", r.Status, width, r.Check, r.Detail)
+		if r.Remedy != "" {
+			fmt.Fprintf(w, "       %-*s  -> %s\n", width, "", r.Remedy)
+		}
+	}
+}
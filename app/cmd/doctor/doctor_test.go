@@ -0,0 +1,139 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+)
+
+// fakeServer answers the calls of the doctor. The uploads larger than maxBody are refused.
+func fakeServer(t *testing.T, key string, maxBody int64) (*httptest.Server, *[]string) {
+	deleted := []string{}
+	mux := http.NewServeMux()
+	reply := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	auth := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("x-api-key") != key {
+				w.WriteHeader(http.StatusUnauthorized)
+				reply(w, map[string]any{"message": "Invalid API key", "statusCode": 401})
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("GET /api/server/ping", func(w http.ResponseWriter, r *http.Request) { reply(w, map[string]string{"res": "pong"}) })
+	mux.HandleFunc("GET /api/users/me", auth(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]any{"id": "1", "email": "user@example.com"})
+	}))
+	mux.HandleFunc("GET /api/server/media-types", auth(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string][]string{"image": {".jpg"}, "video": {".mp4"}})
+	}))
+	mux.HandleFunc("GET /api/server/about", auth(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]string{"version": "v1.112.0"})
+	}))
+	mux.HandleFunc("GET /api/server/features", auth(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]bool{"smartSearch": true})
+	}))
+	mux.HandleFunc("POST /api/search/metadata", auth(func(w http.ResponseWriter, r *http.Request) {
+		reply(w, map[string]any{"assets": map[string]any{"items": []any{}}})
+	}))
+	mux.HandleFunc("GET /api/albums", auth(func(w http.ResponseWriter, r *http.Request) { reply(w, []any{}) }))
+	mux.HandleFunc("GET /api/tags", auth(func(w http.ResponseWriter, r *http.Request) { reply(w, []any{}) }))
+	mux.HandleFunc("GET /api/assets/statistics", auth(func(w http.ResponseWriter, r *http.Request) { reply(w, map[string]int{}) }))
+	mux.HandleFunc("GET /api/jobs", auth(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		reply(w, map[string]any{"message": "Forbidden", "statusCode": 403})
+	}))
+	mux.HandleFunc("POST /api/assets", auth(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, io.LimitReader(r.Body, maxBody+1))
+		if n > maxBody {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		reply(w, map[string]string{"id": "probe", "status": "created"})
+	}))
+	mux.HandleFunc("DELETE /api/assets", auth(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			IDs []string `json:"ids"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		deleted = append(deleted, body.IDs...)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts, &deleted
+}
+
+func TestDoctor(t *testing.T) {
+	ts, deleted := fakeServer(t, "key", 3<<20/2)
+
+	tests := []struct {
+		name string
+		key  string
+		want map[string]Status
+	}{
+		{
+			name: "limited server",
+			key:  "key",
+			want: map[string]Status{
+				"configuration":                       StatusOK,
+				"connectivity":                        StatusOK,
+				"TLS":                                 StatusWarn,
+				"server":                              StatusOK,
+				"API key":                             StatusOK,
+				"server version":                      StatusWarn,
+				"permission asset.read":               StatusOK,
+				"permission tag.read":                 StatusSkip,
+				"permission job.read (administrator)": StatusWarn,
+				"upload size":                         StatusFail,
+				"clock":                               StatusOK,
+			},
+		},
+		{
+			name: "wrong key",
+			key:  "wrong",
+			want: map[string]Status{
+				"server":         StatusOK,
+				"API key":        StatusFail,
+				"server version": StatusSkip,
+				"upload size":    StatusSkip,
+				"clock":          StatusOK,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &doctor{
+				client:    &app.Client{Server: ts.URL, APIKey: tt.key, ClientTimeout: 10 * time.Second, ClientLog: slog.New(slog.NewTextHandler(io.Discard, nil))},
+				probeSize: 2 << 20,
+				maxSkew:   time.Minute,
+				now:       time.Now,
+			}
+			d.run(context.Background())
+			got := map[string]Result{}
+			for _, r := range d.results {
+				got[r.Check] = r
+			}
+			for check, status := range tt.want {
+				if got[check].Status != status {
+					t.Errorf("%s: got %s (%s), want %s", check, got[check].Status, got[check].Detail, status)
+				}
+			}
+		})
+	}
+	if len(*deleted) != 1 || (*deleted)[0] != "probe" {
+		t.Errorf("the upload probe isn't deleted: %v", *deleted)
+	}
+}
@@ -0,0 +1,265 @@
+package fixdates
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/filenames"
+	"github.com/spf13/cobra"
+)
+
+// CameraOffsets gives the time offset to apply to the photos of a camera model.
+// It's given as model=offset, ex: "Canon EOS 5D=-1h30m"
+// Implement the interface pflag.Value
+type CameraOffsets map[string]time.Duration
+
+func (co CameraOffsets) String() string {
+	l := make([]string, 0, len(co))
+	for m, d := range co {
+		l = append(l, m+"="+d.String())
+	}
+	sort.Strings(l)
+	return strings.Join(l, ",")
+}
+
+func (co *CameraOffsets) Set(v string) error {
+	model, offset, ok := strings.Cut(v, "=")
+	model = strings.TrimSpace(model)
+	if !ok || model == "" {
+		return fmt.Errorf("invalid camera offset: %q, expected model=offset", v)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(offset))
+	if err != nil {
+		return fmt.Errorf("invalid camera offset: %q: %w", v, err)
+	}
+	if *co == nil {
+		*co = CameraOffsets{}
+	}
+	(*co)[strings.ToLower(model)] = d
+	return nil
+}
+
+func (co CameraOffsets) Type() string {
+	return "model=offset"
+}
+
+// offset returns the offset of the camera. The camera is found by its model,
+// or by its make and model
+func (co CameraOffsets) offset(cameraMake, model string) (time.Duration, bool) {
+	if model == "" {
+		return 0, false
+	}
+	if d, ok := co[strings.ToLower(model)]; ok {
+		return d, true
+	}
+	d, ok := co[strings.ToLower(strings.TrimSpace(cameraMake+" "+model))]
+	return d, ok
+}
+
+// OffsetTag marks the assets shifted by a camera offset. They are skipped by the next runs,
+// so the offset isn't applied twice.
+const OffsetTag = "{immich-go}/fix-dates/camera-offset"
+
+type FixDatesCmd struct {
+	Selection     app.AssetSelection
+	FromName      bool          // Take the date from the file name
+	FromFolder    bool          // Take the date from the folder names
+	CameraOffsets CameraOffsets // Time offset per camera model
+	Tolerance     time.Duration // Dates closer than this are left untouched
+	Apply         bool          // Update the server, otherwise preview only
+
+	ic      *filenames.InfoCollector
+	tz      *time.Location
+	shifted map[string]bool // IDs of the assets already shifted by a camera offset
+}
+
+// change is a new date for an asset
+type change struct {
+	asset  *immich.Asset
+	date   time.Time
+	source string
+}
+
+func NewFixDatesCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fix-dates [flags]",
+		Short: "Fix the capture date of the server's assets",
+		Long:  `Fix the capture date of the server's assets using the file name, the folder names, or a time offset per camera model. The changes are previewed, and applied with the option --apply`,
+	}
+	o := &FixDatesCmd{}
+	app.AddClientFlags(ctx, cmd, a, false)
+	cmd.TraverseChildren = true
+	o.Selection.AddFlags(cmd)
+	cmd.Flags().BoolVar(&o.FromName, "from-name", false, "Take the capture date from the file name (ex: IMG-20230714-WA0001.jpg)")
+	cmd.Flags().BoolVar(&o.FromFolder, "from-folder", false, "Take the capture date from the folder names of the asset's original path (ex: /photos/2023-07-14 Rome/scan01.jpg)")
+	cmd.Flags().Var(&o.CameraOffsets, "camera-offset", "Shift the capture date of the photos taken with the camera model, given as model=offset (ex: \"Canon EOS 5D=-1h30m\"). The option can be repeated")
+	cmd.Flags().DurationVar(&o.Tolerance, "tolerance", time.Minute, "Dates closer than this duration are left untouched")
+	cmd.Flags().BoolVar(&o.Apply, "apply", false, "Update the server. Without this option, the changes are only previewed")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		log := a.Log()
+
+		if !o.FromName && !o.FromFolder && len(o.CameraOffsets) == 0 {
+			return fmt.Errorf("give at least one source of dates: --from-name, --from-folder or --camera-offset")
+		}
+		o.tz = a.GetTZ()
+		o.ic = filenames.NewInfoCollector(o.tz, client.SupportedMedia())
+
+		if len(o.CameraOffsets) > 0 {
+			if err := client.Require(immich.CapabilityTags); err != nil {
+				log.Warn("the assets shifted by a camera offset can't be marked, a next run would shift them again", "err", err)
+			} else if o.shifted, err = shiftedAssets(ctx, client); err != nil {
+				return err
+			}
+		}
+
+		list, err := o.Selection.Assets(ctx, client)
+		if err != nil {
+			return err
+		}
+		changes := []change{}
+		skipped := 0
+		for _, as := range list {
+			if o.shifted[as.ID] {
+				skipped++
+				continue
+			}
+			if c, ok := o.newDate(as); ok {
+				changes = append(changes, c)
+			}
+		}
+		if skipped > 0 {
+			log.Message("%d assets already shifted by a camera offset are skipped", skipped)
+		}
+
+		for _, c := range changes {
+			fmt.Printf("%s  %s -> %s  (%s)  %s\n", c.asset.ID,
+				formatDate(c.asset.ExifInfo.DateTimeOriginal.Time, o.tz), formatDate(c.date, o.tz), c.source, c.asset.OriginalFileName)
+		}
+		if !o.Apply {
+			log.Message("%d assets out of %d would get a new date, use the option --apply to update them", len(changes), len(list))
+			return nil
+		}
+
+		updated := 0
+		shifted := []string{}
+		for _, c := range changes {
+			_, err := client.UpdateAsset(ctx, c.asset.ID, immich.UpdAssetField{DateTimeOriginal: c.date})
+			if err != nil {
+				log.Error("can't update the date", "file", c.asset.OriginalFileName, "err", err)
+				continue
+			}
+			log.Info("Date updated", "file", c.asset.OriginalFileName, "old", c.asset.ExifInfo.DateTimeOriginal.Time, "new", c.date, "source", c.source)
+			updated++
+			if c.source == sourceCameraOffset {
+				shifted = append(shifted, c.asset.ID)
+			}
+		}
+		log.Message("%d assets updated out of %d", updated, len(list))
+		if len(shifted) > 0 && o.shifted != nil {
+			return markShifted(ctx, client, shifted)
+		}
+		return nil
+	}
+	return cmd
+}
+
+const sourceCameraOffset = "camera offset"
+
+// shiftedAssets returns the IDs of the assets tagged as shifted by a camera offset
+func shiftedAssets(ctx context.Context, client immich.ImmichInterface) (map[string]bool, error) {
+	shifted := map[string]bool{}
+	tags, err := client.GetAllTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get the tags: %w", err)
+	}
+	for _, t := range tags {
+		if t.Value != OffsetTag {
+			continue
+		}
+		err = client.GetAllAssetsWithFilter(ctx, &immich.SearchMetadataQuery{TagIDs: []string{t.ID}, WithArchived: true}, func(as *immich.Asset) error {
+			shifted[as.ID] = true
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't get the shifted assets: %w", err)
+		}
+	}
+	return shifted, nil
+}
+
+// markShifted tags the assets shifted by a camera offset
+func markShifted(ctx context.Context, client immich.ImmichInterface, ids []string) error {
+	tags, err := client.UpsertTags(ctx, []string{OffsetTag})
+	if err != nil || len(tags) == 0 {
+		return fmt.Errorf("can't create the tag %s: %w", OffsetTag, err)
+	}
+	if _, err = client.TagAssets(ctx, tags[0].ID, ids); err != nil {
+		return fmt.Errorf("can't tag the shifted assets: %w", err)
+	}
+	return nil
+}
+
+// newDate returns the new date of the asset, when it differs from the current one.
+// The camera offset comes first, then the file name, then the folder names.
+func (o *FixDatesCmd) newDate(as *immich.Asset) (change, bool) {
+	current := as.ExifInfo.DateTimeOriginal.Time
+	c := change{asset: as}
+
+	if d, ok := o.CameraOffsets.offset(as.ExifInfo.Make, as.ExifInfo.Model); ok && !current.IsZero() {
+		c.date, c.source = current.Add(d), sourceCameraOffset
+	}
+	if c.date.IsZero() && o.FromName {
+		c.date, c.source = o.ic.GetInfo(as.OriginalFileName).Taken, "file name"
+	}
+	if c.date.IsZero() && o.FromFolder && as.OriginalPath != "" {
+		c.date, c.source = o.folderDate(as.OriginalPath), "folder name"
+	}
+	if c.date.IsZero() {
+		return c, false
+	}
+
+	// a name giving only the day doesn't change the time of a date of the same day
+	if c.source != sourceCameraOffset && !current.IsZero() {
+		nd, cur := c.date.In(o.tz), current.In(o.tz)
+		if isMidnight(nd) && cur.Year() == nd.Year() && cur.YearDay() == nd.YearDay() {
+			return c, false
+		}
+	}
+
+	if d := c.date.Sub(current); d > -o.Tolerance && d < o.Tolerance && !current.IsZero() {
+		return c, false
+	}
+	return c, true
+}
+
+func isMidnight(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+}
+
+// folderDate returns the date found in the folder names of the path, starting with the deepest folder.
+// The windows paths of external libraries are accepted.
+func (o *FixDatesCmd) folderDate(p string) time.Time {
+	dirs := strings.Split(path.Dir(strings.ReplaceAll(p, "\\", "/")), "/")
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if t := filenames.TakeTimeFromName(dirs[i], o.tz); !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func formatDate(t time.Time, tz *time.Location) string {
+	if t.IsZero() {
+		return "no date            "
+	}
+	return t.In(tz).Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,129 @@
+package fixdates
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+	"github.com/simulot/immich-go/internal/filenames"
+	"github.com/simulot/immich-go/internal/filetypes"
+)
+
+func TestNewDate(t *testing.T) {
+	tz := time.UTC
+	o := &FixDatesCmd{
+		FromName:   true,
+		FromFolder: true,
+		Tolerance:  time.Minute,
+		tz:         tz,
+		ic:         filenames.NewInfoCollector(tz, filetypes.DefaultSupportedMedia),
+	}
+	if err := o.CameraOffsets.Set("Canon EOS 5D=-1h30m"); err != nil {
+		t.Fatal(err)
+	}
+
+	date := func(y, m, d, h, mn int) time.Time { return time.Date(y, time.Month(m), d, h, mn, 0, 0, tz) }
+	asset := func(name, path, cameraMake, model string, current time.Time) *immich.Asset {
+		a := &immich.Asset{OriginalFileName: name, OriginalPath: path}
+		a.ExifInfo.DateTimeOriginal = immich.ImmichExifTime{Time: current}
+		a.ExifInfo.Make = cameraMake
+		a.ExifInfo.Model = model
+		return a
+	}
+
+	tests := []struct {
+		name   string
+		asset  *immich.Asset
+		want   time.Time
+		source string
+		change bool
+	}{
+		{
+			name:   "whatsapp",
+			asset:  asset("IMG-20230714-WA0001.jpg", "", "", "", date(2024, 1, 3, 18, 0)),
+			want:   date(2023, 7, 14, 0, 0),
+			source: "file name",
+			change: true,
+		},
+		{
+			name:  "same day",
+			asset: asset("IMG-20230714-WA0001.jpg", "", "", "", date(2023, 7, 14, 18, 0)),
+		},
+		{
+			name:  "within tolerance",
+			asset: asset("PXL_20230714_101520123.jpg", "", "", "", date(2023, 7, 14, 10, 15)),
+		},
+		{
+			name:   "folder",
+			asset:  asset("scan01.jpg", `D:\photos\2001-05-20 Wedding\scan01.jpg`, "", "", date(2024, 1, 3, 18, 0)),
+			want:   date(2001, 5, 20, 0, 0),
+			source: "folder name",
+			change: true,
+		},
+		{
+			name:   "camera",
+			asset:  asset("IMG_0001.JPG", "", "Canon", "Canon EOS 5D", date(2023, 7, 14, 12, 0)),
+			want:   date(2023, 7, 14, 10, 30),
+			source: "camera offset",
+			change: true,
+		},
+		{
+			name:  "nothing",
+			asset: asset("IMG_0001.JPG", "/photos/holidays/IMG_0001.JPG", "Sony", "A7", date(2023, 7, 14, 12, 0)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := o.newDate(tt.asset)
+			if ok != tt.change {
+				t.Fatalf("change = %v, want %v (%s %s)", ok, tt.change, c.date, c.source)
+			}
+			if !ok {
+				return
+			}
+			if !c.date.Equal(tt.want) || c.source != tt.source {
+				t.Errorf("got %s (%s), want %s (%s)", c.date, c.source, tt.want, tt.source)
+			}
+		})
+	}
+}
+
+func TestCameraOffsets(t *testing.T) {
+	var co CameraOffsets
+	if err := co.Set("NIKON D700=+2h"); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := co.offset("NIKON CORPORATION", "nikon d700"); !ok || d != 2*time.Hour {
+		t.Errorf("offset = %s, %v", d, ok)
+	}
+	for _, v := range []string{"=1h", "D700", "D700=two hours"} {
+		if err := co.Set(v); err == nil {
+			t.Errorf("Set(%q) must fail", v)
+		}
+	}
+}
+
+func TestShiftedAssets(t *testing.T) {
+	s := fakeimmich.NewServer()
+	defer s.Close()
+	shifted := s.AddAsset("IMG_0001.JPG", []byte("shifted"), time.Now())
+	other := s.AddAsset("IMG_0002.JPG", []byte("other"), time.Now())
+
+	ctx := context.Background()
+	client, err := immich.NewImmichClient(s.URL, s.APIKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = markShifted(ctx, client, []string{shifted}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := shiftedAssets(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got[shifted] || got[other] || len(got) != 1 {
+		t.Errorf("shifted assets = %v, want only %s", got, shifted)
+	}
+}
@@ -0,0 +1,105 @@
+package geotag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/geotrack"
+	"github.com/spf13/cobra"
+)
+
+type GeotagCmd struct {
+	Selection app.AssetSelection
+	Options   geotrack.Options
+	Apply     bool // Update the server, otherwise preview only
+}
+
+// NewGeotagCommand adds the geotag command, to set the position of the server's assets from GPS tracks
+func NewGeotagCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "geotag [flags] [track files...]",
+		Short: "Set the position of the server's assets without GPS data using GPX or KML tracks",
+		Long:  `Set the position of the server's assets without GPS data. The position is interpolated from the points of the GPX or KML tracks recorded around the capture date. The changes are previewed, and applied with the option --apply`,
+	}
+	o := &GeotagCmd{}
+	app.AddClientFlags(ctx, cmd, a, false)
+	cmd.TraverseChildren = true
+	o.Selection.AddFlags(cmd)
+	geotrack.AddFlags(cmd.Flags(), &o.Options)
+	cmd.Flags().BoolVar(&o.Apply, "apply", false, "Update the server. Without this option, the changes are only previewed")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		log := a.Log()
+
+		o.Options.Files = append(o.Options.Files, args...)
+		if len(o.Options.Files) == 0 {
+			return errors.New("give the track files with the option --gpx or as arguments")
+		}
+		g, err := geotrack.NewGeotagger(o.Options)
+		if err != nil {
+			return err
+		}
+		first, last := g.Track().Span()
+		log.Message("%d track points from %s to %s", g.Track().Len(), first.In(a.GetTZ()).Format("2006-01-02 15:04"), last.In(a.GetTZ()).Format("2006-01-02 15:04"))
+
+		list, err := o.Selection.Assets(ctx, client)
+		if err != nil {
+			return err
+		}
+		matched, unmatched := locateAssets(g, list)
+		for _, m := range matched {
+			fmt.Printf("%s  %s  %.6f,%.6f  %s\n", m.asset.ID, m.asset.ExifInfo.DateTimeOriginal.In(a.GetTZ()).Format("2006-01-02 15:04:05"),
+				m.point.Latitude, m.point.Longitude, m.asset.OriginalFileName)
+		}
+		for _, as := range unmatched {
+			log.Info("no track point near the capture date", "file", as.OriginalFileName, "date", as.ExifInfo.DateTimeOriginal.Time)
+		}
+		if !o.Apply {
+			log.Message("%d assets without position would be geotagged, %d have no track point near their capture date. Use the option --apply to update them", len(matched), len(unmatched))
+			return nil
+		}
+
+		updated := 0
+		for _, m := range matched {
+			_, err := client.UpdateAsset(ctx, m.asset.ID, immich.UpdAssetField{Latitude: m.point.Latitude, Longitude: m.point.Longitude})
+			if err != nil {
+				log.Error("can't update the position", "file", m.asset.OriginalFileName, "err", err)
+				continue
+			}
+			log.Info("Position updated", "file", m.asset.OriginalFileName, "latitude", m.point.Latitude, "longitude", m.point.Longitude)
+			updated++
+		}
+		log.Message("%d assets geotagged, %d have no track point near their capture date", updated, len(unmatched))
+		return nil
+	}
+	return cmd
+}
+
+// match is the position found for an asset
+type match struct {
+	asset *immich.Asset
+	point geotrack.Point
+}
+
+// locateAssets finds the position of the assets without GPS data.
+// The assets without capture date or with a position are ignored.
+func locateAssets(g *geotrack.Geotagger, list []*immich.Asset) ([]match, []*immich.Asset) {
+	matched := []match{}
+	unmatched := []*immich.Asset{}
+	for _, as := range list {
+		if as.ExifInfo.Latitude != 0 || as.ExifInfo.Longitude != 0 || as.ExifInfo.DateTimeOriginal.IsZero() {
+			continue
+		}
+		if p, ok := g.Locate(as.ExifInfo.DateTimeOriginal.Time); ok {
+			matched = append(matched, match{asset: as, point: p})
+		} else {
+			unmatched = append(unmatched, as)
+		}
+	}
+	return matched, unmatched
+}
@@ -0,0 +1,49 @@
+package geotag
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/geotrack"
+)
+
+const track = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1"><trk><trkseg>
+  <trkpt lat="48.8584" lon="2.2945"><time>2023-07-14T10:00:00Z</time></trkpt>
+  <trkpt lat="48.8606" lon="2.3376"><time>2023-07-14T10:10:00Z</time></trkpt>
+</trkseg></trk></gpx>`
+
+func TestLocateAssets(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "track.gpx")
+	if err := os.WriteFile(name, []byte(track), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	g, err := geotrack.NewGeotagger(geotrack.Options{Files: []string{name}, MaxGap: 10 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	asset := func(id string, date time.Time, lat, lon float64) *immich.Asset {
+		a := &immich.Asset{ID: id}
+		a.ExifInfo.DateTimeOriginal = immich.ImmichExifTime{Time: date}
+		a.ExifInfo.Latitude, a.ExifInfo.Longitude = lat, lon
+		return a
+	}
+	list := []*immich.Asset{
+		asset("in track", time.Date(2023, 7, 14, 10, 5, 0, 0, time.UTC), 0, 0),
+		asset("with position", time.Date(2023, 7, 14, 10, 5, 0, 0, time.UTC), 45, 5),
+		asset("no date", time.Time{}, 0, 0),
+		asset("out of track", time.Date(2023, 7, 15, 10, 5, 0, 0, time.UTC), 0, 0),
+	}
+
+	matched, unmatched := locateAssets(g, list)
+	if len(matched) != 1 || matched[0].asset.ID != "in track" {
+		t.Errorf("unexpected matched assets: %+v", matched)
+	}
+	if len(unmatched) != 1 || unmatched[0].ID != "out of track" {
+		t.Errorf("unexpected unmatched assets: %+v", unmatched)
+	}
+}
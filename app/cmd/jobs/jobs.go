@@ -0,0 +1,250 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/serverjobs"
+	"github.com/spf13/cobra"
+)
+
+// ExitTimeout is the exit code when the jobs are still running at the end of the --timeout
+const ExitTimeout = 2
+
+type JobsOptions struct {
+	JSON     bool          // JSON output
+	Timeout  time.Duration // Maximum wait, 0 for no limit
+	Interval time.Duration // Time between two checks of the queues
+}
+
+// validateWait checks the options of the wait
+func (o *JobsOptions) validateWait() error {
+	if o.Interval <= 0 {
+		return fmt.Errorf("the --interval must be positive, got %s", o.Interval)
+	}
+	if o.Timeout < 0 {
+		return fmt.Errorf("the --timeout can't be negative, got %s", o.Timeout)
+	}
+	return nil
+}
+
+// NewJobsCommand adds the jobs command, to control the job queues of the server
+func NewJobsCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "jobs",
+		Short: "List, pause, resume, start the server's job queues, or wait for their completion",
+		Long: `List, pause, resume, start the server's job queues, or wait for their completion.
+The queues are given by their names as listed by the list sub-command, like thumbnailGeneration or metadataExtraction.
+The name "heavy" stands for the queues using most of the server's resources during an import, and "all" for all the queues.`,
+	}
+	o := &JobsOptions{}
+	app.AddClientFlags(ctx, cmd, a, false)
+	cmd.TraverseChildren = true
+	cmd.PersistentFlags().BoolVar(&o.JSON, "json", false, "Write the result as JSON")
+	cmd.PersistentFlags().DurationVar(&o.Timeout, "timeout", 0, "Maximum time to wait for the jobs, 0 for no limit")
+	cmd.PersistentFlags().DurationVar(&o.Interval, "interval", 5*time.Second, "Time between two checks of the job queues while waiting")
+
+	cmd.AddCommand(newListCommand(ctx, a, o))
+	cmd.AddCommand(newSendCommand(ctx, a, o, immich.Pause, "pause <queue>...", "Pause the job queues"))
+	cmd.AddCommand(newSendCommand(ctx, a, o, immich.Resume, "resume <queue>...", "Resume the job queues"))
+	cmd.AddCommand(newSendCommand(ctx, a, o, immich.Start, "start <queue>...", "Start the job queues on the assets not yet processed, or on all the assets with --force"))
+	cmd.AddCommand(newWaitCommand(ctx, a, o))
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		return errors.New("you must specify a subcommand to the jobs command")
+	}
+	return cmd
+}
+
+func newListCommand(ctx context.Context, a *app.Application, o *JobsOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the job queues and their counters",
+		Args:  cobra.NoArgs,
+	}
+	cmd.SetContext(ctx)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		jobs, err := a.Client().Immich.GetJobs(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("can't get the jobs: %w", err)
+		}
+		if o.JSON {
+			return writeJSON(jobs)
+		}
+		printJobs(jobs)
+		return nil
+	}
+	return cmd
+}
+
+func newSendCommand(ctx context.Context, a *app.Application, o *JobsOptions, command immich.JobCommand, use, short string) *cobra.Command {
+	var force, wait bool
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.MinimumNArgs(1),
+	}
+	cmd.SetContext(ctx)
+	if command == immich.Start {
+		cmd.Flags().BoolVar(&force, "force", false, "Process all the assets, not only the ones not yet processed")
+	}
+	if command != immich.Pause {
+		cmd.Flags().BoolVar(&wait, "wait", false, "Wait for the completion of the queues")
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		if wait {
+			if err := o.validateWait(); err != nil {
+				return err
+			}
+		}
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		jobs, err := client.GetJobs(ctx)
+		if err != nil {
+			return fmt.Errorf("can't get the jobs: %w", err)
+		}
+		ids, err := queueIDs(jobs, args)
+		if err != nil {
+			return err
+		}
+
+		results := map[string]immich.SendJobCommandResponse{}
+		for _, id := range ids {
+			r, err := client.SendJobCommand(ctx, id, command, force)
+			if err != nil {
+				return fmt.Errorf("can't %s the job queue %q: %w", command, id, err)
+			}
+			results[string(id)] = r
+			a.Log().Info("Job command sent", "queue", id, "command", command, "force", force)
+			if !o.JSON {
+				fmt.Printf("%-26s %s\n", id, command)
+			}
+		}
+		if o.JSON {
+			err = writeJSON(results)
+			if err != nil {
+				return err
+			}
+		}
+		if wait {
+			return waitJobs(ctx, a, o, ids...)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func newWaitCommand(ctx context.Context, a *app.Application, o *JobsOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wait [queue...]",
+		Short: "Wait until the job queues are idle. All the running queues are watched when none is given",
+	}
+	cmd.SetContext(ctx)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		if err := o.validateWait(); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		var ids []immich.JobID
+		if len(args) > 0 {
+			jobs, err := a.Client().Immich.GetJobs(ctx)
+			if err != nil {
+				return fmt.Errorf("can't get the jobs: %w", err)
+			}
+			ids, err = queueIDs(jobs, args)
+			if err != nil {
+				return err
+			}
+		}
+		return waitJobs(ctx, a, o, ids...)
+	}
+	return cmd
+}
+
+// waitJobs waits for the queues, and reports the progression
+func waitJobs(ctx context.Context, a *app.Application, o *JobsOptions, ids ...immich.JobID) error {
+	start := time.Now()
+	err := serverjobs.Wait(ctx, a.Client().Immich, o.Interval, o.Timeout, func(busy map[string]immich.Job) {
+		for _, n := range serverjobs.Names(busy) {
+			j := busy[n]
+			a.Log().Info("Waiting for the jobs", "queue", n, "active", j.JobCounts.Active, "waiting", j.JobCounts.Waiting, "delayed", j.JobCounts.Delayed)
+		}
+	}, ids...)
+	if errors.Is(err, serverjobs.ErrTimeout) {
+		return &app.ExitError{Code: ExitTimeout, Err: fmt.Errorf("the jobs are still running after %s", o.Timeout)}
+	}
+	if err != nil {
+		return err
+	}
+	a.Log().Info("The jobs are completed", "duration", time.Since(start).Round(time.Second))
+	if o.JSON {
+		jobs, err := a.Client().Immich.GetJobs(ctx)
+		if err != nil {
+			return fmt.Errorf("can't get the jobs: %w", err)
+		}
+		return writeJSON(jobs)
+	}
+	fmt.Printf("The jobs are completed after %s\n", time.Since(start).Round(time.Second))
+	return nil
+}
+
+// queueIDs checks the queue names given by the user. The names "all" and "heavy" are expanded.
+func queueIDs(jobs map[string]immich.Job, args []string) ([]immich.JobID, error) {
+	ids := []immich.JobID{}
+	seen := map[immich.JobID]bool{}
+	add := func(l ...immich.JobID) {
+		for _, id := range l {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	for _, arg := range args {
+		switch arg {
+		case "all":
+			for _, n := range serverjobs.Names(jobs) {
+				add(immich.JobID(n))
+			}
+		case "heavy":
+			for _, id := range serverjobs.HeavyQueues {
+				if _, ok := jobs[string(id)]; ok {
+					add(id)
+				}
+			}
+		default:
+			add(immich.JobID(arg))
+		}
+	}
+	return ids, serverjobs.Check(jobs, ids...)
+}
+
+func printJobs(jobs map[string]immich.Job) {
+	fmt.Printf("%-26s %-7s %8s %8s %8s %8s %10s\n", "QUEUE", "STATUS", "ACTIVE", "WAITING", "DELAYED", "FAILED", "COMPLETED")
+	for _, n := range serverjobs.Names(jobs) {
+		j := jobs[n]
+		status := "idle"
+		switch {
+		case j.QueueStatus.IsPaused:
+			status = "paused"
+		case !serverjobs.IsIdle(j):
+			status = "active"
+		}
+		fmt.Printf("%-26s %-7s %8d %8d %8d %8d %10d\n", n, status, j.JobCounts.Active, j.JobCounts.Waiting, j.JobCounts.Delayed, j.JobCounts.Failed, j.JobCounts.Completed)
+	}
+}
+
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
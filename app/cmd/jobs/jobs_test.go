@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestQueueIDs(t *testing.T) {
+	jobs := map[string]immich.Job{
+		"thumbnailGeneration": {},
+		"metadataExtraction":  {},
+		"smartSearch":         {},
+	}
+	tests := []struct {
+		args    []string
+		want    []immich.JobID
+		wantErr bool
+	}{
+		{args: []string{"metadataExtraction"}, want: []immich.JobID{"metadataExtraction"}},
+		{args: []string{"heavy", "thumbnailGeneration"}, want: []immich.JobID{"thumbnailGeneration", "smartSearch"}},
+		{args: []string{"all"}, want: []immich.JobID{"metadataExtraction", "smartSearch", "thumbnailGeneration"}},
+		{args: []string{"unknown"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := queueIDs(jobs, tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("queueIDs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !slices.Equal(got, tt.want) {
+			t.Errorf("queueIDs(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWait(t *testing.T) {
+	tests := []struct {
+		o       JobsOptions
+		wantErr bool
+	}{
+		{o: JobsOptions{Interval: 5 * time.Second}},
+		{o: JobsOptions{Interval: time.Second, Timeout: time.Minute}},
+		{o: JobsOptions{Interval: 0}, wantErr: true},
+		{o: JobsOptions{Interval: -time.Second}, wantErr: true},
+		{o: JobsOptions{Interval: time.Second, Timeout: -time.Minute}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.o.validateWait(); (err != nil) != tt.wantErr {
+			t.Errorf("validateWait(%+v) error = %v, wantErr %v", tt.o, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package login
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/configuration"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+type LoginCmd struct {
+	Email         string        // User's email
+	Password      string        // User's password, asked when not given
+	OAuth         bool          // Log in with the OAuth provider of the server
+	OAuthRedirect string        // Local address receiving the OAuth callback
+	OAuthTimeout  time.Duration // Time given to the user to log in on the provider's page
+	MintAPIKey    bool          // Create an API key for the profile
+	ReplaceAPIKey bool          // Remove the API key of the profile not minted by a login
+	APIKeyName    string        // Name of the minted API key
+	Permissions   []string      // Permissions of the minted API key
+
+	message func(format string, args ...any) // Prints the instructions
+	stdin   io.Reader                        // Source of the password when it isn't given
+}
+
+// NewLoginCommand adds the login command, opening a session stored in the profile
+func NewLoginCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "login [flags]",
+		Short: "Open a session on the server with an email and a password, or with OAuth, and save it in the profile",
+		Long: `Open a session on the server with the user's email and password, or with the OAuth provider of the server.
+The session token is saved in the profile, and used by the other commands when no API key is given.
+With --mint-api-key, an API key is created for the profile. It's revoked by the logout command.`,
+		Args: cobra.NoArgs,
+	}
+	o := &LoginCmd{}
+	client := a.Client()
+	hostname, _ := os.Hostname()
+
+	cmd.Flags().StringVarP(&client.Server, "server", "s", client.Server, "Immich server address (example http://your-ip:2283 or https://your-domain)")
+	cmd.Flags().BoolVar(&client.SkipSSL, "skip-verify-ssl", false, "Skip SSL verification")
+	client.AddNetworkFlags(cmd.Flags(), "", configuration.DefaultProfile)
+	cmd.Flags().StringVar(&o.Email, "email", "", "User's email")
+	cmd.Flags().StringVar(&o.Password, "password", "", "User's password, read from $IMMICH_PASSWORD or asked when not given")
+	cmd.Flags().BoolVar(&o.OAuth, "oauth", false, "Log in with the OAuth provider of the server")
+	cmd.Flags().StringVar(&o.OAuthRedirect, "oauth-redirect", "http://127.0.0.1:33123/oauth-callback", "Local address receiving the OAuth callback, to allow in the provider's settings")
+	cmd.Flags().DurationVar(&o.OAuthTimeout, "oauth-timeout", 5*time.Minute, "Time given to log in on the provider's page")
+	cmd.Flags().BoolVar(&o.MintAPIKey, "mint-api-key", false, "Create an API key for the profile, revoked by the logout command")
+	cmd.Flags().BoolVar(&o.ReplaceAPIKey, "replace-api-key", false, "Remove the API key of the profile not created by a login")
+	cmd.Flags().StringVar(&o.APIKeyName, "api-key-name", "immich-go "+hostname, "Name of the minted API key")
+	cmd.Flags().StringSliceVar(&o.Permissions, "api-key-permissions", nil, "Permissions of the minted API key (example asset.upload,album.create), all when not given")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		// the login can create a new profile
+		profile := client.Profile
+		if _, err := configuration.ReadProfile(client.ConfigFile, profile); err != nil {
+			client.Profile = ""
+		}
+		err := client.ApplyProfile(cmd.Flags().Changed)
+		client.Profile = profile
+		if err != nil {
+			return err
+		}
+		o.message = a.Log().Message
+		o.stdin = os.Stdin
+		return o.run(ctx, client)
+	}
+	return cmd
+}
+
+func (o *LoginCmd) run(ctx context.Context, client *app.Client) error {
+	if client.Server == "" {
+		return errors.New("missing the parameter --server, Immich server address (http://<your-ip>:2283 or https://<your-domain>)")
+	}
+	previous := readProfile(client.ConfigFile, client.Profile)
+	// a key given by the user isn't known by immich-go, it can't be recovered once removed
+	userKey := previous.APIKey != "" && previous.APIKeyID == ""
+	if userKey && o.MintAPIKey && !o.ReplaceAPIKey {
+		return fmt.Errorf("the profile %q has an API key not created by a login, use --replace-api-key to replace it", profileName(client.Profile))
+	}
+
+	transport, err := client.TransportOptions()
+	if err != nil {
+		return err
+	}
+	ic, err := immich.NewImmichClient(client.Server, "", immich.OptionTransport(transport))
+	if err != nil {
+		return err
+	}
+
+	var session immich.LoginResponse
+	if o.OAuth {
+		session, err = o.oauthLogin(ctx, ic)
+	} else {
+		session, err = o.passwordLogin(ctx, ic)
+	}
+	if err != nil {
+		return fmt.Errorf("can't log in: %w", err)
+	}
+
+	ic, err = immich.NewImmichClient(client.Server, "", immich.OptionTransport(transport), immich.OptionAccessToken(session.AccessToken))
+	if err != nil {
+		return err
+	}
+	p := previous
+	p.Server = client.Server
+	p.SkipSSL = client.SkipSSL
+	p.Proxy = client.Proxy
+	p.CACert = client.CACert
+	p.ClientCert = client.ClientCert
+	p.ClientKey = client.ClientKey
+	p.Headers = nil
+	if len(transport.Headers) > 0 {
+		p.Headers = transport.Headers
+	}
+	p.AccessToken = session.AccessToken
+	if !userKey || o.ReplaceAPIKey {
+		p.APIKey, p.APIKeyID = "", ""
+	}
+
+	if o.MintAPIKey {
+		key, secret, err := ic.CreateAPIKey(ctx, o.APIKeyName, o.Permissions)
+		if err != nil {
+			return fmt.Errorf("can't create the API key: %w", err)
+		}
+		p.APIKey, p.APIKeyID = secret, key.ID
+		o.message("API key %q created", key.Name)
+	}
+	// The key minted by a previous login is replaced by the new one
+	if previous.APIKeyID != "" && previous.APIKeyID != p.APIKeyID {
+		if err := ic.DeleteAPIKey(ctx, previous.APIKeyID); err != nil {
+			o.message("Can't revoke the previous API key: %s", err)
+		}
+	}
+
+	err = configuration.WriteProfile(client.ConfigFile, client.Profile, p)
+	if err != nil {
+		return err
+	}
+	if userKey && p.APIKey == previous.APIKey {
+		o.message("The profile keeps its API key, used by the other commands instead of the session. Use --replace-api-key to remove it")
+	}
+	o.message("Logged in as %s, the session is saved in the profile %q of %s", session.UserEmail, profileName(client.Profile), client.ConfigFile)
+	if session.ShouldChangePassword {
+		o.message("The server asks to change the password")
+	}
+	return nil
+}
+
+func (o *LoginCmd) passwordLogin(ctx context.Context, ic *immich.ImmichClient) (immich.LoginResponse, error) {
+	if o.Email == "" {
+		return immich.LoginResponse{}, errors.New("missing the parameter --email, or use --oauth")
+	}
+	password := o.Password
+	if password == "" {
+		password = os.Getenv("IMMICH_PASSWORD")
+	}
+	if password == "" {
+		var err error
+		password, err = o.askPassword()
+		if err != nil {
+			return immich.LoginResponse{}, err
+		}
+	}
+	return ic.Login(ctx, o.Email, password)
+}
+
+// askPassword reads the password on the terminal without echo, or on the first line of the standard input
+func (o *LoginCmd) askPassword() (string, error) {
+	if f, ok := o.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprintf(os.Stderr, "Password for %s: ", o.Email)
+		b, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(b), err
+	}
+	s, err := bufio.NewReader(o.stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(s, "\r\n"), nil
+}
+
+// oauthLogin gets the provider's page from the server, and waits for the browser to come back
+// on the local redirect address with the authorization code.
+func (o *LoginCmd) oauthLogin(ctx context.Context, ic *immich.ImmichClient) (immich.LoginResponse, error) {
+	redirect, err := url.Parse(o.OAuthRedirect)
+	if err != nil || redirect.Host == "" {
+		return immich.LoginResponse{}, fmt.Errorf("invalid OAuth redirect address %q", o.OAuthRedirect)
+	}
+	l, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return immich.LoginResponse{}, err
+	}
+	defer l.Close()
+
+	authorizeURL, err := ic.OAuthAuthorize(ctx, o.OAuthRedirect)
+	if err != nil {
+		return immich.LoginResponse{}, err
+	}
+
+	callback := make(chan string, 1)
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != redirect.Path {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintln(w, "immich-go has received the authorization, you can close this page.")
+			u := *redirect
+			u.RawQuery = r.URL.RawQuery
+			select {
+			case callback <- u.String():
+			default:
+			}
+		}),
+	}
+	go func() { _ = server.Serve(l) }()
+	defer server.Close()
+
+	o.message("Open this page in your browser to log in:\n%s", authorizeURL)
+	select {
+	case <-ctx.Done():
+		return immich.LoginResponse{}, ctx.Err()
+	case <-time.After(o.OAuthTimeout):
+		return immich.LoginResponse{}, errors.New("the OAuth login has timed out")
+	case u := <-callback:
+		return ic.OAuthCallback(ctx, u)
+	}
+}
+
+// readProfile gets the current settings of the profile, an empty one when it doesn't exist yet
+func readProfile(file, name string) configuration.Profile {
+	c, err := configuration.ConfigRead(file)
+	if err != nil {
+		return configuration.Profile{}
+	}
+	return c.Profiles[profileName(name)]
+}
+
+func profileName(name string) string {
+	if name == "" {
+		return configuration.DefaultProfile
+	}
+	return name
+}
@@ -0,0 +1,127 @@
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/configuration"
+)
+
+// fakeServer accepts the password "demo", and records the revoked keys and closed sessions
+func fakeServer() (*httptest.Server, *[]string) {
+	calls := []string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["password"] != "demo" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"Incorrect email or password","statusCode":401}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"accessToken": "token-" + body["email"], "userEmail": body["email"]})
+	})
+	mux.HandleFunc("POST /api/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"secret":"secret","apiKey":{"id":"key-1","name":"immich-go"}}`))
+	})
+	mux.HandleFunc("DELETE /api/api-keys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "revoke "+r.PathValue("id")+" "+r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /api/auth/logout", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "logout "+r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"successful":true}`))
+	})
+	return httptest.NewServer(mux), &calls
+}
+
+func TestLoginLogout(t *testing.T) {
+	server, calls := fakeServer()
+	defer server.Close()
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "immich-go.json")
+	messages := []string{}
+	message := func(format string, args ...any) { messages = append(messages, format) }
+
+	client := &app.Client{Server: server.URL, ConfigFile: file, Profile: "home", Headers: []string{"CF-Access-Client-Id: id"}}
+	login := &LoginCmd{Email: "demo@immich.app", stdin: strings.NewReader("wrong\n"), message: message}
+	if err := login.run(ctx, client); err == nil {
+		t.Fatalf("the wrong password should be refused")
+	}
+	login.stdin = strings.NewReader("demo\n")
+	login.MintAPIKey = true
+	if err := login.run(ctx, client); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	p, err := configuration.ReadProfile(file, "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Server != server.URL || p.AccessToken != "token-demo@immich.app" || p.APIKey != "secret" || p.APIKeyID != "key-1" || p.Headers["CF-Access-Client-Id"] != "id" {
+		t.Errorf("the profile isn't saved: %+v", p)
+	}
+
+	logout := &LogoutCmd{message: message}
+	if err := logout.run(ctx, &app.Client{ConfigFile: file, Profile: "home"}); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	p, _ = configuration.ReadProfile(file, "home")
+	if p.AccessToken != "" || p.APIKey != "" || p.APIKeyID != "" || p.Server != server.URL {
+		t.Errorf("the credentials are still in the profile: %+v", p)
+	}
+	want := []string{"revoke key-1 Bearer token-demo@immich.app", "logout Bearer token-demo@immich.app"}
+	if strings.Join(*calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %q, want %q", *calls, want)
+	}
+}
+
+func TestLoginKeepsUserAPIKey(t *testing.T) {
+	server, calls := fakeServer()
+	defer server.Close()
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "immich-go.json")
+	message := func(format string, args ...any) {}
+	err := configuration.WriteProfile(file, "home", configuration.Profile{Server: server.URL, APIKey: "user-key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &app.Client{Server: server.URL, ConfigFile: file, Profile: "home"}
+
+	login := &LoginCmd{Email: "demo@immich.app", Password: "demo", message: message}
+	if err := login.run(ctx, client); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	p, _ := configuration.ReadProfile(file, "home")
+	if p.APIKey != "user-key" || p.AccessToken == "" {
+		t.Errorf("the user's API key should be kept: %+v", p)
+	}
+
+	login.MintAPIKey = true
+	if err := login.run(ctx, client); err == nil {
+		t.Error("minting a key should be refused without --replace-api-key")
+	}
+	p, _ = configuration.ReadProfile(file, "home")
+	if p.APIKey != "user-key" {
+		t.Errorf("the user's API key should be kept: %+v", p)
+	}
+
+	login.ReplaceAPIKey = true
+	if err := login.run(ctx, client); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	p, _ = configuration.ReadProfile(file, "home")
+	if p.APIKey != "secret" || p.APIKeyID != "key-1" {
+		t.Errorf("the user's API key should be replaced: %+v", p)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("the user's API key must not be revoked: %q", *calls)
+	}
+}
@@ -0,0 +1,92 @@
+package login
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/configuration"
+	"github.com/spf13/cobra"
+)
+
+type LogoutCmd struct {
+	KeepAPIKey bool // Keep the API key minted by the login
+
+	message func(format string, args ...any)
+}
+
+// NewLogoutCommand adds the logout command, closing the session of the profile and revoking its API key
+func NewLogoutCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logout [flags]",
+		Short: "Close the session saved in the profile, and revoke the API key minted by the login",
+		Args:  cobra.NoArgs,
+	}
+	o := &LogoutCmd{}
+	client := a.Client()
+	client.AddNetworkFlags(cmd.Flags(), "", configuration.DefaultProfile)
+	cmd.Flags().BoolVar(&o.KeepAPIKey, "keep-api-key", false, "Keep the API key minted by the login in the profile")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		err := client.ApplyProfile(cmd.Flags().Changed)
+		if err != nil {
+			return err
+		}
+		o.message = a.Log().Message
+		return o.run(ctx, client)
+	}
+	return cmd
+}
+
+func (o *LogoutCmd) run(ctx context.Context, client *app.Client) error {
+	p, err := configuration.ReadProfile(client.ConfigFile, client.Profile)
+	if err != nil {
+		return err
+	}
+	revokeKey := p.APIKeyID != "" && !o.KeepAPIKey
+	if p.AccessToken == "" && !revokeKey {
+		o.message("No session opened in the profile %q", profileName(client.Profile))
+		return nil
+	}
+
+	transport, err := client.TransportOptions()
+	if err != nil {
+		return err
+	}
+	// The session token is used first: the minted key can't close the session
+	key := ""
+	if p.AccessToken == "" {
+		key = p.APIKey
+	}
+	ic, err := immich.NewImmichClient(p.Server, key, immich.OptionTransport(transport), immich.OptionAccessToken(p.AccessToken))
+	if err != nil {
+		return err
+	}
+
+	// A failure on the server doesn't keep the credentials in the profile, but the user is told
+	// to revoke them on the Immich web page.
+	if revokeKey {
+		if err := ic.DeleteAPIKey(ctx, p.APIKeyID); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't revoke the API key %s, remove it in the account settings of the Immich web page: %s\n", p.APIKeyID, err)
+		} else {
+			o.message("API key revoked")
+		}
+		p.APIKey, p.APIKeyID = "", ""
+	}
+	if p.AccessToken != "" {
+		if err := ic.Logout(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Can't close the session on the server: %s\n", err)
+		}
+		p.AccessToken = ""
+	}
+
+	err = configuration.WriteProfile(client.ConfigFile, client.Profile, p)
+	if err != nil {
+		return err
+	}
+	o.message("Logged out, the profile %q is updated", profileName(client.Profile))
+	return nil
+}
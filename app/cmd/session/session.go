@@ -0,0 +1,315 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/ui"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// sessionTagRoot is the parent of the tags given by the option --session-tag
+const sessionTagRoot = "{immich-go}"
+
+// NewSessionCommand adds the session command, to act on the assets uploaded with the option --session-tag
+func NewSessionCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "List, show or rollback the upload sessions tagged with --session-tag",
+	}
+	app.AddClientFlags(ctx, cmd, a, false)
+	cmd.PersistentPreRunE = app.ChainRunEFunctions(cmd.PersistentPreRunE, app.RequireCapabilities(immich.CapabilityTags), ctx, cmd, a)
+	cmd.TraverseChildren = true
+
+	cmd.AddCommand(newListCommand(ctx, a))
+	cmd.AddCommand(newShowCommand(ctx, a))
+	cmd.AddCommand(newRollbackCommand(ctx, a))
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		return errors.New("you must specify a subcommand to the session command")
+	}
+	return cmd
+}
+
+func newListCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the upload sessions with their number of assets",
+		Args:  cobra.NoArgs,
+	}
+	cmd.SetContext(ctx)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		out := cmd.OutOrStdout()
+
+		tags, err := getSessionTags(ctx, client)
+		if err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			fmt.Fprintln(out, "No session found")
+			return nil
+		}
+		fmt.Fprintf(out, "%-25s %8s\n", "Session", "Assets")
+		for _, t := range tags {
+			ids, err := getSessionAssets(ctx, client, t)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%-25s %8d\n", t.Name, len(ids))
+		}
+		return nil
+	}
+	return cmd
+}
+
+func newShowCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <session>",
+		Short: "List the assets and the albums of an upload session",
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.SetContext(ctx)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		s, err := loadSession(cmd.Context(), a.Client().Immich, args[0])
+		if err != nil {
+			return err
+		}
+		s.print(cmd.OutOrStdout())
+		return nil
+	}
+	return cmd
+}
+
+func newRollbackCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	var toTrash, yes bool
+	cmd := &cobra.Command{
+		Use:   "rollback <session>",
+		Short: "Delete the assets of an upload session, the albums left empty, and the session tag",
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.SetContext(ctx)
+	cmd.Flags().BoolVar(&toTrash, "trash", false, "Move the assets to the trash instead of deleting them")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Rollback without asking for a confirmation")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		log := a.Log()
+		dryRun := a.Client().DryRun
+		out := cmd.OutOrStdout()
+
+		s, err := loadSession(ctx, client, args[0])
+		if err != nil {
+			return err
+		}
+
+		// preview of the rollback
+		s.print(out)
+		action := "deleted"
+		if toTrash {
+			action = "moved to the trash"
+		}
+		empty := 0
+		for _, al := range s.albums {
+			if al.empty() {
+				empty++
+			}
+		}
+		fmt.Fprintf(out, "\nThe %d assets will be %s. The %d albums having only assets of the session, and the session tag will be deleted.\n", len(s.assets), action, empty)
+		if !dryRun && !yes {
+			ok, err := confirm(ctx, cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			if !ok {
+				log.Message("Rollback cancelled")
+				return nil
+			}
+		}
+
+		ids := make([]string, len(s.assets))
+		for i, as := range s.assets {
+			ids[i] = as.ID
+		}
+		const batchSize = 500
+		for start := 0; start < len(ids); start += batchSize {
+			end := min(start+batchSize, len(ids))
+			err = client.DeleteAssets(ctx, ids[start:end], !toTrash)
+			if err != nil {
+				return fmt.Errorf("can't delete the assets of the session: %w", err)
+			}
+		}
+		log.Message("%d assets %s", len(ids), done(dryRun, action))
+
+		for _, al := range s.albums {
+			if !al.empty() {
+				continue
+			}
+			err = client.DeleteAlbum(ctx, al.id)
+			if err != nil {
+				log.Error("can't delete the album", "album", al.name, "err", err)
+				continue
+			}
+			log.Message("Album %q %s", al.name, done(dryRun, "deleted"))
+		}
+
+		err = client.DeleteTag(ctx, s.tag.ID)
+		if err != nil {
+			return fmt.Errorf("can't delete the session tag: %w", err)
+		}
+		log.Message("Tag %q %s", s.tag.Value, done(dryRun, "deleted"))
+		return nil
+	}
+	return cmd
+}
+
+// done gives the past participle of the action, or its conditional in dry-run mode
+func done(dryRun bool, action string) string {
+	if dryRun {
+		return "would be " + action + " (dry-run)"
+	}
+	return action
+}
+
+// confirm asks for the confirmation of the rollback on the terminal.
+// Without a terminal, the rollback must be confirmed with the option --yes.
+func confirm(ctx context.Context, in io.Reader) (bool, error) {
+	if f, ok := in.(*os.File); !ok || !term.IsTerminal(int(f.Fd())) {
+		return false, errors.New("the rollback must be confirmed with the option --yes")
+	}
+	answer, err := ui.ConfirmYesNo(ctx, "Proceed with the rollback?", "n")
+	if err != nil {
+		return false, err
+	}
+	return answer == "y", nil
+}
+
+type session struct {
+	tag    immich.TagSimplified
+	assets []*immich.Asset
+	albums []album
+}
+
+// print lists the assets and the albums of the session
+func (s *session) print(w io.Writer) {
+	fmt.Fprintf(w, "Session %s: %d assets\n\n", s.tag.Name, len(s.assets))
+	for _, as := range s.assets {
+		fmt.Fprintf(w, "%s  %s  %s\n", as.ID, as.ExifInfo.DateTimeOriginal.Format("2006-01-02 15:04:05"), as.OriginalFileName)
+	}
+	if len(s.albums) > 0 {
+		fmt.Fprintf(w, "\nAlbums:\n")
+		for _, al := range s.albums {
+			note := ""
+			if al.empty() {
+				note = " (only assets of the session)"
+			}
+			fmt.Fprintf(w, "%s  %d/%d assets  %s%s\n", al.id, al.fromSession, al.total, al.name, note)
+		}
+	}
+}
+
+// album counts the assets of an album coming from the session
+type album struct {
+	id          string
+	name        string
+	total       int
+	fromSession int
+}
+
+// empty returns true when the album has only assets of the session
+func (al album) empty() bool {
+	return al.total == al.fromSession
+}
+
+// getSessionTags returns the session tags, sorted by date
+func getSessionTags(ctx context.Context, client immich.ImmichInterface) ([]immich.TagSimplified, error) {
+	tags, err := client.GetAllTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get the tags: %w", err)
+	}
+	sessions := []immich.TagSimplified{}
+	for _, t := range tags {
+		if strings.HasPrefix(t.Value, sessionTagRoot+"/") {
+			sessions = append(sessions, t)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Value < sessions[j].Value
+	})
+	return sessions, nil
+}
+
+// findSession finds the session tag by its full value or by its name
+func findSession(ctx context.Context, client immich.ImmichInterface, name string) (immich.TagSimplified, error) {
+	tags, err := getSessionTags(ctx, client)
+	if err != nil {
+		return immich.TagSimplified{}, err
+	}
+	for _, t := range tags {
+		if t.Value == name || t.Name == name {
+			return t, nil
+		}
+	}
+	return immich.TagSimplified{}, fmt.Errorf("session %q not found", name)
+}
+
+func getSessionAssets(ctx context.Context, client immich.ImmichInterface, tag immich.TagSimplified) ([]*immich.Asset, error) {
+	list := []*immich.Asset{}
+	query := &immich.SearchMetadataQuery{WithExif: true, WithArchived: true, TagIDs: []string{tag.ID}}
+	err := client.GetAllAssetsWithFilter(ctx, query, func(as *immich.Asset) error {
+		list = append(list, as)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't get the assets of the session: %w", err)
+	}
+	return list, nil
+}
+
+// loadSession gets the session's assets, and the albums containing them
+func loadSession(ctx context.Context, client immich.ImmichInterface, name string) (*session, error) {
+	tag, err := findSession(ctx, client, name)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{tag: tag}
+	s.assets, err = getSessionAssets(ctx, client, tag)
+	if err != nil {
+		return nil, err
+	}
+	inSession := map[string]bool{}
+	for _, as := range s.assets {
+		inSession[as.ID] = true
+	}
+
+	albums, err := client.GetAllAlbums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get the albums: %w", err)
+	}
+	for _, al := range albums {
+		info, err := client.GetAlbumInfo(ctx, al.ID, false)
+		if err != nil {
+			return nil, fmt.Errorf("can't get the album %q: %w", al.AlbumName, err)
+		}
+		n := 0
+		for _, as := range info.Assets {
+			if inSession[as.ID] {
+				n++
+			}
+		}
+		if n > 0 {
+			s.albums = append(s.albums, album{id: al.ID, name: al.AlbumName, total: len(info.Assets), fromSession: n})
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+	"github.com/simulot/immich-go/internal/fakeImmich/cmdtest"
+)
+
+const sessionTag = "{immich-go}/2024-10-10 10-00-00"
+
+// newServer gives a server with a session of two assets, an album having only
+// assets of the session, and an album shared with an asset uploaded before.
+func newServer(t *testing.T) (*fakeimmich.Server, map[string]string) {
+	s := cmdtest.NewServer(t)
+	d := time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)
+	ids := map[string]string{
+		"before": s.AddAsset("OLD.JPG", []byte("old"), d),
+		"new1":   s.AddAsset("NEW1.JPG", []byte("new1"), d),
+		"new2":   s.AddAsset("NEW2.JPG", []byte("new2"), d),
+	}
+	s.AddAlbum("Session only", ids["new1"], ids["new2"])
+	s.AddAlbum("Shared", ids["before"], ids["new1"])
+	s.TagAssets(sessionTag, ids["new1"], ids["new2"])
+	return s, ids
+}
+
+func TestList(t *testing.T) {
+	s, _ := newServer(t)
+	out, err := cmdtest.Run(s, NewSessionCommand, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "2024-10-10 10-00-00") || !strings.Contains(out, " 2\n") {
+		t.Errorf("unexpected list:\n%s", out)
+	}
+}
+
+func TestShow(t *testing.T) {
+	s, _ := newServer(t)
+	out, err := cmdtest.Run(s, NewSessionCommand, "show", "2024-10-10 10-00-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"2 assets",
+		"NEW1.JPG",
+		"NEW2.JPG",
+		"2/2 assets  Session only (only assets of the session)",
+		"1/2 assets  Shared\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "OLD.JPG") {
+		t.Errorf("the output lists an asset out of the session:\n%s", out)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	t.Run("without confirmation", func(t *testing.T) {
+		s, ids := newServer(t)
+		out, err := cmdtest.Run(s, NewSessionCommand, "rollback", "2024-10-10 10-00-00")
+		if err == nil {
+			t.Error("the rollback should require --yes without a terminal")
+		}
+		if !strings.Contains(out, "NEW1.JPG") {
+			t.Errorf("the rollback should preview the session:\n%s", out)
+		}
+		for _, k := range []string{"new1", "new2"} {
+			if _, ok := s.Asset(ids[k]); !ok {
+				t.Errorf("%s: deleted without confirmation", k)
+			}
+		}
+	})
+
+	t.Run("dry-run", func(t *testing.T) {
+		s, ids := newServer(t)
+		out, err := cmdtest.Run(s, NewSessionCommand, "rollback", "2024-10-10 10-00-00", "--dry-run")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			"2 assets would be deleted (dry-run)",
+			`Album "Session only" would be deleted (dry-run)`,
+			`Tag "` + sessionTag + `" would be deleted (dry-run)`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("the output lacks %q:\n%s", want, out)
+			}
+		}
+		for _, k := range []string{"new1", "new2"} {
+			if as, ok := s.Asset(ids[k]); !ok || as.Trashed {
+				t.Errorf("%s: deleted in dry-run mode", k)
+			}
+		}
+		if len(s.Albums()) != 2 {
+			t.Errorf("albums deleted in dry-run mode: %v", s.Albums())
+		}
+		if _, ok := s.Tags()[sessionTag]; !ok {
+			t.Error("tag deleted in dry-run mode")
+		}
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		s, ids := newServer(t)
+		out, err := cmdtest.Run(s, NewSessionCommand, "rollback", "2024-10-10 10-00-00", "--yes", "--trash")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "2 assets moved to the trash\n") {
+			t.Errorf("unexpected output:\n%s", out)
+		}
+		for k, want := range map[string]bool{"before": false, "new1": true, "new2": true} {
+			if as, _ := s.Asset(ids[k]); as.Trashed != want {
+				t.Errorf("%s: trashed %v, want %v", k, as.Trashed, want)
+			}
+		}
+		albums := s.Albums()
+		if _, ok := albums["Session only"]; ok {
+			t.Error("the album having only assets of the session should be deleted")
+		}
+		if _, ok := albums["Shared"]; !ok {
+			t.Error("the shared album should be kept")
+		}
+		if _, ok := s.Tags()[sessionTag]; ok {
+			t.Error("the session tag should be deleted")
+		}
+	})
+}
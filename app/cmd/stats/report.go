@@ -0,0 +1,217 @@
+package stats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// Counter counts the assets of a category and their size
+type Counter struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+	Bytes int64  `json:"bytes"`
+}
+
+func (c *Counter) add(as *immich.Asset) {
+	c.Count++
+	c.Bytes += as.ExifInfo.FileSizeInByte
+}
+
+// File is an asset listed in the largest files
+type File struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Report is the breakdown of the library
+type Report struct {
+	Total       Counter   `json:"total"`
+	Years       []Counter `json:"years"`
+	Months      []Counter `json:"months"`
+	Cameras     []Counter `json:"cameras"`
+	FileTypes   []Counter `json:"fileTypes"`
+	Albums      []Counter `json:"albums"`
+	SessionTags []Counter `json:"sessionTags"`
+	NoDate      Counter   `json:"noDate"`
+	NoGPS       Counter   `json:"noGPS"`
+	Largest     []File    `json:"largest"`
+}
+
+// counters is a set of counters indexed by their key
+type counters map[string]*Counter
+
+func (cs counters) add(key string, as *immich.Asset) {
+	c, ok := cs[key]
+	if !ok {
+		c = &Counter{Key: key}
+		cs[key] = c
+	}
+	c.add(as)
+}
+
+// byKey returns the counters sorted by key
+func (cs counters) byKey() []Counter {
+	l := make([]Counter, 0, len(cs))
+	for _, c := range cs {
+		l = append(l, *c)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Key < l[j].Key })
+	return l
+}
+
+// byCount returns the counters sorted by decreasing count
+func (cs counters) byCount() []Counter {
+	l := cs.byKey()
+	sort.SliceStable(l, func(i, j int) bool { return l[i].Count > l[j].Count })
+	return l
+}
+
+// newReport computes the breakdown of the assets. The albums and the session tags
+// are given as lists of asset IDs.
+func newReport(list []*immich.Asset, albums, sessions map[string][]string, largest int, tz *time.Location) *Report {
+	r := &Report{Total: Counter{Key: "total"}, NoDate: Counter{Key: "no date"}, NoGPS: Counter{Key: "no GPS"}}
+	years, months, cameras, types := counters{}, counters{}, counters{}, counters{}
+	byID := map[string]*immich.Asset{}
+
+	for _, as := range list {
+		byID[as.ID] = as
+		r.Total.add(as)
+		if d := as.ExifInfo.DateTimeOriginal.Time; d.IsZero() {
+			r.NoDate.add(as)
+		} else {
+			d = d.In(tz)
+			years.add(d.Format("2006"), as)
+			months.add(d.Format("2006-01"), as)
+		}
+		if as.ExifInfo.Latitude == 0 && as.ExifInfo.Longitude == 0 {
+			r.NoGPS.add(as)
+		}
+		camera := strings.TrimSpace(as.ExifInfo.Make + " " + as.ExifInfo.Model)
+		if camera == "" {
+			camera = "unknown"
+		}
+		cameras.add(camera, as)
+		ext := strings.ToLower(path.Ext(as.OriginalFileName))
+		if ext == "" {
+			ext = strings.ToLower(as.Type)
+		}
+		types.add(ext, as)
+	}
+	r.Years, r.Months = years.byKey(), months.byKey()
+	r.Cameras, r.FileTypes = cameras.byCount(), types.byCount()
+	r.Albums = membership(albums, byID)
+	r.SessionTags = membership(sessions, byID)
+	sort.Slice(r.SessionTags, func(i, j int) bool { return r.SessionTags[i].Key < r.SessionTags[j].Key })
+
+	sorted := append([]*immich.Asset(nil), list...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ExifInfo.FileSizeInByte > sorted[j].ExifInfo.FileSizeInByte
+	})
+	for _, as := range sorted[:max(0, min(largest, len(sorted)))] {
+		r.Largest = append(r.Largest, File{ID: as.ID, Name: as.OriginalFileName, Bytes: as.ExifInfo.FileSizeInByte})
+	}
+	return r
+}
+
+// membership counts the assets of each group. The assets out of the list are ignored.
+func membership(groups map[string][]string, byID map[string]*immich.Asset) []Counter {
+	cs := counters{}
+	for key, ids := range groups {
+		cs[key] = &Counter{Key: key}
+		for _, id := range ids {
+			if as, ok := byID[id]; ok {
+				cs[key].add(as)
+			}
+		}
+	}
+	return cs.byCount()
+}
+
+// section is a named table of the report
+type section struct {
+	name     string
+	counters []Counter
+}
+
+// sections gives the report as a list of tables
+func (r *Report) sections() []section {
+	return []section{
+		{"total", []Counter{r.Total, r.NoDate, r.NoGPS}},
+		{"year", r.Years},
+		{"month", r.Months},
+		{"camera", r.Cameras},
+		{"file type", r.FileTypes},
+		{"album", r.Albums},
+		{"session tag", r.SessionTags},
+	}
+}
+
+func (r *Report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// writeCSV writes one line per counter, and the largest files with the section "largest"
+func (r *Report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"section", "key", "count", "bytes"})
+	for _, s := range r.sections() {
+		for _, c := range s.counters {
+			_ = cw.Write([]string{s.name, c.Key, strconv.Itoa(c.Count), strconv.FormatInt(c.Bytes, 10)})
+		}
+	}
+	for _, f := range r.Largest {
+		_ = cw.Write([]string{"largest", f.Name + " (" + f.ID + ")", "1", strconv.FormatInt(f.Bytes, 10)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *Report) writeTable(w io.Writer) error {
+	for _, s := range r.sections() {
+		if len(s.counters) == 0 {
+			continue
+		}
+		width := len(s.name)
+		for _, c := range s.counters {
+			width = max(width, len(c.Key))
+		}
+		fmt.Fprintf(w, "%-*s %10s %12s\n", width, strings.ToUpper(s.name), "ASSETS", "SIZE")
+		for _, c := range s.counters {
+			fmt.Fprintf(w, "%-*s %10d %12s\n", width, c.Key, c.Count, formatSize(c.Bytes))
+		}
+		fmt.Fprintln(w)
+	}
+	if len(r.Largest) > 0 {
+		fmt.Fprintln(w, "LARGEST FILES")
+		for _, f := range r.Largest {
+			fmt.Fprintf(w, "%12s  %s  %s\n", formatSize(f.Bytes), f.ID, f.Name)
+		}
+	}
+	return nil
+}
+
+func formatSize(s int64) string {
+	switch {
+	case s >= 1<<40:
+		return fmt.Sprintf("%.1fTB", float64(s)/(1<<40))
+	case s >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(s)/(1<<30))
+	case s >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(s)/(1<<20))
+	case s >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(s)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", s)
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestNewReport(t *testing.T) {
+	asset := func(id, name, model string, size int64, date time.Time, gps bool) *immich.Asset {
+		a := &immich.Asset{ID: id, OriginalFileName: name, Type: "IMAGE"}
+		a.ExifInfo.Make = "Canon"
+		a.ExifInfo.Model = model
+		a.ExifInfo.FileSizeInByte = size
+		a.ExifInfo.DateTimeOriginal = immich.ImmichExifTime{Time: date}
+		if gps {
+			a.ExifInfo.Latitude, a.ExifInfo.Longitude = 48.8, 2.3
+		}
+		return a
+	}
+	list := []*immich.Asset{
+		asset("1", "a.JPG", "EOS 5D", 100, time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC), true),
+		asset("2", "b.jpg", "EOS 5D", 300, time.Date(2023, 8, 1, 10, 0, 0, 0, time.UTC), false),
+		asset("3", "c.CR2", "EOS R", 1000, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), false),
+		asset("4", "d.mp4", "", 50, time.Time{}, false),
+	}
+	albums := map[string][]string{"Holidays": {"1", "2", "unknown"}, "Empty": {}}
+	sessions := map[string][]string{"{immich-go}/2024-01-01 10-00-00": {"3", "4"}}
+
+	r := newReport(list, albums, sessions, 2, time.UTC)
+
+	check := func(name string, got []Counter, want ...Counter) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+			return
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: got %+v, want %+v", name, got, want)
+				return
+			}
+		}
+	}
+	check("total", []Counter{r.Total, r.NoDate, r.NoGPS}, Counter{"total", 4, 1450}, Counter{"no date", 1, 50}, Counter{"no GPS", 3, 1350})
+	check("years", r.Years, Counter{"2023", 2, 400}, Counter{"2024", 1, 1000})
+	check("months", r.Months, Counter{"2023-07", 1, 100}, Counter{"2023-08", 1, 300}, Counter{"2024-01", 1, 1000})
+	check("cameras", r.Cameras, Counter{"Canon EOS 5D", 2, 400}, Counter{"Canon", 1, 50}, Counter{"Canon EOS R", 1, 1000})
+	check("file types", r.FileTypes, Counter{".jpg", 2, 400}, Counter{".cr2", 1, 1000}, Counter{".mp4", 1, 50})
+	check("albums", r.Albums, Counter{"Holidays", 2, 400}, Counter{"Empty", 0, 0})
+	check("sessions", r.SessionTags, Counter{"{immich-go}/2024-01-01 10-00-00", 2, 1050})
+	if len(r.Largest) != 2 || r.Largest[0].ID != "3" || r.Largest[1].ID != "2" {
+		t.Errorf("largest: got %+v", r.Largest)
+	}
+	if r := newReport(list, albums, sessions, -1, time.UTC); len(r.Largest) != 0 {
+		t.Errorf("largest with a negative count: got %+v", r.Largest)
+	}
+
+	var b bytes.Buffer
+	if err := r.writeJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var back Report
+	if err := json.Unmarshal(b.Bytes(), &back); err != nil || back.Total != r.Total {
+		t.Errorf("JSON round trip: %v, %+v", err, back.Total)
+	}
+	b.Reset()
+	if err := r.writeCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "year,2023,2,400\n") {
+		t.Errorf("unexpected CSV:\n%s", b.String())
+	}
+}
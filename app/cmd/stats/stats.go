@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/spf13/cobra"
+)
+
+// sessionTagRoot is the parent of the tags given by the option --session-tag
+const sessionTagRoot = "{immich-go}"
+
+type StatsCmd struct {
+	Selection app.AssetSelection
+	Format    string // table, csv or json
+	Output    string // Output file, stdout when empty
+	Largest   int    // Number of largest files listed
+}
+
+// NewStatsCommand adds the stats command, giving a breakdown of the library
+func NewStatsCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats [flags]",
+		Short: "Give the breakdown of the server's library",
+		Long:  `Count the assets and their size per year, month, camera, file type, album and session tag, the assets without date or GPS data, and list the largest files`,
+		Args:  cobra.NoArgs,
+	}
+	o := &StatsCmd{}
+	app.AddClientFlags(ctx, cmd, a, false)
+	cmd.TraverseChildren = true
+	o.Selection.AddFlags(cmd)
+	cmd.Flags().StringVar(&o.Format, "format", "table", "Output format: table, csv or json")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", "", "Write the statistics into the file instead of the standard output")
+	cmd.Flags().IntVar(&o.Largest, "largest", 10, "Number of largest files listed")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+
+		if o.Largest < 0 {
+			return fmt.Errorf("the --largest can't be negative, got %d", o.Largest)
+		}
+
+		var write func(r *Report, w io.Writer) error
+		switch strings.ToLower(o.Format) {
+		case "table":
+			write = (*Report).writeTable
+		case "csv":
+			write = (*Report).writeCSV
+		case "json":
+			write = (*Report).writeJSON
+		default:
+			return fmt.Errorf("unknown format %q, expected table, csv or json", o.Format)
+		}
+
+		list, err := o.Selection.Assets(ctx, client)
+		if err != nil {
+			return err
+		}
+		albums, err := getAlbumAssets(ctx, client)
+		if err != nil {
+			return err
+		}
+		sessions, err := getSessionAssets(ctx, client)
+		if err != nil {
+			return err
+		}
+		r := newReport(list, albums, sessions, o.Largest, a.GetTZ())
+
+		if o.Output == "" {
+			return write(r, os.Stdout)
+		}
+		f, err := os.Create(o.Output)
+		if err != nil {
+			return err
+		}
+		err = write(r, f)
+		err = errors.Join(err, f.Close())
+		if err == nil {
+			a.Log().Message("Statistics of %d assets written into %s", r.Total.Count, o.Output)
+		}
+		return err
+	}
+	return cmd
+}
+
+// getAlbumAssets returns the asset IDs of each album
+func getAlbumAssets(ctx context.Context, client immich.ImmichInterface) (map[string][]string, error) {
+	albums, err := client.GetAllAlbums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get the albums: %w", err)
+	}
+	byAlbum := map[string][]string{}
+	for _, al := range albums {
+		info, err := client.GetAlbumInfo(ctx, al.ID, false)
+		if err != nil {
+			return nil, fmt.Errorf("can't get the album %q: %w", al.AlbumName, err)
+		}
+		ids := make([]string, 0, len(info.Assets))
+		for _, as := range info.Assets {
+			ids = append(ids, as.ID)
+		}
+		byAlbum[al.AlbumName] = append(byAlbum[al.AlbumName], ids...)
+	}
+	return byAlbum, nil
+}
+
+// getSessionAssets returns the asset IDs of each session tag, none when the server has no tags
+func getSessionAssets(ctx context.Context, client immich.ImmichInterface) (map[string][]string, error) {
+	if !client.Supports(immich.CapabilityTags) {
+		return map[string][]string{}, nil
+	}
+	tags, err := client.GetAllTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't get the tags: %w", err)
+	}
+	bySession := map[string][]string{}
+	for _, t := range tags {
+		if !strings.HasPrefix(t.Value, sessionTagRoot+"/") {
+			continue
+		}
+		ids := []string{}
+		err := client.GetAllAssetsWithFilter(ctx, &immich.SearchMetadataQuery{WithArchived: true, TagIDs: []string{t.ID}}, func(as *immich.Asset) error {
+			ids = append(ids, as.ID)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't get the assets of the tag %q: %w", t.Value, err)
+		}
+		bySession[t.Value] = ids
+	}
+	return bySession, nil
+}
@@ -0,0 +1,371 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/spf13/cobra"
+)
+
+// batchSize is the number of assets sent in one call
+const batchSize = 1000
+
+// NewTagCommand adds the tag command, to manage the tags of the server
+func NewTagCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tag",
+		Short: "Apply, rename, remove or prune the tags of the server",
+	}
+	app.AddClientFlags(ctx, cmd, a, false)
+	cmd.PersistentPreRunE = app.ChainRunEFunctions(cmd.PersistentPreRunE, app.RequireCapabilities(immich.CapabilityTags), ctx, cmd, a)
+	cmd.TraverseChildren = true
+
+	cmd.AddCommand(newApplyCommand(ctx, a))
+	cmd.AddCommand(newRemoveCommand(ctx, a))
+	cmd.AddCommand(newRenameCommand(ctx, a))
+	cmd.AddCommand(newPruneCommand(ctx, a))
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		return errors.New("you must specify a subcommand to the tag command")
+	}
+	return cmd
+}
+
+func newApplyCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	s := &app.AssetSelection{}
+	cmd := &cobra.Command{
+		Use:   "apply <tag>",
+		Short: "Tag the selected assets. The tag is created when needed",
+		Long:  `Tag the assets selected by date range, camera make and model, album or file name. Hierarchical tags are given as parent/child`,
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.SetContext(ctx)
+	s.AddFlags(cmd)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		if !s.IsSet() {
+			return errors.New("give at least one selection criterion to tag the assets")
+		}
+		value := cleanTag(args[0])
+
+		list, err := s.Assets(ctx, client)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			a.Log().Message("No asset selected")
+			return nil
+		}
+		tags, err := client.UpsertTags(ctx, []string{value})
+		if err != nil {
+			return fmt.Errorf("can't create the tag %q: %w", value, err)
+		}
+		ids := assetIDs(list)
+		for start := 0; start < len(ids); start += batchSize {
+			end := min(start+batchSize, len(ids))
+			_, err = client.BulkTagAssets(ctx, []string{tags[0].ID}, ids[start:end])
+			if err != nil {
+				return fmt.Errorf("can't tag the assets: %w", err)
+			}
+		}
+		dryRunList(a, list, "tag "+value)
+		a.Log().Message("%d assets tagged with %q", len(ids), value)
+		return nil
+	}
+	return cmd
+}
+
+func newRemoveCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	s := &app.AssetSelection{}
+	cmd := &cobra.Command{
+		Use:   "remove <tag>",
+		Short: "Remove the tag from the selected assets, or from all its assets when no selection is given",
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.SetContext(ctx)
+	s.AddFlags(cmd)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		value := cleanTag(args[0])
+
+		tags, err := client.GetAllTags(ctx)
+		if err != nil {
+			return fmt.Errorf("can't get the tags: %w", err)
+		}
+		t, ok := findTag(tags, value)
+		if !ok {
+			return fmt.Errorf("tag %q not found", value)
+		}
+		list, err := s.Assets(ctx, client, t.ID)
+		if err != nil {
+			return err
+		}
+		list, err = taggedDirectly(ctx, client, tags, t, list)
+		if err != nil {
+			return err
+		}
+		ids := assetIDs(list)
+		for start := 0; start < len(ids); start += batchSize {
+			end := min(start+batchSize, len(ids))
+			_, err = client.UntagAssets(ctx, t.ID, ids[start:end])
+			if err != nil {
+				return fmt.Errorf("can't remove the tag from the assets: %w", err)
+			}
+		}
+		dryRunList(a, list, "untag "+value)
+		a.Log().Message("Tag %q removed from %d assets", value, len(ids))
+		return nil
+	}
+	return cmd
+}
+
+func newRenameCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rename <tag> <new tag>",
+		Short: "Rename a tag and its children",
+		Long:  `Rename a tag path, ex: "Holidays/2023" to "Travels/2023". The children tags are renamed too.`,
+		Args:  cobra.ExactArgs(2),
+	}
+	cmd.SetContext(ctx)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		log := a.Log()
+
+		tags, err := client.GetAllTags(ctx)
+		if err != nil {
+			return fmt.Errorf("can't get the tags: %w", err)
+		}
+		renamings, err := renameTags(tags, cleanTag(args[0]), cleanTag(args[1]))
+		if err != nil {
+			return err
+		}
+
+		// The server can't rename a tag: the new tags are created, the assets are tagged
+		// with them, and the old tags are deleted.
+		values := make([]string, len(renamings))
+		for i, r := range renamings {
+			values[i] = r.to
+		}
+		newTags, err := client.UpsertTags(ctx, values)
+		if err != nil {
+			return fmt.Errorf("can't create the tags: %w", err)
+		}
+		newIDs := map[string]string{}
+		for _, t := range newTags {
+			newIDs[t.Value] = t.ID
+		}
+
+		// The search by tag returns also the assets of the children tags,
+		// keep only the assets tagged directly
+		tagged := map[string]map[string]*immich.Asset{}
+		for _, r := range renamings {
+			list, err := (&app.AssetSelection{}).Assets(ctx, client, r.from.ID)
+			if err != nil {
+				return err
+			}
+			tagged[r.from.Value] = map[string]*immich.Asset{}
+			for _, as := range list {
+				tagged[r.from.Value][as.ID] = as
+			}
+		}
+		for _, r := range renamings {
+			for _, c := range renamings {
+				if strings.HasPrefix(c.from.Value, r.from.Value+"/") {
+					for id := range tagged[c.from.Value] {
+						delete(tagged[r.from.Value], id)
+					}
+				}
+			}
+		}
+
+		for _, r := range renamings {
+			list := make([]*immich.Asset, 0, len(tagged[r.from.Value]))
+			for _, as := range tagged[r.from.Value] {
+				list = append(list, as)
+			}
+			ids := assetIDs(list)
+			for start := 0; start < len(ids); start += batchSize {
+				end := min(start+batchSize, len(ids))
+				_, err = client.TagAssets(ctx, newIDs[r.to], ids[start:end])
+				if err != nil {
+					return fmt.Errorf("can't tag the assets with %q: %w", r.to, err)
+				}
+			}
+			dryRunList(a, list, "tag "+r.to)
+			log.Message("Tag %q renamed into %q, %d assets", r.from.Value, r.to, len(ids))
+		}
+
+		// deleting the parent tag deletes its children
+		err = client.DeleteTag(ctx, renamings[0].from.ID)
+		if err != nil {
+			return fmt.Errorf("can't delete the tag %q: %w", renamings[0].from.Value, err)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func newPruneCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the tags without asset",
+		Args:  cobra.NoArgs,
+	}
+	cmd.SetContext(ctx)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		client := a.Client().Immich
+		log := a.Log()
+
+		tags, err := client.GetAllTags(ctx)
+		if err != nil {
+			return fmt.Errorf("can't get the tags: %w", err)
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			return tags[i].Value < tags[j].Value
+		})
+
+		errFound := errors.New("found")
+		deleted := []string{}
+		for _, t := range tags {
+			if hasAncestor(deleted, t.Value) {
+				// already deleted with its parent
+				continue
+			}
+			// the search by tag includes the assets of the children
+			err = client.GetAllAssetsWithFilter(ctx, &immich.SearchMetadataQuery{WithArchived: true, TagIDs: []string{t.ID}}, func(as *immich.Asset) error {
+				return errFound
+			})
+			if errors.Is(err, errFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("can't get the assets of the tag %q: %w", t.Value, err)
+			}
+			err = client.DeleteTag(ctx, t.ID)
+			if err != nil {
+				log.Error("can't delete the tag", "tag", t.Value, "err", err)
+				continue
+			}
+			deleted = append(deleted, t.Value)
+			log.Message("Tag %q deleted", t.Value)
+		}
+		log.Message("%d tags without asset deleted", len(deleted))
+		return nil
+	}
+	return cmd
+}
+
+// taggedDirectly keeps the assets of the list having the tag, and not only one of its children.
+// The search by tag returns also the assets of the children tags.
+func taggedDirectly(ctx context.Context, client immich.ImmichInterface, tags []immich.TagSimplified, t immich.TagSimplified, list []*immich.Asset) ([]*immich.Asset, error) {
+	inChildren := map[string]bool{}
+	for _, c := range tags {
+		if !strings.HasPrefix(c.Value, t.Value+"/") {
+			continue
+		}
+		children, err := (&app.AssetSelection{}).Assets(ctx, client, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, as := range children {
+			inChildren[as.ID] = true
+		}
+	}
+	return slices.DeleteFunc(list, func(as *immich.Asset) bool { return inChildren[as.ID] }), nil
+}
+
+// renaming gives the new value of a tag
+type renaming struct {
+	from immich.TagSimplified
+	to   string
+}
+
+// renameTags returns the new values of the tag and of its children, parent first
+func renameTags(tags []immich.TagSimplified, from, to string) ([]renaming, error) {
+	if from == "" || to == "" {
+		return nil, errors.New("the tag names can't be empty")
+	}
+	if from == to {
+		return nil, errors.New("the new tag name is the same as the old one")
+	}
+	if strings.HasPrefix(to, from+"/") {
+		return nil, fmt.Errorf("can't rename the tag %q into one of its children", from)
+	}
+	renamings := []renaming{}
+	for _, t := range tags {
+		if t.Value == from || strings.HasPrefix(t.Value, from+"/") {
+			renamings = append(renamings, renaming{from: t, to: to + strings.TrimPrefix(t.Value, from)})
+		}
+	}
+	sort.Slice(renamings, func(i, j int) bool {
+		return renamings[i].from.Value < renamings[j].from.Value
+	})
+	if len(renamings) == 0 || renamings[0].from.Value != from {
+		return nil, fmt.Errorf("tag %q not found", from)
+	}
+	return renamings, nil
+}
+
+// cleanTag removes the spaces and the slashes around the tag and its parts
+func cleanTag(value string) string {
+	parts := strings.Split(value, "/")
+	l := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			l = append(l, p)
+		}
+	}
+	return strings.Join(l, "/")
+}
+
+func findTag(tags []immich.TagSimplified, value string) (immich.TagSimplified, bool) {
+	for _, t := range tags {
+		if t.Value == value {
+			return t, true
+		}
+	}
+	return immich.TagSimplified{}, false
+}
+
+// hasAncestor returns true when a parent of the tag is in the list
+func hasAncestor(list []string, value string) bool {
+	for _, p := range list {
+		if strings.HasPrefix(value, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func assetIDs(list []*immich.Asset) []string {
+	ids := make([]string, len(list))
+	for i, as := range list {
+		ids[i] = as.ID
+	}
+	return ids
+}
+
+// dryRunList prints the assets concerned by the action in dry-run mode
+func dryRunList(a *app.Application, list []*immich.Asset, action string) {
+	if !a.Client().DryRun {
+		return
+	}
+	for _, as := range list {
+		fmt.Printf("(dry-run) %s: %s %s\n", action, as.ID, as.OriginalFileName)
+	}
+}
@@ -0,0 +1,113 @@
+package tag
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/fakeImmich/cmdtest"
+)
+
+func TestRenameTags(t *testing.T) {
+	tags := []immich.TagSimplified{
+		{ID: "3", Value: "Holidays/2023/Italy"},
+		{ID: "1", Value: "Holidays"},
+		{ID: "2", Value: "Holidays/2023"},
+		{ID: "4", Value: "Holidays/2024"},
+		{ID: "5", Value: "Holidays 2023"},
+	}
+
+	got, err := renameTags(tags, "Holidays/2023", "Travels/2023")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Holidays/2023":       "Travels/2023",
+		"Holidays/2023/Italy": "Travels/2023/Italy",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d renamings, want %d: %+v", len(got), len(want), got)
+	}
+	if got[0].from.ID != "2" {
+		t.Errorf("the parent tag must be the first, got %q", got[0].from.Value)
+	}
+	for _, r := range got {
+		if want[r.from.Value] != r.to {
+			t.Errorf("%q renamed into %q, want %q", r.from.Value, r.to, want[r.from.Value])
+		}
+	}
+
+	if _, err := renameTags(tags, "Holidays", "Holidays/Old"); err == nil {
+		t.Error("renaming a tag into its child must fail")
+	}
+	if _, err := renameTags(tags, "Work", "Office"); err == nil {
+		t.Error("renaming an unknown tag must fail")
+	}
+}
+
+func TestCleanTag(t *testing.T) {
+	for in, want := range map[string]string{
+		" Holidays / 2023 ": "Holidays/2023",
+		"/People//Alice/":   "People/Alice",
+		"Nature":            "Nature",
+	} {
+		if got := cleanTag(in); got != want {
+			t.Errorf("cleanTag(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	s := cmdtest.NewServer(t)
+	d := time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)
+	parent := s.AddAsset("PARENT.JPG", []byte("parent"), d)
+	child := s.AddAsset("CHILD.JPG", []byte("child"), d)
+	s.TagAssets("Holidays", parent)
+	s.TagAssets("Holidays/2023", child)
+
+	ctx := context.Background()
+	client, err := immich.NewImmichClient(s.URL, s.APIKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := client.GetAllTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holidays, _ := findTag(all, "Holidays")
+	list, err := (&app.AssetSelection{}).Assets(ctx, client, holidays.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("the search by tag should give the assets of the children: %d assets", len(list))
+	}
+	list, err = taggedDirectly(ctx, client, all, holidays, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != parent {
+		t.Errorf("only the asset tagged with the parent should be kept: %v", assetIDs(list))
+	}
+
+	out, err := cmdtest.Run(s, NewTagCommand, "remove", "Holidays")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `Tag "Holidays" removed from 1 assets`) {
+		t.Errorf("the message should count the assets tagged directly: %q", out)
+	}
+
+	got := s.Tags()
+	if len(got["Holidays"]) != 0 {
+		t.Errorf("the tag should be removed from its assets: %v", got["Holidays"])
+	}
+	if !slices.Equal(got["Holidays/2023"], []string{"CHILD.JPG"}) {
+		t.Errorf("the assets of the child tag should keep it: %v", got["Holidays/2023"])
+	}
+}
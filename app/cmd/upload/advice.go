@@ -252,7 +252,17 @@ func (ii *immichIndex) ShouldUpload(la *assets.Asset) (*Advice, error) {
 		}
 		return ii.adviceSameOnServer(sa), nil
 	}
+	return ii.shouldUploadByName(la), nil
+}
+
+// ShouldUploadWithoutChecksum checks if the server has this asset by comparing its name, date and size only.
+// It is used when the asset's file is streamed to the server, and its checksum isn't known yet.
+// The server detects the duplicates by checksum during the upload.
+func (ii *immichIndex) ShouldUploadWithoutChecksum(la *assets.Asset) *Advice {
+	return ii.shouldUploadByName(la)
+}
 
+func (ii *immichIndex) shouldUploadByName(la *assets.Asset) *Advice {
 	filename := path.Base(la.File.Name())
 
 	// check all files with the same name
@@ -276,15 +286,15 @@ func (ii *immichIndex) ShouldUpload(la *assets.Asset) (*Advice, error) {
 
 			switch {
 			case compareDate == 0 && compareSize == 0:
-				return ii.adviceSameOnServer(sa), nil
+				return ii.adviceSameOnServer(sa)
 			case compareDate == 0 && compareSize > 0:
-				return ii.adviceSmallerOnServer(sa), nil
+				return ii.adviceSmallerOnServer(sa)
 			case compareDate == 0 && compareSize < 0:
-				return ii.adviceBetterOnServer(sa), nil
+				return ii.adviceBetterOnServer(sa)
 			}
 		}
 	}
-	return ii.adviceNotOnServer(), nil
+	return ii.adviceNotOnServer()
 }
 
 func compareDate(d1 time.Time, d2 time.Time) int {
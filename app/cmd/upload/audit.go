@@ -0,0 +1,261 @@
+package upload
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/simulot/immich-go/adapters"
+	"github.com/simulot/immich-go/adapters/folder"
+	gp "github.com/simulot/immich-go/adapters/googlePhotos"
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/filenames"
+	"github.com/simulot/immich-go/internal/fshelper"
+	"github.com/spf13/cobra"
+)
+
+// AuditStatus tells if a local file is on the server
+type AuditStatus int
+
+const (
+	AuditPresent        AuditStatus = iota // the server has the same file
+	AuditBetterOnServer                    // the server has the file in a better quality
+	AuditWorseOnServer                     // the server has the file in a lower quality
+	AuditTrashOnly                         // the file is only in the server's trash
+	AuditMissing                           // the server doesn't have the file
+	AuditError                             // the file can't be read
+)
+
+func (s AuditStatus) String() string {
+	switch s {
+	case AuditPresent:
+		return "present"
+	case AuditBetterOnServer:
+		return "better-on-server"
+	case AuditWorseOnServer:
+		return "worse-on-server"
+	case AuditTrashOnly:
+		return "trash-only"
+	case AuditMissing:
+		return "missing"
+	case AuditError:
+		return "error"
+	}
+	return fmt.Sprintf("audit(%d)", s)
+}
+
+// Exit codes of the audit command
+const (
+	AuditExitMissing = 2 // some files are missing, only in the trash, or unreadable
+	AuditExitWorse   = 3 // all files are on the server, but some in a lower quality
+)
+
+// AuditOptions are the options of the audit command
+type AuditOptions struct {
+	Output string // CSV file listing all the files
+	All    bool   // list also the files present on the server
+}
+
+// auditEntry is the audit result of a local file
+type auditEntry struct {
+	status     AuditStatus
+	file       string
+	assetID    string // server's asset, when found
+	serverName string
+	message    string
+}
+
+// auditReport collects the audit results
+type auditReport struct {
+	entries []auditEntry
+	counts  [AuditError + 1]int
+}
+
+func (r *auditReport) add(e auditEntry) {
+	r.entries = append(r.entries, e)
+	r.counts[e.status]++
+}
+
+// auditStatus translates the upload advice into the audit status
+func auditStatus(advice *Advice) AuditStatus {
+	if advice.ServerAsset != nil && advice.ServerAsset.Trashed {
+		return AuditTrashOnly
+	}
+	switch advice.Advice {
+	case SameOnServer, AlreadyProcessed:
+		return AuditPresent
+	case BetterOnServer:
+		return AuditBetterOnServer
+	case SmallerOnServer:
+		return AuditWorseOnServer
+	}
+	return AuditMissing
+}
+
+// exitError returns the error ending the audit, with the exit code matching the worst status
+func (r *auditReport) exitError() error {
+	if n := r.counts[AuditMissing] + r.counts[AuditTrashOnly] + r.counts[AuditError]; n > 0 {
+		return &app.ExitError{Code: AuditExitMissing, Err: fmt.Errorf("%d files are not on the server", n)}
+	}
+	if n := r.counts[AuditWorseOnServer]; n > 0 {
+		return &app.ExitError{Code: AuditExitWorse, Err: fmt.Errorf("%d files are on the server in a lower quality", n)}
+	}
+	return nil
+}
+
+// writeCSV writes all the entries into the file
+func (r *auditReport) writeCSV(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"status", "file", "asset_id", "server_file", "message"})
+	for _, e := range r.entries {
+		_ = w.Write([]string{e.status.String(), e.file, e.assetID, e.serverName, e.message})
+	}
+	w.Flush()
+	return errors.Join(w.Error(), f.Close())
+}
+
+// NewAuditCommand adds the audit command, checking that the local files are on the server
+func NewAuditCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Check that the local files are on the server, without uploading them",
+		Long: `Check that the local files are on the server, without uploading them.
+The exit code is 0 when all files are on the server, 2 when some files are missing or only in the trash, 3 when some files are on the server in a lower quality, and 1 on errors.`,
+	}
+	options := &AuditOptions{}
+	app.AddClientFlags(ctx, cmd, a, false)
+	cmd.TraverseChildren = true
+	cmd.PersistentFlags().StringVarP(&options.Output, "output", "o", "", "Write the status of all the files into a CSV file")
+	cmd.PersistentFlags().BoolVar(&options.All, "all", false, "List also the files present on the server")
+	cmd.PersistentPreRunE = app.ChainRunEFunctions(cmd.PersistentPreRunE, (&UploadOptions{}).Open, ctx, cmd, a)
+
+	cmd.AddCommand(newAuditFromFolderCommand(ctx, cmd, a, options))
+	cmd.AddCommand(newAuditFromGooglePhotosCommand(ctx, cmd, a, options))
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		return errors.New("you must specify a subcommand to the audit command")
+	}
+	return cmd
+}
+
+func newAuditFromFolderCommand(ctx context.Context, parent *cobra.Command, a *app.Application, auditOptions *AuditOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "from-folder [flags] <path>...",
+		Short: "Check that the files of a folder are on the server",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	cmd.SetContext(ctx)
+	options := &folder.ImportFolderOptions{}
+	options.AddFromFolderFlags(cmd, parent)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		options.TZ = a.GetTZ()
+
+		fsyss, err := fshelper.ParsePath(args)
+		if err != nil {
+			return err
+		}
+		if len(fsyss) == 0 {
+			return errors.New("No file found matching the pattern: " + strings.Join(args, ","))
+		}
+		options.SupportedMedia = a.Client().Immich.SupportedMedia()
+		options.InfoCollector = filenames.NewInfoCollector(a.GetTZ(), options.SupportedMedia)
+		adapter, err := folder.NewLocalFiles(ctx, a.Jnl(), options, fsyss...)
+		if err != nil {
+			return err
+		}
+		err = runAudit(ctx, a, auditOptions, adapter)
+		return errors.Join(err, fshelper.CloseFSs(fsyss))
+	}
+	return cmd
+}
+
+func newAuditFromGooglePhotosCommand(ctx context.Context, parent *cobra.Command, a *app.Application, auditOptions *AuditOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "from-google-photos [flags] <takeout-*.zip> | <takeout-folder>",
+		Short: "Check that the photos of a Google Photos takeout are on the server",
+		Args:  cobra.MinimumNArgs(1),
+	}
+	cmd.SetContext(ctx)
+	options := &gp.ImportFlags{}
+	options.AddFromGooglePhotosFlags(cmd, parent)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		ctx := cmd.Context()
+		options.TZ = a.GetTZ()
+
+		fsyss, err := fshelper.ParsePath(args)
+		if err != nil {
+			return err
+		}
+		if len(fsyss) == 0 {
+			return errors.New("No file found matching the pattern: " + strings.Join(args, ","))
+		}
+		options.SupportedMedia = a.Client().Immich.SupportedMedia()
+		options.InfoCollector = filenames.NewInfoCollector(a.GetTZ(), options.SupportedMedia)
+		adapter, err := gp.NewTakeout(ctx, a.Jnl(), options, fsyss...)
+		if err != nil {
+			return err
+		}
+		err = runAudit(ctx, a, auditOptions, adapter)
+		return errors.Join(err, fshelper.CloseFSs(fsyss))
+	}
+	return cmd
+}
+
+// runAudit compares the assets given by the adapter with the server's assets, using the same rules as the upload
+func runAudit(ctx context.Context, a *app.Application, options *AuditOptions, adapter adapters.Reader) error {
+	log := a.Log()
+	upCmd := newUpload(UpModeFolder, a, &UploadOptions{})
+	upCmd.assetIndex = newAssetIndex()
+	err := upCmd.getImmichAssets(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	report := &auditReport{}
+	for g := range adapter.Browse(ctx) {
+		for _, r := range g.Removed {
+			r.Asset.Close()
+		}
+		for _, la := range g.Assets {
+			e := auditEntry{file: la.File.FullName()}
+			advice, err := upCmd.assetIndex.ShouldUpload(la)
+			la.Close()
+			if err != nil {
+				e.status, e.message = AuditError, err.Error()
+			} else {
+				e.status, e.message = auditStatus(advice), advice.Message
+				if sa := advice.ServerAsset; sa != nil && e.status != AuditMissing {
+					e.assetID, e.serverName = sa.ID, sa.OriginalFileName
+				}
+			}
+			report.add(e)
+			if e.status != AuditPresent || options.All {
+				fmt.Printf("%-16s %s %s\n", e.status, e.file, e.assetID)
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if options.Output != "" {
+		if err := report.writeCSV(options.Output); err != nil {
+			return fmt.Errorf("can't write the audit report: %w", err)
+		}
+	}
+	log.Message("Audit of %d files:", len(report.entries))
+	for s := AuditPresent; s <= AuditError; s++ {
+		log.Message("%-16s: %d", s, report.counts[s])
+	}
+	return report.exitError()
+}
@@ -0,0 +1,53 @@
+package upload
+
+import (
+	"testing"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/assets"
+)
+
+func TestAuditStatus(t *testing.T) {
+	tests := []struct {
+		advice *Advice
+		want   AuditStatus
+	}{
+		{advice: &Advice{Advice: SameOnServer, ServerAsset: &assets.Asset{}}, want: AuditPresent},
+		{advice: &Advice{Advice: SameOnServer, ServerAsset: &assets.Asset{Trashed: true}}, want: AuditTrashOnly},
+		{advice: &Advice{Advice: BetterOnServer, ServerAsset: &assets.Asset{}}, want: AuditBetterOnServer},
+		{advice: &Advice{Advice: SmallerOnServer, ServerAsset: &assets.Asset{}}, want: AuditWorseOnServer},
+		{advice: &Advice{Advice: NotOnServer}, want: AuditMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want.String(), func(t *testing.T) {
+			if got := auditStatus(tt.advice); got != tt.want {
+				t.Errorf("auditStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []AuditStatus
+		want     int
+	}{
+		{name: "all present", statuses: []AuditStatus{AuditPresent, AuditBetterOnServer}, want: 0},
+		{name: "worse", statuses: []AuditStatus{AuditPresent, AuditWorseOnServer}, want: AuditExitWorse},
+		{name: "missing", statuses: []AuditStatus{AuditWorseOnServer, AuditMissing}, want: AuditExitMissing},
+		{name: "trash", statuses: []AuditStatus{AuditTrashOnly}, want: AuditExitMissing},
+		{name: "error", statuses: []AuditStatus{AuditError}, want: AuditExitMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &auditReport{}
+			for _, s := range tt.statuses {
+				r.add(auditEntry{status: s})
+			}
+			if got := app.ExitCode(r.exitError()); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
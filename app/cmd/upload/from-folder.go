@@ -3,10 +3,12 @@ package upload
 import (
 	"context"
 	"errors"
+	"io/fs"
 	"strings"
 
 	"github.com/simulot/immich-go/adapters/folder"
 	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/afterupload"
 	"github.com/simulot/immich-go/internal/filenames"
 	"github.com/simulot/immich-go/internal/fshelper"
 	"github.com/spf13/cobra"
@@ -21,13 +23,12 @@ func NewFromFolderCommand(ctx context.Context, parent *cobra.Command, app *app.A
 	cmd.SetContext(ctx)
 	options := &folder.ImportFolderOptions{}
 	options.AddFromFolderFlags(cmd, parent)
+	afterupload.AddFlags(cmd, &upOptions.AfterUpload)
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
 		// ready to run
 		ctx := cmd.Context()
 		log := app.Log()
-		client := app.Client()
-		options.TZ = app.GetTZ()
 
 		// parse arguments
 		fsyss, err := fshelper.ParsePath(args)
@@ -38,19 +39,31 @@ func NewFromFolderCommand(ctx context.Context, parent *cobra.Command, app *app.A
 			log.Message("No file found matching the pattern: %s", strings.Join(args, ","))
 			return errors.New("No file found matching the pattern: " + strings.Join(args, ","))
 		}
-
-		// create the adapter for folders
-		options.SupportedMedia = client.Immich.SupportedMedia()
-		upOptions.Filters = append(upOptions.Filters, options.ManageBurst.GroupFilter(), options.ManageRawJPG.GroupFilter(), options.ManageHEICJPG.GroupFilter())
-
-		options.InfoCollector = filenames.NewInfoCollector(app.GetTZ(), options.SupportedMedia)
-		adapter, err := folder.NewLocalFiles(ctx, app.Jnl(), options, fsyss...)
+		err = upOptions.AfterUpload.CheckSources(fsyss)
 		if err != nil {
 			return err
 		}
 
-		return newUpload(UpModeFolder, app, upOptions).run(ctx, adapter, app, fsyss)
+		return runFromFolder(ctx, app, options, upOptions, fsyss)
 	}
 
 	return cmd
 }
+
+// runFromFolder uploads the files found in the file systems
+func runFromFolder(ctx context.Context, app *app.Application, options *folder.ImportFolderOptions, upOptions *UploadOptions, fsyss []fs.FS) error {
+	client := app.Client()
+	options.TZ = app.GetTZ()
+
+	// create the adapter for folders
+	options.SupportedMedia = client.Immich.SupportedMedia()
+	upOptions.Filters = append(upOptions.Filters, options.ManageBurst.GroupFilter(), options.ManageRawJPG.GroupFilter(), options.ManageHEICJPG.GroupFilter())
+
+	options.InfoCollector = filenames.NewInfoCollector(app.GetTZ(), options.SupportedMedia)
+	adapter, err := folder.NewLocalFiles(ctx, app.Jnl(), options, fsyss...)
+	if err != nil {
+		return err
+	}
+
+	return newUpload(UpModeFolder, app, upOptions).run(ctx, adapter, app, fsyss)
+}
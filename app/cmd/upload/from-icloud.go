@@ -7,6 +7,7 @@ import (
 
 	"github.com/simulot/immich-go/adapters/folder"
 	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/afterupload"
 	"github.com/simulot/immich-go/internal/filenames"
 	"github.com/simulot/immich-go/internal/fshelper"
 	"github.com/spf13/cobra"
@@ -21,6 +22,7 @@ func NewFromICloudCommand(ctx context.Context, parent *cobra.Command, app *app.A
 	cmd.SetContext(ctx)
 	options := &folder.ImportFolderOptions{}
 	options.AddFromICloudFlags(cmd, parent)
+	afterupload.AddFlags(cmd, &upOptions.AfterUpload)
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
 		// ready to run
@@ -38,6 +40,10 @@ func NewFromICloudCommand(ctx context.Context, parent *cobra.Command, app *app.A
 			log.Message("No file found matching the pattern: %s", strings.Join(args, ","))
 			return errors.New("No file found matching the pattern: " + strings.Join(args, ","))
 		}
+		err = upOptions.AfterUpload.CheckSources(fsyss)
+		if err != nil {
+			return err
+		}
 
 		// create the adapter for folders
 		options.SupportedMedia = client.Immich.SupportedMedia()
@@ -49,7 +55,7 @@ func NewFromICloudCommand(ctx context.Context, parent *cobra.Command, app *app.A
 			return err
 		}
 
-		return newUpload(UpModeFolder, app, upOptions).run(ctx, adapter, app, fsyss)
+		return newUpload(UpModeICloud, app, upOptions).run(ctx, adapter, app, fsyss)
 	}
 
 	return cmd
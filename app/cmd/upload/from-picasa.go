@@ -49,7 +49,7 @@ func NewFromPicasaCommand(ctx context.Context, parent *cobra.Command, app *app.A
 			return err
 		}
 
-		return newUpload(UpModeFolder, app, upOptions).run(ctx, adapter, app, fsyss)
+		return newUpload(UpModePicasa, app, upOptions).run(ctx, adapter, app, fsyss)
 	}
 
 	return cmd
@@ -3,6 +3,7 @@ package upload
 import (
 	"context"
 	"errors"
+	"io/fs"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -29,9 +30,6 @@ func NewFromGooglePhotosCommand(ctx context.Context, parent *cobra.Command, app
 	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
 		ctx := cmd.Context()
 		log := app.Log()
-		client := app.Client()
-
-		options.TZ = app.GetTZ()
 
 		fsyss, err := fshelper.ParsePath(args)
 		if err != nil {
@@ -42,34 +40,41 @@ func NewFromGooglePhotosCommand(ctx context.Context, parent *cobra.Command, app
 			return errors.New("No file found matching the pattern: " + strings.Join(args, ","))
 		}
 
-		if options.TakeoutTag {
-			for _, fsys := range fsyss {
-				if fsys, ok := fsys.(fshelper.NameFS); ok {
-					options.TakeoutName = fsys.Name()
-					break
-				}
-			}
+		return runFromGooglePhotos(ctx, app, options, upOptions, fsyss)
+	}
 
-			if filepath.Ext(options.TakeoutName) == ".zip" {
-				options.TakeoutName = strings.TrimSuffix(options.TakeoutName, filepath.Base(options.TakeoutName))
-			}
-			if options.TakeoutName == "" {
-				options.TakeoutTag = false
+	return cmd
+}
+
+// runFromGooglePhotos uploads the takeout found in the file systems
+func runFromGooglePhotos(ctx context.Context, app *app.Application, options *gp.ImportFlags, upOptions *UploadOptions, fsyss []fs.FS) error {
+	client := app.Client()
+	options.TZ = app.GetTZ()
+
+	if options.TakeoutTag {
+		for _, fsys := range fsyss {
+			if fsys, ok := fsys.(fshelper.NameFS); ok {
+				options.TakeoutName = fsys.Name()
+				break
 			}
-			options.TakeoutName = _re3digits.ReplaceAllString(options.TakeoutName, "")
 		}
 
-		upOptions.Filters = append(upOptions.Filters, options.ManageBurst.GroupFilter(), options.ManageRawJPG.GroupFilter(), options.ManageHEICJPG.GroupFilter())
-
-		options.SupportedMedia = client.Immich.SupportedMedia()
-		options.InfoCollector = filenames.NewInfoCollector(app.GetTZ(), options.SupportedMedia)
-		adapter, err := gp.NewTakeout(ctx, app.Jnl(), options, fsyss...)
-		if err != nil {
-			return err
+		if filepath.Ext(options.TakeoutName) == ".zip" {
+			options.TakeoutName = strings.TrimSuffix(options.TakeoutName, filepath.Base(options.TakeoutName))
 		}
-		err = newUpload(UpModeGoogleTakeout, app, upOptions).setTakeoutOptions(options).run(ctx, adapter, app, fsyss)
-		return err
+		if options.TakeoutName == "" {
+			options.TakeoutTag = false
+		}
+		options.TakeoutName = _re3digits.ReplaceAllString(options.TakeoutName, "")
 	}
 
-	return cmd
+	upOptions.Filters = append(upOptions.Filters, options.ManageBurst.GroupFilter(), options.ManageRawJPG.GroupFilter(), options.ManageHEICJPG.GroupFilter())
+
+	options.SupportedMedia = client.Immich.SupportedMedia()
+	options.InfoCollector = filenames.NewInfoCollector(app.GetTZ(), options.SupportedMedia)
+	adapter, err := gp.NewTakeout(ctx, app.Jnl(), options, fsyss...)
+	if err != nil {
+		return err
+	}
+	return newUpload(UpModeGoogleTakeout, app, upOptions).setTakeoutOptions(options).run(ctx, adapter, app, fsyss)
 }
@@ -0,0 +1,73 @@
+package upload
+
+import (
+	"context"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/fileevent"
+	"github.com/simulot/immich-go/internal/geotrack"
+)
+
+// openGeotagger reads the track files given with the option --gpx
+func (upCmd *UpCmd) openGeotagger() error {
+	if len(upCmd.Geotag.Files) == 0 {
+		return nil
+	}
+	g, err := geotrack.NewGeotagger(upCmd.Geotag)
+	if err != nil {
+		return err
+	}
+	first, last := g.Track().Span()
+	upCmd.app.Log().Info("GPS tracks loaded", "points", g.Track().Len(), "from", first, "to", last)
+	upCmd.geotagger = g
+	return nil
+}
+
+// geotag sets the position of an asset without GPS data using the tracks.
+// The capture date and the position are the ones collected by the adapter, the file isn't read again.
+// It returns true when the position has been found.
+func (upCmd *UpCmd) geotag(ctx context.Context, a *assets.Asset) bool {
+	if upCmd.geotagger == nil || a.Latitude != 0 || a.Longitude != 0 {
+		return false
+	}
+	captureDate := a.CaptureDate
+	for _, md := range []*assets.Metadata{a.FromSideCar, a.FromSourceFile} {
+		if md == nil {
+			continue
+		}
+		if md.Latitude != 0 || md.Longitude != 0 {
+			return false
+		}
+		if captureDate.IsZero() {
+			captureDate = md.DateTaken
+		}
+	}
+
+	p, ok := upCmd.geotagger.Locate(captureDate)
+	if !ok {
+		return false
+	}
+	a.Latitude, a.Longitude = p.Latitude, p.Longitude
+	if a.FromApplication != nil {
+		// the application's metadata are applied after the upload
+		a.FromApplication.Latitude, a.FromApplication.Longitude = p.Latitude, p.Longitude
+	}
+	upCmd.app.Jnl().Record(ctx, fileevent.Geotagged, a.File, "latitude", p.Latitude, "longitude", p.Longitude)
+	return true
+}
+
+// sendPosition gives the position found in the tracks to the server.
+// The asset is already uploaded: a failure is recorded as a warning, and the processing of the asset goes on.
+func (upCmd *UpCmd) sendPosition(ctx context.Context, a *assets.Asset, serverStatus string) {
+	if serverStatus == immich.StatusDuplicate {
+		return
+	}
+	_, err := upCmd.app.Client().Immich.UpdateAsset(ctx, a.ID, immich.UpdAssetField{
+		Latitude:  a.Latitude,
+		Longitude: a.Longitude,
+	})
+	if err != nil {
+		upCmd.app.Jnl().Record(ctx, fileevent.INFO, a.File, "warning", "the position found in the tracks can't be sent: "+err.Error())
+	}
+}
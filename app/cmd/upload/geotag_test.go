@@ -0,0 +1,136 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/assets"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+	"github.com/simulot/immich-go/internal/fakeImmich/cmdtest"
+	"github.com/simulot/immich-go/internal/fileevent"
+	"github.com/simulot/immich-go/internal/fshelper"
+	"github.com/simulot/immich-go/internal/geotrack"
+)
+
+const testTrack = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1"><trk><trkseg>
+  <trkpt lat="48.8584" lon="2.2945"><time>2023-07-14T10:00:00Z</time></trkpt>
+  <trkpt lat="48.8606" lon="2.3376"><time>2023-07-14T10:10:00Z</time></trkpt>
+</trkseg></trk></gpx>`
+
+const testSidecar = `<x:xmpmeta xmlns:x='adobe:ns:meta/'>
+<rdf:RDF xmlns:rdf='http://www.w3.org/1999/02/22-rdf-syntax-ns#'>
+ <rdf:Description rdf:about='' xmlns:xmp='http://ns.adobe.com/xap/1.0/'>
+  <xmp:Rating>0</xmp:Rating>
+ </rdf:Description>
+ <rdf:Description rdf:about='' xmlns:exif='http://ns.adobe.com/exif/1.0/'>
+  <exif:DateTimeOriginal>2023-07-14T10:05:00Z</exif:DateTimeOriginal>
+ </rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>`
+
+// openCountFS counts the opened files
+type openCountFS struct {
+	fstest.MapFS
+	opened int
+}
+
+func (fsys *openCountFS) Open(name string) (fs.File, error) {
+	fsys.opened++
+	return fsys.MapFS.Open(name)
+}
+
+func TestGeotag(t *testing.T) {
+	gpx := filepath.Join(t.TempDir(), "track.gpx")
+	if err := os.WriteFile(gpx, []byte(testTrack), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := app.NewWithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	upCmd := newUpload(UpModeFolder, a, &UploadOptions{Geotag: geotrack.Options{Files: []string{gpx}, MaxGap: 10 * time.Minute}})
+	if err := upCmd.openGeotagger(); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := &openCountFS{MapFS: fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: []byte("no exif")}}}
+	inTrack := time.Date(2023, 7, 14, 10, 5, 0, 0, time.UTC)
+	asset := func(captureDate time.Time) *assets.Asset {
+		return &assets.Asset{File: fshelper.FSName(fsys, "photo.jpg"), CaptureDate: captureDate}
+	}
+
+	located := asset(inTrack)
+	if !upCmd.geotag(context.Background(), located) || located.Latitude == 0 || located.Longitude == 0 {
+		t.Errorf("the asset taken during the track should be positioned: %v, %v", located.Latitude, located.Longitude)
+	}
+
+	fromSidecar := asset(time.Time{})
+	fromSidecar.FromSideCar = &assets.Metadata{DateTaken: inTrack}
+	if !upCmd.geotag(context.Background(), fromSidecar) {
+		t.Error("the date of the sidecar should be used")
+	}
+
+	positioned := asset(inTrack)
+	positioned.FromSourceFile = &assets.Metadata{Latitude: 45, Longitude: 5}
+	if upCmd.geotag(context.Background(), positioned) {
+		t.Error("an asset having a position must be left untouched")
+	}
+
+	if upCmd.geotag(context.Background(), asset(time.Time{})) {
+		t.Error("an asset without date can't be positioned")
+	}
+
+	if fsys.opened != 0 {
+		t.Errorf("the files have been opened %d times, they shouldn't", fsys.opened)
+	}
+}
+
+func TestGeotagRefusedPosition(t *testing.T) {
+	dir := t.TempDir()
+	gpx := filepath.Join(dir, "track.gpx")
+	photos := filepath.Join(dir, "photos")
+	if err := os.Mkdir(photos, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		gpx:                                    testTrack,
+		filepath.Join(photos, "photo.jpg"):     "no exif",
+		filepath.Join(photos, "photo.jpg.xmp"): testSidecar,
+	} {
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := cmdtest.NewServer(t)
+	s.InjectFault(fakeimmich.Fault{Method: "PUT", Path: "/api/assets/*", Status: 500})
+	a := cmdtest.NewApp()
+	_, err := cmdtest.RunApp(a, s, NewUploadCommand, "from-folder", "--no-ui",
+		"--gpx", gpx, "--into-album", "Trip", "--tag", "hike", photos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refused := false
+	for _, c := range s.Calls() {
+		refused = refused || strings.HasPrefix(c, "PUT /api/assets/")
+	}
+	if !refused {
+		t.Fatalf("the position should have been sent: %v", s.Calls())
+	}
+	counts := a.Jnl().GetCounts()
+	if counts[fileevent.Uploaded] != 1 || counts[fileevent.Geotagged] != 1 || counts[fileevent.UploadServerError] != 0 {
+		t.Errorf("uploaded: %d, geotagged: %d, server errors: %d, want 1, 1 and 0",
+			counts[fileevent.Uploaded], counts[fileevent.Geotagged], counts[fileevent.UploadServerError])
+	}
+	if len(s.Albums()["Trip"]) != 1 || len(s.Tags()["hike"]) != 1 {
+		t.Errorf("the asset should be added to its album and tag: %v, %v", s.Albums(), s.Tags())
+	}
+}
@@ -0,0 +1,76 @@
+package upload
+
+import (
+	"context"
+	"errors"
+
+	"github.com/simulot/immich-go/adapters"
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/fileevent"
+	"golang.org/x/sync/errgroup"
+)
+
+// Run uploads the assets produced by the adapter, without any user interface.
+// The indexProgress function, when not nil, is called while the server's assets are read.
+//
+// The application must have an opened client and a journal.
+// Run is the entry point used when immich-go is embedded into another program.
+func Run(ctx context.Context, a *app.Application, options *UploadOptions, adapter adapters.Reader, indexProgress func(value, total int)) error {
+	if a.Jnl() == nil {
+		a.SetJnl(fileevent.NewRecorder(a.Log().Logger))
+	}
+	upCmd := newUpload(UpModeFolder, a, options)
+	if err := upCmd.openGeotagger(); err != nil {
+		return err
+	}
+	upCmd.openCaches(ctx)
+	defer upCmd.closeCaches()
+
+	upCmd.adapter = adapter
+	upCmd.assetIndex = newAssetIndex()
+	return upCmd.runHeadless(ctx, indexProgress)
+}
+
+// runHeadless runs the upload pipeline without reporting the progression on the console.
+func (upCmd *UpCmd) runHeadless(ctx context.Context, immichUpdate progressUpdate) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	processGrp := errgroup.Group{}
+	var groupChan chan *assets.Group
+
+	processGrp.Go(func() error {
+		// Get immich asset
+		err := upCmd.getImmichAssets(ctx, immichUpdate)
+		if err != nil {
+			cancel(err)
+		}
+		return err
+	})
+	processGrp.Go(func() error {
+		return upCmd.getImmichAlbums(ctx)
+	})
+	processGrp.Go(func() error {
+		groupChan = upCmd.prefetcher.Run(ctx, upCmd.browse(ctx))
+		return nil
+	})
+	err := processGrp.Wait()
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+		return err
+	}
+
+	err = upCmd.uploadLoop(ctx, groupChan)
+	if err != nil {
+		return err
+	}
+
+	counts := upCmd.app.Jnl().GetCounts()
+	if counts[fileevent.Error]+counts[fileevent.UploadServerError] > 0 {
+		return errors.New("some errors have occurred, look at the log for details")
+	}
+	return nil
+}
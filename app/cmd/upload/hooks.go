@@ -0,0 +1,131 @@
+package upload
+
+import (
+	"context"
+
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/fileevent"
+	"github.com/simulot/immich-go/internal/fshelper"
+	"github.com/simulot/immich-go/internal/hooks"
+)
+
+// runHook calls the user's hook of the phase for the asset.
+// It returns false when the asset must not be uploaded.
+// The hook's failures are handled according to the --hook-on-failure policy.
+func (upCmd *UpCmd) runHook(ctx context.Context, phase hooks.Phase, a *assets.Asset, advice *Advice, status string) (bool, error) {
+	if !upCmd.Hooks.IsSet(phase) {
+		return true, nil
+	}
+	return upCmd.callHook(ctx, newHookRequest(phase, a, advice, status), a.File, a)
+}
+
+func newHookRequest(phase hooks.Phase, a *assets.Asset, advice *Advice, status string) hooks.Request {
+	req := hooks.Request{
+		Phase:    phase,
+		File:     a.File.FullName(),
+		Metadata: hooks.NewMetadata(a),
+		ServerID: a.ID,
+		Status:   status,
+	}
+	if advice != nil {
+		req.Advice = advice.Advice.String()
+		if advice.ServerAsset != nil && req.ServerID == "" {
+			req.ServerID = advice.ServerAsset.ID
+		}
+	}
+	return req
+}
+
+// callHook runs the hook and handles its response. The asset is nil after the upload, when it can't be changed anymore.
+func (upCmd *UpCmd) callHook(ctx context.Context, req hooks.Request, file fshelper.FSAndName, a *assets.Asset) (bool, error) {
+	phase := req.Phase
+	resp, err := upCmd.Hooks.Run(ctx, req)
+	if err != nil {
+		switch upCmd.Hooks.OnFailure {
+		case hooks.FailureContinue:
+			upCmd.app.Log().Warn("hook failure ignored", "file", file, "err", err)
+			return true, nil
+		case hooks.FailureSkip:
+			if phase != hooks.BeforeUpload {
+				upCmd.app.Log().Warn("hook failure ignored", "file", file, "err", err)
+				return true, nil
+			}
+			upCmd.app.Jnl().Record(ctx, fileevent.UploadNotSelected, file, "reason", "the hook has failed", "warning", err.Error())
+			return false, nil
+		default:
+			upCmd.app.Jnl().Record(ctx, fileevent.Error, file, "error", err.Error())
+			return false, err
+		}
+	}
+
+	switch resp.Action {
+	case hooks.Skip:
+		if phase != hooks.BeforeUpload {
+			upCmd.app.Log().Warn("the hook can't skip an asset already uploaded", "file", file, "phase", phase)
+			return true, nil
+		}
+		upCmd.app.Jnl().Record(ctx, fileevent.UploadNotSelected, file, "reason", "rejected by the hook", "hook reason", resp.Reason)
+		return false, nil
+	case hooks.Override:
+		if phase != hooks.BeforeUpload {
+			upCmd.app.Log().Warn("the hook can't override the metadata of an asset already uploaded", "file", file, "phase", phase)
+			return true, nil
+		}
+		resp.Metadata.Apply(a)
+		upCmd.app.Log().Info("metadata overridden by the hook", "file", file, "reason", resp.Reason)
+	}
+	return true, nil
+}
+
+// albumHook is an after-album request waiting for the albums to be saved on the server
+type albumHook struct {
+	file fshelper.FSAndName
+	req  hooks.Request
+	left int // number of albums not saved yet
+}
+
+// waitAlbumHook prepares the after-album hook of an uploaded asset. The hook is called
+// when the album cache saves the asset in each of its albums on the server.
+func (upCmd *UpCmd) waitAlbumHook(a *assets.Asset, advice *Advice, status string) {
+	if !upCmd.Hooks.IsSet(hooks.AfterAlbum) || len(a.Albums) == 0 || a.ID == "" {
+		return
+	}
+	upCmd.albumHooksLock.Lock()
+	defer upCmd.albumHooksLock.Unlock()
+	if upCmd.albumHooks == nil {
+		upCmd.albumHooks = map[string]albumHook{}
+	}
+	titles := map[string]bool{} // the album cache saves the asset once per title
+	for _, al := range a.Albums {
+		titles[al.Title] = true
+	}
+	upCmd.albumHooks[a.ID] = albumHook{file: a.File, req: newHookRequest(hooks.AfterAlbum, a, advice, status), left: len(titles)}
+}
+
+// runAlbumHooks calls the after-album hook of the assets just added to the album on the server.
+// The request of an asset is forgotten once all its albums are saved.
+// The failures are recorded, but can't stop the upload of the asset already done.
+func (upCmd *UpCmd) runAlbumHooks(ctx context.Context, album assets.Album, ids []string) {
+	if !upCmd.Hooks.IsSet(hooks.AfterAlbum) {
+		return
+	}
+	for _, id := range ids {
+		upCmd.albumHooksLock.Lock()
+		h, ok := upCmd.albumHooks[id]
+		if ok {
+			h.left--
+			if h.left <= 0 {
+				delete(upCmd.albumHooks, id)
+			} else {
+				upCmd.albumHooks[id] = h
+			}
+		}
+		upCmd.albumHooksLock.Unlock()
+		if !ok {
+			continue
+		}
+		req := h.req
+		req.Album = album.Title
+		_, _ = upCmd.callHook(ctx, req, h.file, nil)
+	}
+}
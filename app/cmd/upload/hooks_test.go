@@ -0,0 +1,57 @@
+package upload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/fakeImmich/cmdtest"
+	"github.com/simulot/immich-go/internal/fshelper"
+	"github.com/simulot/immich-go/internal/hooks"
+)
+
+func TestAlbumHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not available on windows")
+	}
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	script := filepath.Join(dir, "hook.sh")
+	err := os.WriteFile(script, []byte("#!/bin/sh\ncat > /dev/null\necho album >> "+calls+"\n"), 0o700) // #nosec G306
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upCmd := newUpload(UpModeFolder, cmdtest.NewApp(), &UploadOptions{Hooks: hooks.Options{AfterAlbum: script}})
+	fsys := fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: []byte("photo")}}
+	a := &assets.Asset{
+		File:   fshelper.FSName(fsys, "photo.jpg"),
+		ID:     "id-1",
+		Albums: []assets.Album{{Title: "Holidays"}, {Title: "Family"}, {Title: "Holidays"}},
+	}
+	upCmd.waitAlbumHook(a, nil, "")
+
+	ctx := context.Background()
+	upCmd.runAlbumHooks(ctx, assets.Album{Title: "Holidays"}, []string{"id-1"})
+	if _, ok := upCmd.albumHooks["id-1"]; !ok {
+		t.Fatal("the request must wait for the second album")
+	}
+	upCmd.runAlbumHooks(ctx, assets.Album{Title: "Family"}, []string{"id-1"})
+	if _, ok := upCmd.albumHooks["id-1"]; ok {
+		t.Error("the request must be forgotten once all the albums are saved")
+	}
+	upCmd.runAlbumHooks(ctx, assets.Album{Title: "Other"}, []string{"id-1"})
+
+	b, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(b), "album"); n != 2 {
+		t.Errorf("the hook has been called %d times, want 2", n)
+	}
+}
@@ -0,0 +1,36 @@
+package upload
+
+import (
+	"context"
+
+	"github.com/simulot/immich-go/internal/serverjobs"
+)
+
+// pauseJobs pauses the server's heavy job queues during the upload.
+// The returned function resumes the queues paused by immich-go.
+func (upCmd *UpCmd) pauseJobs(ctx context.Context) func() {
+	if !upCmd.PauseJobs {
+		return func() {}
+	}
+	client := upCmd.app.Client().Immich
+	paused, err := serverjobs.Pause(ctx, client, serverjobs.HeavyQueues...)
+	if err != nil {
+		// the job queues can be controlled only by administrators
+		upCmd.app.Log().Warn("can't pause the server's jobs", "err", err)
+	}
+	if len(paused) > 0 {
+		upCmd.app.Log().Info("server's jobs paused during the upload", "queues", paused)
+	}
+	return func() {
+		if len(paused) == 0 {
+			return
+		}
+		// resume the queues, even when the upload is interrupted
+		err := serverjobs.Resume(context.WithoutCancel(ctx), client, paused...)
+		if err != nil {
+			upCmd.app.Log().Error("can't resume the server's jobs", "err", err)
+			return
+		}
+		upCmd.app.Log().Info("server's jobs resumed", "queues", paused)
+	}
+}
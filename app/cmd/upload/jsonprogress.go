@@ -0,0 +1,254 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/fileevent"
+)
+
+// Values of the --progress flag
+const (
+	ProgressUI   = "ui"   // interactive user interface
+	ProgressText = "text" // progress line, like --no-ui
+	ProgressJSON = "json" // newline-delimited JSON events on the standard output
+)
+
+// jsonSchemaVersion is the version of the JSON events, documented in docs/progress-json.md.
+// Fields can be added without changing the version. It changes when a field is removed or changes of meaning.
+const jsonSchemaVersion = 1
+
+// jsonEvent is the header of all events
+type jsonEvent struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+}
+
+type jsonStartEvent struct {
+	jsonEvent
+	Schema  int    `json:"schema"`
+	Version string `json:"version"`
+	Mode    string `json:"mode"`
+	DryRun  bool   `json:"dry_run"`
+}
+
+type jsonPhaseEvent struct {
+	jsonEvent
+	Phase string `json:"phase"`
+}
+
+type jsonFileEvent struct {
+	jsonEvent
+	Event   string         `json:"event"`
+	File    string         `json:"file,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+type jsonProgressEvent struct {
+	jsonEvent
+	ServerReadPercent int              `json:"server_read_percent"`
+	AssetsFound       int64            `json:"assets_found"`
+	AssetsProcessed   int64            `json:"assets_processed"`
+	BytesUploaded     int64            `json:"bytes_uploaded"`
+	BytesPerSecond    float64          `json:"bytes_per_second"`
+	AssetsPerSecond   float64          `json:"assets_per_second"`
+	ETASeconds        *float64         `json:"eta_seconds"` // null when unknown
+	UploadPaused      bool             `json:"upload_paused"`
+	Counters          map[string]int64 `json:"counters"`
+}
+
+type jsonErrorEvent struct {
+	jsonEvent
+	Message string `json:"message"`
+}
+
+type jsonEndEvent struct {
+	jsonEvent
+	ElapsedSeconds float64          `json:"elapsed_seconds"`
+	BytesUploaded  int64            `json:"bytes_uploaded"`
+	Counters       map[string]int64 `json:"counters"`
+	Error          string           `json:"error,omitempty"`
+}
+
+// jsonProgress writes the progression of the upload as JSON events, one per line
+type jsonProgress struct {
+	lock sync.Mutex
+	enc  *json.Encoder
+	jnl  *fileevent.Recorder
+
+	keepJSONLess   bool // the assets without JSON are uploaded
+	start          time.Time
+	uploadStart    time.Time // end of the server reading
+	processedStart int64     // assets processed before the upload start
+	uploadOnce     sync.Once
+	uploading      atomic.Bool // the fields above are set
+
+	serverRead    atomic.Int64 // percentage of the server's assets read
+	bytesUploaded atomic.Int64
+}
+
+func newJSONProgress(w io.Writer, jnl *fileevent.Recorder) *jsonProgress {
+	jp := &jsonProgress{
+		enc:   json.NewEncoder(w),
+		jnl:   jnl,
+		start: time.Now(),
+	}
+	jnl.AddListener(jp.fileEvent)
+	return jp
+}
+
+func (jp *jsonProgress) write(v any) {
+	jp.lock.Lock()
+	defer jp.lock.Unlock()
+	_ = jp.enc.Encode(v)
+}
+
+func (jp *jsonProgress) header(t string) jsonEvent {
+	return jsonEvent{Type: t, Time: time.Now()}
+}
+
+func (jp *jsonProgress) begin(mode UpLoadMode, dryRun bool) {
+	jp.write(jsonStartEvent{jsonEvent: jp.header("start"), Schema: jsonSchemaVersion, Version: app.Version, Mode: mode.Name(), DryRun: dryRun})
+	jp.phase("reading_server")
+}
+
+func (jp *jsonProgress) phase(p string) {
+	jp.write(jsonPhaseEvent{jsonEvent: jp.header("phase"), Phase: p})
+}
+
+// serverProgress follows the reading of the server's assets. The upload phase starts when they are all read.
+func (jp *jsonProgress) serverProgress(value, total int) {
+	pct := 100
+	if total > 0 {
+		pct = 100 * value / total
+	}
+	jp.serverRead.Store(int64(pct))
+	if value >= total {
+		jp.uploadOnce.Do(func() {
+			jp.uploadStart = time.Now()
+			jp.processedStart = jp.jnl.TotalProcessed(jp.keepJSONLess)
+			jp.uploading.Store(true)
+			jp.phase("uploading")
+		})
+	}
+}
+
+// fileEvent is the journal's listener
+func (jp *jsonProgress) fileEvent(_ context.Context, code fileevent.Code, file slog.LogValuer, args ...any) {
+	e := jsonFileEvent{jsonEvent: jp.header("file"), Event: code.Name()}
+	if file != nil {
+		e.File = file.LogValue().String()
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		if e.Details == nil {
+			e.Details = map[string]any{}
+		}
+		e.Details[fmt.Sprint(args[i])] = jsonValue(args[i+1])
+	}
+	if code == fileevent.Uploaded {
+		if size, ok := e.Details["size"].(int); ok {
+			jp.bytesUploaded.Add(int64(size))
+		}
+	}
+	jp.write(e)
+}
+
+// jsonValue keeps the simple values, and gives the text of the others
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case string, bool, int, int64, float64:
+		return v
+	case slog.LogValuer:
+		return v.LogValue().String()
+	case error:
+		return v.Error()
+	}
+	return fmt.Sprint(v)
+}
+
+func (jp *jsonProgress) counters() map[string]int64 {
+	counts := jp.jnl.GetCounts()
+	m := make(map[string]int64, len(counts))
+	for c, n := range counts {
+		m[fileevent.Code(c).Name()] = n
+	}
+	return m
+}
+
+func (jp *jsonProgress) progress(paused bool) {
+	e := jsonProgressEvent{
+		jsonEvent:         jp.header("progress"),
+		ServerReadPercent: int(jp.serverRead.Load()),
+		AssetsFound:       jp.jnl.TotalAssets(),
+		AssetsProcessed:   jp.jnl.TotalProcessed(jp.keepJSONLess),
+		BytesUploaded:     jp.bytesUploaded.Load(),
+		UploadPaused:      paused,
+		Counters:          jp.counters(),
+	}
+	if jp.uploading.Load() {
+		if elapsed := time.Since(jp.uploadStart).Seconds(); elapsed > 0 {
+			e.BytesPerSecond = float64(e.BytesUploaded) / elapsed
+			e.AssetsPerSecond = float64(e.AssetsProcessed-jp.processedStart) / elapsed
+			if e.AssetsPerSecond > 0 {
+				eta := float64(max(e.AssetsFound-e.AssetsProcessed, 0)) / e.AssetsPerSecond
+				e.ETASeconds = &eta
+			}
+		}
+	}
+	jp.write(e)
+}
+
+func (jp *jsonProgress) end(err error) {
+	if err != nil {
+		jp.write(jsonErrorEvent{jsonEvent: jp.header("error"), Message: err.Error()})
+	}
+	jp.phase("done")
+	e := jsonEndEvent{
+		jsonEvent:      jp.header("end"),
+		ElapsedSeconds: time.Since(jp.start).Seconds(),
+		BytesUploaded:  jp.bytesUploaded.Load(),
+		Counters:       jp.counters(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	jp.write(e)
+}
+
+// runJSON runs the upload, and writes the progression as JSON events on the standard output
+func (upCmd *UpCmd) runJSON(ctx context.Context, app *app.Application) error {
+	jp := newJSONProgress(os.Stdout, app.Jnl())
+	jp.keepJSONLess = upCmd.takeoutOptions != nil && upCmd.takeoutOptions.KeepJSONLess
+	jp.begin(upCmd.Mode, app.Client().DryRun)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				jp.progress(upCmd.pausedUntil.Load() != 0)
+				return
+			case <-ticker.C:
+				jp.progress(upCmd.pausedUntil.Load() != 0)
+			}
+		}
+	}()
+
+	err := upCmd.runHeadless(ctx, jp.serverProgress)
+	close(stop)
+	<-done
+	jp.end(err)
+	return err
+}
@@ -0,0 +1,68 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/internal/fileevent"
+	"github.com/simulot/immich-go/internal/fshelper"
+)
+
+func TestJSONProgress(t *testing.T) {
+	ctx := context.Background()
+	buf := bytes.NewBuffer(nil)
+	jnl := fileevent.NewRecorder(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	jp := newJSONProgress(buf, jnl)
+	fsys := fstest.MapFS{"a.jpg": &fstest.MapFile{Data: []byte("a")}}
+
+	jp.begin(UpModeFolder, false)
+	jp.serverProgress(5, 10)
+	jp.serverProgress(10, 10)
+	jnl.Record(ctx, fileevent.DiscoveredImage, fshelper.FSName(fsys, "a.jpg"))
+	jnl.Record(ctx, fileevent.Uploaded, fshelper.FSName(fsys, "a.jpg"), "size", 1000)
+	jp.progress(false)
+	jp.end(errors.New("some errors have occurred"))
+
+	types := []string{}
+	events := []map[string]any{}
+	s := bufio.NewScanner(buf)
+	for s.Scan() {
+		e := map[string]any{}
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatalf("invalid line %q: %v", s.Text(), err)
+		}
+		types = append(types, e["type"].(string))
+		events = append(events, e)
+	}
+	want := []string{"start", "phase", "phase", "file", "file", "progress", "error", "phase", "end"}
+	if !reflect.DeepEqual(types, want) {
+		t.Fatalf("events %v, want %v", types, want)
+	}
+	if events[0]["schema"] != float64(jsonSchemaVersion) || events[0]["mode"] != "folder" {
+		t.Errorf("start event: %v", events[0])
+	}
+	if events[2]["phase"] != "uploading" || events[7]["phase"] != "done" {
+		t.Errorf("phases: %v, %v", events[2], events[7])
+	}
+	if e := events[4]; e["event"] != "uploaded" || e["file"] != "a.jpg" || e["details"].(map[string]any)["size"] != float64(1000) {
+		t.Errorf("file event: %v", e)
+	}
+	p := events[5]
+	if p["server_read_percent"] != float64(100) || p["bytes_uploaded"] != float64(1000) || p["assets_found"] != float64(1) || p["assets_processed"] != float64(1) {
+		t.Errorf("progress event: %v", p)
+	}
+	if p["counters"].(map[string]any)["uploaded"] != float64(1) {
+		t.Errorf("progress counters: %v", p["counters"])
+	}
+	if e := events[8]; e["error"] != "some errors have occurred" || e["bytes_uploaded"] != float64(1000) {
+		t.Errorf("end event: %v", e)
+	}
+}
@@ -0,0 +1,144 @@
+package upload
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/fileevent"
+	"github.com/simulot/immich-go/internal/metrics"
+)
+
+// uploadMetrics are the Prometheus metrics of the upload, served on --metrics-addr.
+// The methods do nothing on a nil receiver, when the metrics are disabled.
+type uploadMetrics struct {
+	registry *metrics.Registry
+	server   *metrics.Server
+
+	events    *metrics.CounterVec   // journal events by code
+	bytes     *metrics.CounterVec   // bytes uploaded
+	requests  *metrics.CounterVec   // calls to the server by end point and status
+	latency   *metrics.HistogramVec // duration of the calls by end point
+	queue     *metrics.GaugeVec     // groups read from the source, not yet taken by the upload loop
+	flushes   *metrics.CounterVec   // album and tag cache flushes
+	tolerated *metrics.CounterVec   // errors tolerated by --on-server-errors
+}
+
+func newUploadMetrics() *uploadMetrics {
+	r := metrics.NewRegistry()
+	return &uploadMetrics{
+		registry:  r,
+		events:    r.NewCounterVec("immich_go_file_events_total", "Number of file events by code", "event"),
+		bytes:     r.NewCounterVec("immich_go_uploaded_bytes_total", "Number of bytes uploaded"),
+		requests:  r.NewCounterVec("immich_go_api_requests_total", "Number of calls to the server by end point and HTTP status, error when the server hasn't responded", "endpoint", "code"),
+		latency:   r.NewHistogramVec("immich_go_api_request_duration_seconds", "Duration of the calls to the server by end point", nil, "endpoint"),
+		queue:     r.NewGaugeVec("immich_go_upload_queue_groups", "Number of groups of assets read from the source and waiting for the upload"),
+		flushes:   r.NewCounterVec("immich_go_cache_flushes_total", "Number of flushes of the album and tag caches to the server", "cache"),
+		tolerated: r.NewCounterVec("immich_go_upload_errors_tolerated_total", "Number of upload errors tolerated by --on-server-errors, the upload going on"),
+	}
+}
+
+// openMetrics starts the metrics server when --metrics-addr is given
+func (upCmd *UpCmd) openMetrics() error {
+	if upCmd.MetricsAddr == "" {
+		return nil
+	}
+	m := newUploadMetrics()
+	s, err := metrics.Serve(upCmd.MetricsAddr, m.registry)
+	if err != nil {
+		return err
+	}
+	m.server = s
+	upCmd.metrics = m
+	upCmd.app.Jnl().AddListener(m.fileEvent)
+	if c, ok := upCmd.app.Client().Immich.(immich.ImmichCallObserverInterface); ok {
+		c.SetCallObserver(m.serverCall)
+	}
+	upCmd.app.Log().Info("metrics served", "url", "http://"+s.Addr()+"/metrics")
+	return nil
+}
+
+// closeMetrics stops the metrics server
+func (upCmd *UpCmd) closeMetrics() {
+	if upCmd.metrics == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := upCmd.metrics.server.Close(ctx); err != nil {
+		upCmd.app.Log().Warn("can't stop the metrics server", "err", err)
+	}
+}
+
+// fileEvent is the journal's listener
+func (m *uploadMetrics) fileEvent(_ context.Context, code fileevent.Code, _ slog.LogValuer, args ...any) {
+	m.events.Inc(code.Name())
+	if code != fileevent.Uploaded {
+		return
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == "size" {
+			if size, ok := args[i+1].(int); ok && size > 0 {
+				m.bytes.Add(float64(size))
+			}
+		}
+	}
+}
+
+// serverCall is the client's call observer
+func (m *uploadMetrics) serverCall(endPoint string, status int, d time.Duration) {
+	code := "error"
+	if status != 0 {
+		code = strconv.Itoa(status)
+	}
+	m.requests.Inc(endPoint, code)
+	m.latency.Observe(d.Seconds(), endPoint)
+}
+
+// cacheFlushed counts a flush of the album or tag cache
+func (m *uploadMetrics) cacheFlushed(cache string) {
+	if m == nil {
+		return
+	}
+	m.flushes.Inc(cache)
+}
+
+// errorTolerated counts an error of the upload loop that doesn't stop the upload
+func (m *uploadMetrics) errorTolerated() {
+	if m == nil {
+		return
+	}
+	m.tolerated.Inc()
+}
+
+// dequeued counts a group taken by the upload loop
+func (m *uploadMetrics) dequeued() {
+	if m == nil {
+		return
+	}
+	m.queue.Add(-1)
+}
+
+// browse reads the groups of the source, and counts them in the upload queue when the metrics are enabled
+func (upCmd *UpCmd) browse(ctx context.Context) chan *assets.Group {
+	in := upCmd.adapter.Browse(ctx)
+	if upCmd.metrics == nil {
+		return in
+	}
+	out := make(chan *assets.Group)
+	go func() {
+		defer close(out)
+		for g := range in {
+			upCmd.metrics.queue.Add(1)
+			select {
+			case out <- g:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
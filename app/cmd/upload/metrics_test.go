@@ -0,0 +1,51 @@
+package upload
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/internal/fileevent"
+)
+
+func TestUploadMetrics(t *testing.T) {
+	ctx := context.Background()
+	m := newUploadMetrics()
+	m.fileEvent(ctx, fileevent.DiscoveredImage, nil)
+	m.fileEvent(ctx, fileevent.Uploaded, nil, "size", 1000)
+	m.fileEvent(ctx, fileevent.Uploaded, nil, "size", 500)
+	m.serverCall("AssetUpload", 201, 200*time.Millisecond)
+	m.serverCall("AssetUpload", 0, time.Second)
+	m.cacheFlushed("album")
+	m.errorTolerated()
+	m.queue.Add(2)
+	m.dequeued()
+
+	sb := strings.Builder{}
+	if _, err := m.registry.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`immich_go_file_events_total{event="discovered_image"} 1`,
+		`immich_go_file_events_total{event="uploaded"} 2`,
+		`immich_go_uploaded_bytes_total 1500`,
+		`immich_go_api_requests_total{endpoint="AssetUpload",code="201"} 1`,
+		`immich_go_api_requests_total{endpoint="AssetUpload",code="error"} 1`,
+		`immich_go_api_request_duration_seconds_bucket{endpoint="AssetUpload",le="0.25"} 1`,
+		`immich_go_api_request_duration_seconds_count{endpoint="AssetUpload"} 2`,
+		`immich_go_cache_flushes_total{cache="album"} 1`,
+		`immich_go_upload_queue_groups 1`,
+		`immich_go_upload_errors_tolerated_total 1`,
+	} {
+		if !strings.Contains(sb.String(), want+"\n") {
+			t.Errorf("the metrics lack %q:\n%s", want, sb.String())
+		}
+	}
+
+	// disabled metrics
+	var none *uploadMetrics
+	none.cacheFlushed("tag")
+	none.dequeued()
+	none.errorTolerated()
+}
@@ -50,7 +50,11 @@ func (upCmd *UpCmd) runNoUI(ctx context.Context, app *app.Application) error {
 		}
 		lock.Unlock()
 
-		return fmt.Sprintf("\rImmich read %d%%, Assets found: %d, Upload errors: %d, Uploaded %d %s", immichPct, app.Jnl().TotalAssets(), counts[fileevent.UploadServerError], counts[fileevent.Uploaded], string(spinner[spinIdx]))
+		paused := ""
+		if upCmd.pausedUntil.Load() != 0 {
+			paused = ", Upload " + upCmd.throttleStatus()
+		}
+		return fmt.Sprintf("\rImmich read %d%%, Assets found: %d, Upload errors: %d, Uploaded %d%s %s", immichPct, app.Jnl().TotalAssets(), counts[fileevent.UploadServerError], counts[fileevent.Uploaded], paused, string(spinner[spinIdx]))
 	}
 	uiGrp := errgroup.Group{}
 
@@ -92,7 +96,7 @@ func (upCmd *UpCmd) runNoUI(ctx context.Context, app *app.Application) error {
 		})
 		processGrp.Go(func() error {
 			// Run Prepare
-			groupChan = upCmd.adapter.Browse(ctx)
+			groupChan = upCmd.prefetcher.Run(ctx, upCmd.browse(ctx))
 			return err
 		})
 		err = processGrp.Wait()
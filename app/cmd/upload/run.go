@@ -5,12 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/simulot/immich-go/adapters"
 	gp "github.com/simulot/immich-go/adapters/googlePhotos"
 	"github.com/simulot/immich-go/app"
 	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/afterupload"
 	"github.com/simulot/immich-go/internal/assets"
 	"github.com/simulot/immich-go/internal/assets/cache"
 	cliflags "github.com/simulot/immich-go/internal/cliFlags"
@@ -18,6 +25,10 @@ import (
 	"github.com/simulot/immich-go/internal/filters"
 	"github.com/simulot/immich-go/internal/fshelper"
 	"github.com/simulot/immich-go/internal/gen/syncset"
+	"github.com/simulot/immich-go/internal/geotrack"
+	"github.com/simulot/immich-go/internal/hooks"
+	"github.com/simulot/immich-go/internal/prefetch"
+	"github.com/simulot/immich-go/internal/throttle"
 )
 
 type UpCmd struct {
@@ -38,6 +49,33 @@ type UpCmd struct {
 
 	albumsCache *cache.CollectionCache[assets.Album] // List of albums present on the server
 	tagsCache   *cache.CollectionCache[assets.Tag]   // List of tags present on the server
+
+	prefetcher *prefetch.Prefetcher // Prepare the assets ahead of the upload
+
+	limiter     *throttle.Limiter // Limit the upload bandwidth
+	pausedUntil atomic.Int64      // Unix time of the end of the current pause, 0 when uploading
+
+	afterUpload     *afterupload.Processor // Move or delete the source files once uploaded
+	afterUploadList []afterUploadItem      // Source files to process at the end of the upload
+
+	geotagger *geotrack.Geotagger // Position the assets without GPS data, nil when no track is given
+
+	noTags     error     // Why the tags are ignored, nil when the server supports them
+	noTagsOnce sync.Once // Report once the ignored tags
+
+	metrics *uploadMetrics // Prometheus metrics, nil when disabled
+
+	albumHooksLock sync.Mutex
+	albumHooks     map[string]albumHook // after-album hooks waiting for the albums' saving, by asset ID
+}
+
+// afterUploadItem is a source file confirmed by the server
+type afterUploadItem struct {
+	file           fshelper.FSAndName
+	sidecars       []fshelper.FSAndName
+	checksum       string // local checksum
+	id             string // server's asset ID
+	serverChecksum string // server's asset checksum, when known
 }
 
 func newUpload(mode UpLoadMode, app *app.Application, options *UploadOptions) *UpCmd {
@@ -48,10 +86,63 @@ func newUpload(mode UpLoadMode, app *app.Application, options *UploadOptions) *U
 		localAssets:       syncset.New[string](),
 		immichAssetsReady: make(chan struct{}),
 	}
-
+	upCmd.prefetcher = prefetch.New(options.PrefetchWorkers, options.PrefetchWindow, int64(options.PrefetchMaxCache), upCmd.prepareAsset)
+	if options.AfterUpload.Policy.Action != afterupload.Keep {
+		if options.AfterUpload.Journal == "" {
+			options.AfterUpload.Journal = "immich-go-after-upload.jsonl"
+			if f := app.Log().File; f != "" {
+				options.AfterUpload.Journal = strings.TrimSuffix(f, filepath.Ext(f)) + ".after-upload.jsonl"
+			}
+		}
+		upCmd.afterUpload = afterupload.NewProcessor(options.AfterUpload, app.Client().DryRun)
+	}
+	upCmd.limiter = throttle.NewLimiter(int64(options.MaxUploadRate))
+	if c, ok := app.Client().Immich.(immich.ImmichUploadLimiterInterface); ok && options.MaxUploadRate > 0 {
+		c.SetUploadLimiter(upCmd.limiter)
+	}
 	return upCmd
 }
 
+// waitUploadWindow pauses the upload until the next upload window opens
+func (upCmd *UpCmd) waitUploadWindow(ctx context.Context) error {
+	if upCmd.UploadWindows.IsOpen(time.Now()) {
+		return nil
+	}
+	err := upCmd.UploadWindows.Wait(ctx, func(until time.Time) {
+		upCmd.pausedUntil.Store(until.Unix())
+		upCmd.app.Log().Info("upload paused, outside of the upload windows", "until", until.Format(time.DateTime))
+	})
+	upCmd.pausedUntil.Store(0)
+	if err == nil {
+		upCmd.app.Log().Info("upload resumed")
+	}
+	return err
+}
+
+// throttleStatus describes the current limitation of the upload
+func (upCmd *UpCmd) throttleStatus() string {
+	if until := upCmd.pausedUntil.Load(); until != 0 {
+		return "paused until " + time.Unix(until, 0).Format("15:04")
+	}
+	if r := upCmd.limiter.Rate(); r > 0 {
+		return "max " + cliflags.ByteRate(r).String()
+	}
+	return "unlimited"
+}
+
+// prepareAsset caches the asset's file and computes its checksum ahead of the upload
+func (upCmd *UpCmd) prepareAsset(ctx context.Context, a *assets.Asset) int64 {
+	if upCmd.StreamZip && a.IsStreamable() {
+		// the file is read once during the upload
+		return 0
+	}
+	n, err := a.Prepare()
+	if err != nil {
+		upCmd.app.Log().Warn("can't prepare the asset", "file", a.File, "err", err)
+	}
+	return n
+}
+
 func (upCmd *UpCmd) setTakeoutOptions(options *gp.ImportFlags) *UpCmd {
 	upCmd.takeoutOptions = options
 	return upCmd
@@ -102,38 +193,67 @@ func (upCmd *UpCmd) saveTags(ctx context.Context, tag assets.Tag, ids []string)
 	return tag, err
 }
 
-func (upCmd *UpCmd) run(ctx context.Context, adapter adapters.Reader, app *app.Application, fsys []fs.FS) error {
+// openCaches prepares the album and tag caches. They are flushed by closeCaches.
+// The tags are ignored when the server doesn't support them.
+func (upCmd *UpCmd) openCaches(ctx context.Context) {
+	upCmd.noTags = upCmd.app.Client().Immich.Require(immich.CapabilityTags)
 	upCmd.albumsCache = cache.NewCollectionCache(50, func(album assets.Album, ids []string) (assets.Album, error) {
-		return upCmd.saveAlbum(ctx, album, ids)
+		upCmd.metrics.cacheFlushed("album")
+		album, err := upCmd.saveAlbum(ctx, album, ids)
+		if err == nil {
+			upCmd.runAlbumHooks(ctx, album, ids)
+		}
+		return album, err
 	})
 	upCmd.tagsCache = cache.NewCollectionCache(50, func(tag assets.Tag, ids []string) (assets.Tag, error) {
+		upCmd.metrics.cacheFlushed("tag")
 		return upCmd.saveTags(ctx, tag, ids)
 	})
+}
 
-	upCmd.adapter = adapter
-	defer func() {
-		upCmd.albumsCache.Close()
-	}()
-	defer func() {
-		upCmd.tagsCache.Close()
-	}()
+func (upCmd *UpCmd) closeCaches() {
+	upCmd.tagsCache.Close()
+	upCmd.albumsCache.Close()
+}
 
+func (upCmd *UpCmd) run(ctx context.Context, adapter adapters.Reader, app *app.Application, fsys []fs.FS) error {
+	if err := upCmd.openGeotagger(); err != nil {
+		return errors.Join(err, fshelper.CloseFSs(fsys))
+	}
+	if err := upCmd.openMetrics(); err != nil {
+		return errors.Join(fmt.Errorf("can't serve the metrics: %w", err), fshelper.CloseFSs(fsys))
+	}
+	defer upCmd.closeMetrics()
+	upCmd.openCaches(ctx)
+	defer upCmd.closeCaches()
+	defer upCmd.pauseJobs(ctx)()
+
+	upCmd.adapter = adapter
 	runner := upCmd.runUI
 	upCmd.assetIndex = newAssetIndex()
 
-	if upCmd.NoUI {
-		runner = upCmd.runNoUI
-	}
-	_, err := tcell.NewScreen()
-	if err != nil {
-		upCmd.app.Log().Warn("can't initialize the screen for the UI mode. Falling back to no-gui mode", "err", err)
-		fmt.Println("can't initialize the screen for the UI mode. Falling back to no-gui mode")
+	switch {
+	case upCmd.Progress == ProgressJSON:
+		runner = upCmd.runJSON
+	case upCmd.NoUI:
 		runner = upCmd.runNoUI
+	default:
+		_, err := tcell.NewScreen()
+		if err != nil {
+			upCmd.app.Log().Warn("can't initialize the screen for the UI mode. Falling back to no-gui mode", "err", err)
+			fmt.Println("can't initialize the screen for the UI mode. Falling back to no-gui mode")
+			runner = upCmd.runNoUI
+		}
 	}
-	err = runner(ctx, app)
+	err := runner(ctx, app)
 
 	err = errors.Join(err, fshelper.CloseFSs(fsys))
-	app.Jnl().Report()
+	if upCmd.Progress == ProgressJSON {
+		// the standard output is kept for the JSON events
+		app.Jnl().ReportTo(os.Stderr)
+	} else {
+		app.Jnl().Report()
+	}
 
 	return err
 }
@@ -241,13 +361,18 @@ assetLoop:
 			if !ok {
 				break assetLoop
 			}
+			upCmd.metrics.dequeued()
+			if err := upCmd.waitUploadWindow(ctx); err != nil {
+				return err
+			}
 			err = upCmd.handleGroup(ctx, g)
+			upCmd.prefetcher.Release(g)
 			if err != nil {
 				upCmd.app.Log().Error(err.Error())
 
 				switch {
 				case upCmd.app.Client().OnServerErrors == cliflags.OnServerErrorsNeverStop:
-					// nop
+					upCmd.metrics.errorTolerated()
 				case upCmd.app.Client().OnServerErrors == cliflags.OnServerErrorsStop:
 					return err
 				default:
@@ -257,6 +382,7 @@ assetLoop:
 						upCmd.app.Log().Error(err.Error())
 						return err
 					}
+					upCmd.metrics.errorTolerated()
 				}
 			}
 		}
@@ -273,6 +399,9 @@ assetLoop:
 		}
 	}
 
+	if upCmd.afterUpload.Enabled() {
+		upCmd.processAfterUpload(ctx)
+	}
 	return err
 }
 
@@ -329,40 +458,73 @@ func (upCmd *UpCmd) handleAsset(ctx context.Context, a *assets.Asset) error {
 		a.Close() // Close and clean resources linked to the local asset
 	}()
 
-	// var status stri g
-	advice, err := upCmd.assetIndex.ShouldUpload(a)
-	if err != nil {
-		return err
+	var advice *Advice
+	var err error
+	if upCmd.StreamZip && a.Checksum == "" && a.IsStreamable() {
+		advice = upCmd.assetIndex.ShouldUploadWithoutChecksum(a)
+	} else {
+		advice, err = upCmd.assetIndex.ShouldUpload(a)
+		if err != nil {
+			return err
+		}
 	}
 
 	switch advice.Advice {
 	case NotOnServer: // Upload and manage albums
+		ok, err := upCmd.runHook(ctx, hooks.BeforeUpload, a, advice, "")
+		if !ok || err != nil {
+			return err
+		}
+		geotagged := upCmd.geotag(ctx, a)
 		serverStatus, err := upCmd.uploadAsset(ctx, a)
 		if err != nil {
 			return err
 		}
+		if geotagged && a.FromApplication == nil { // otherwise, the position is sent with the application's metadata
+			upCmd.sendPosition(ctx, a, serverStatus)
+		}
+		upCmd.addAfterUpload(a, "")
+		_, err = upCmd.runHook(ctx, hooks.AfterUpload, a, advice, serverStatus)
+		if err != nil {
+			return err
+		}
 
 		if serverStatus != immich.StatusDuplicate {
 			// TODO: current version of Immich doesn't allow to add same tag to an asset already tagged.
 			//       there is no mean to go the list of tagged assets for a given tag.
+			upCmd.waitAlbumHook(a, advice, serverStatus)
 			upCmd.manageAssetAlbums(ctx, a.File, a.ID, a.Albums)
 			upCmd.manageAssetTags(ctx, a)
 		}
-		return nil
+		return err
 	case SmallerOnServer: // Upload, manage albums and delete the server's asset
+		ok, err := upCmd.runHook(ctx, hooks.BeforeUpload, a, advice, "")
+		if !ok || err != nil {
+			return err
+		}
 
 		// Remember existing asset's albums, if any
 		a.Albums = append(a.Albums, advice.ServerAsset.Albums...)
 
 		// Upload the superior asset
+		geotagged := upCmd.geotag(ctx, a)
 		serverStatus, err := upCmd.replaceAsset(ctx, advice.ServerAsset.ID, a, advice.ServerAsset)
 		if err != nil {
 			return err
 		}
+		if geotagged {
+			upCmd.sendPosition(ctx, a, serverStatus)
+		}
+		upCmd.addAfterUpload(a, "")
+		_, err = upCmd.runHook(ctx, hooks.AfterUpload, a, advice, serverStatus)
+		if err != nil {
+			return err
+		}
 
 		if serverStatus != immich.StatusDuplicate {
 			// TODO: current version of Immich doesn't allow to add same tag to an asset already tagged.
 			//       there is no mean to go the list of tagged assets for a given tag.
+			upCmd.waitAlbumHook(a, advice, serverStatus)
 			upCmd.manageAssetAlbums(ctx, a.File, a.ID, a.Albums)
 			upCmd.manageAssetTags(ctx, a)
 		}
@@ -377,6 +539,7 @@ func (upCmd *UpCmd) handleAsset(ctx context.Context, a *assets.Asset) error {
 		a.Albums = append(a.Albums, advice.ServerAsset.Albums...)
 		upCmd.app.Jnl().Record(ctx, fileevent.UploadServerDuplicate, a.File, "reason", advice.Message)
 		upCmd.manageAssetAlbums(ctx, a.File, a.ID, a.Albums)
+		upCmd.addAfterUpload(a, advice.ServerAsset.Checksum)
 
 	case BetterOnServer: // and manage albums
 		a.ID = advice.ServerAsset.ID
@@ -408,9 +571,13 @@ func (upCmd *UpCmd) uploadAsset(ctx context.Context, a *assets.Asset) (string, e
 			upCmd.app.Jnl().Record(ctx, fileevent.UploadServerDuplicate, a.File, "reason", "the server already has this file", "original name", originalName)
 		}
 	} else {
-		upCmd.app.Jnl().Record(ctx, fileevent.Uploaded, a.File)
+		upCmd.app.Jnl().Record(ctx, fileevent.Uploaded, a.File, "size", a.FileSize)
 	}
 	a.ID = ar.ID
+	if err := upCmd.completeChecksum(a, ar.ID); err != nil {
+		upCmd.app.Jnl().Record(ctx, fileevent.Error, a.File, "error", err.Error())
+		return "", err
+	}
 
 	// // DEBGUG
 	//  if theID, ok := upCmd.assetIndex.byI
@@ -455,12 +622,31 @@ func (upCmd *UpCmd) replaceAsset(ctx context.Context, ID string, a, old *assets.
 		}
 	} else {
 		a.ID = ID
+		if err := upCmd.completeChecksum(a, ""); err != nil {
+			upCmd.app.Jnl().Record(ctx, fileevent.Error, a.File, "error", err.Error())
+			return "", err
+		}
 		upCmd.app.Jnl().Record(ctx, fileevent.UploadUpgraded, a.File)
 		upCmd.assetIndex.replaceAsset(a, old)
 	}
 	return ar.Status, nil
 }
 
+// completeChecksum sets the checksum of a streamed asset when the upload hasn't read the whole file.
+// This happens when the server detects a duplicate before the end of the upload, or in dry-run mode.
+// The checksum of the server's asset is used when known, otherwise the file is read again.
+func (upCmd *UpCmd) completeChecksum(a *assets.Asset, serverID string) error {
+	if a.Checksum != "" {
+		return nil
+	}
+	if sa := upCmd.assetIndex.getByID(serverID); sa != nil && sa.Checksum != "" {
+		a.Checksum = sa.Checksum
+		return nil
+	}
+	_, err := a.GetChecksum()
+	return err
+}
+
 // manageAssetAlbums add the assets to the albums listed.
 // If an album does not exist, it is created.
 // If the album already has the asset, it is not added.
@@ -482,6 +668,12 @@ func (upCmd *UpCmd) manageAssetTags(ctx context.Context, a *assets.Asset) {
 	if len(a.Tags) == 0 {
 		return
 	}
+	if upCmd.noTags != nil {
+		upCmd.noTagsOnce.Do(func() {
+			upCmd.app.Log().Warn("The tags are ignored", "err", upCmd.noTags)
+		})
+		return
+	}
 
 	tags := make([]string, len(a.Tags))
 	for i := range a.Tags {
@@ -499,21 +691,63 @@ func (upCmd *UpCmd) DeleteServerAssets(ctx context.Context, ids []string) error
 	return upCmd.app.Client().Immich.DeleteAssets(ctx, ids, false)
 }
 
-/*
-func (app *UpCmd) DeleteLocalAssets() error {
-	app.RootImmichFlags.Message(fmt.Sprintf("%d local assets to delete.", len(app.deleteLocalList)))
+// addAfterUpload remembers the asset's files to move or delete them at the end of the upload
+func (upCmd *UpCmd) addAfterUpload(a *assets.Asset, serverChecksum string) {
+	if !upCmd.afterUpload.Enabled() || a.ID == "" {
+		return
+	}
+	item := afterUploadItem{
+		file:           a.File,
+		checksum:       a.Checksum,
+		id:             a.ID,
+		serverChecksum: serverChecksum,
+	}
+	for _, md := range []*assets.Metadata{a.FromSideCar, a.FromApplication} {
+		if md != nil && md.File.FS() != nil && md.File.Name() != "" {
+			item.sidecars = append(item.sidecars, md.File)
+		}
+	}
+	upCmd.afterUploadList = append(upCmd.afterUploadList, item)
+}
 
-	for _, a := range app.deleteLocalList {
-		if !app.DryRun {
-			app.Log.Info(fmt.Sprintf("delete file %q", a.Title))
-			err := a.Remove()
-			if err != nil {
-				return err
+// processAfterUpload moves or deletes the source files when their checksum matches the server's asset checksum.
+func (upCmd *UpCmd) processAfterUpload(ctx context.Context) {
+	defer func() {
+		if err := upCmd.afterUpload.Close(); err != nil {
+			upCmd.app.Log().Error("can't close the after-upload journal", "err", err)
+		}
+	}()
+	dryRun := upCmd.app.Client().DryRun
+	for _, item := range upCmd.afterUploadList {
+		if ctx.Err() != nil {
+			return
+		}
+		if !dryRun {
+			serverChecksum := item.serverChecksum
+			if serverChecksum == "" {
+				sa, err := upCmd.app.Client().Immich.GetAssetInfo(ctx, item.id)
+				if err != nil {
+					upCmd.app.Jnl().Record(ctx, fileevent.Error, item.file, "error", "can't get the server's asset: "+err.Error())
+					continue
+				}
+				serverChecksum = sa.Checksum
 			}
-		} else {
-			app.Log.Info(fmt.Sprintf("file %q not deleted, dry run mode.", a.Title))
+			if item.checksum == "" || item.checksum != serverChecksum {
+				upCmd.app.Jnl().Record(ctx, fileevent.INFO, item.file, "warning", "the file is kept: its checksum doesn't match the server's asset")
+				continue
+			}
+		}
+		action, err := upCmd.afterUpload.Apply(item.id, append([]fshelper.FSAndName{item.file}, item.sidecars...)...)
+		if err != nil {
+			upCmd.app.Jnl().Record(ctx, fileevent.Error, item.file, "error", err.Error())
+			continue
+		}
+		switch action {
+		case afterupload.Move:
+			upCmd.app.Jnl().Record(ctx, fileevent.SourceMoved, item.file)
+		case afterupload.Delete:
+			upCmd.app.Jnl().Record(ctx, fileevent.SourceDeleted, item.file)
 		}
 	}
-	return nil
+	upCmd.app.Log().Info("after-upload journal", "file", upCmd.afterUpload.JournalName())
 }
-*/
@@ -0,0 +1,284 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simulot/immich-go/adapters/folder"
+	gp "github.com/simulot/immich-go/adapters/googlePhotos"
+	"github.com/simulot/immich-go/app"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+	"github.com/simulot/immich-go/internal/fakefs"
+	"github.com/simulot/immich-go/internal/fileevent"
+	"github.com/simulot/immich-go/internal/filenames"
+	"github.com/simulot/immich-go/internal/filetypes"
+	"github.com/spf13/cobra"
+)
+
+// SimulateOptions are the flags of the simulate command
+type SimulateOptions struct {
+	Listings      []string // Listings of the files to upload, as made by unzip -l or the debug-bundle command
+	ServerListing string   // Listing of the files already on the server
+	DateFormat    string   // Date format of the listings
+	Decisions     string   // File receiving the decisions, the standard output when empty
+
+	server    *fakeimmich.Server
+	decisions *decisionRecorder
+}
+
+// NewSimulateCommand adds the simulate command. It runs the upload on file systems made from file listings,
+// against an in-process server, to predict how a takeout will be handled without downloading it.
+func NewSimulateCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	upOptions := &UploadOptions{NoUI: true}
+	options := &SimulateOptions{}
+	cmd := &cobra.Command{
+		Use:   folder.SimulateCmdName,
+		Short: "Simulate an upload from the listing of the files, without the files and without the server",
+		Long: `Simulate an upload from the listing of the files of a takeout or a folder, as given by "unzip -l" or by the debug-bundle command.
+The analysis of the files, the grouping, the filters, and the comparison with the server's assets run as during an upload,
+against an in-process server having the assets of the --server-listing, or none. Nothing is uploaded.
+The command prints the decision taken for each file, and the counters of the run.`,
+	}
+	cmd.TraverseChildren = true
+	cmd.PersistentFlags().StringArrayVar(&options.Listings, "listing", nil, "Listing of the files to upload. Can be specified multiple times")
+	cmd.PersistentFlags().StringVar(&options.ServerListing, "server-listing", "", "Listing of the files already on the server (default: an empty server)")
+	cmd.PersistentFlags().StringVar(&options.DateFormat, "date-format", "2006-01-02 15:04", "Date format of the listings, in the Go layout")
+	cmd.PersistentFlags().StringVar(&options.Decisions, "decisions", "", "Write the decision taken for each file in this file (default: the standard output)")
+	cmd.PersistentFlags().StringVar(&a.Client().TimeZone, "time-zone", "", "Override the system time zone")
+	cmd.PersistentPreRunE = app.ChainRunEFunctions(cmd.PersistentPreRunE, upOptions.Open, ctx, cmd, a)
+	cmd.PersistentPreRunE = app.ChainRunEFunctions(cmd.PersistentPreRunE, options.Open, ctx, cmd, a)
+	cmd.PersistentPostRunE = app.ChainRunEFunctions(cmd.PersistentPostRunE, options.Close, ctx, cmd, a)
+
+	cmd.AddCommand(newSimulateFromGooglePhotosCommand(ctx, cmd, a, upOptions, options))
+	cmd.AddCommand(newSimulateFromFolderCommand(ctx, cmd, a, upOptions, options))
+	return cmd
+}
+
+func newSimulateFromGooglePhotosCommand(ctx context.Context, parent *cobra.Command, app *app.Application, upOptions *UploadOptions, simOptions *SimulateOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "from-google-photos [flags]",
+		Short: "Simulate the upload of a Google Photos takeout from its listing",
+		Args:  cobra.NoArgs,
+	}
+	cmd.SetContext(ctx)
+	options := &gp.ImportFlags{}
+	options.AddFromGooglePhotosFlags(cmd, parent)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		// the copies of a photo in the albums and the year folders of a takeout are the same file
+		fsyss, err := simOptions.prepare(app, true)
+		if err != nil {
+			return err
+		}
+		err = runFromGooglePhotos(cmd.Context(), app, options, upOptions, fsyss)
+		return errors.Join(err, simOptions.writeDecisions(cmd.OutOrStdout()))
+	}
+	return cmd
+}
+
+func newSimulateFromFolderCommand(ctx context.Context, parent *cobra.Command, app *app.Application, upOptions *UploadOptions, simOptions *SimulateOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "from-folder [flags]",
+		Short: "Simulate the upload of a folder from its listing",
+		Args:  cobra.NoArgs,
+	}
+	cmd.SetContext(ctx)
+	options := &folder.ImportFolderOptions{}
+	options.AddFromFolderFlags(cmd, parent)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		fsyss, err := simOptions.prepare(app, false)
+		if err != nil {
+			return err
+		}
+		err = runFromFolder(cmd.Context(), app, options, upOptions, fsyss)
+		return errors.Join(err, simOptions.writeDecisions(cmd.OutOrStdout()))
+	}
+	return cmd
+}
+
+// Open starts the in-process server, and connects the client to it in dry-run mode
+func (options *SimulateOptions) Open(ctx context.Context, cmd *cobra.Command, a *app.Application) error {
+	if len(options.Listings) == 0 {
+		return errors.New("missing the parameter --listing, listing of the files to upload")
+	}
+	options.server = fakeimmich.NewServer()
+
+	client := a.Client()
+	client.Server = options.server.URL
+	client.APIKey = options.server.APIKey
+	client.DryRun = true
+	err := app.OpenClient(ctx, cmd, a)
+	if err != nil {
+		return err
+	}
+
+	options.decisions = newDecisionRecorder()
+	a.Jnl().AddListener(options.decisions.record)
+	return nil
+}
+
+// Close stops the in-process server
+func (options *SimulateOptions) Close(ctx context.Context, cmd *cobra.Command, a *app.Application) error {
+	err := app.CloseClient(ctx, cmd, a)
+	if options.server != nil {
+		options.server.Close()
+	}
+	return err
+}
+
+// prepare puts the assets of the server listing on the in-process server, and gives the file systems described by the listings.
+// The files having the same path and size are the same file, or the same base name and size when byName is set.
+func (options *SimulateOptions) prepare(a *app.Application, byName bool) ([]fs.FS, error) {
+	if options.ServerListing != "" {
+		n, err := options.seedServer(a.GetTZ(), byName)
+		if err != nil {
+			return nil, err
+		}
+		a.Log().Info("server listing loaded", "file", options.ServerListing, "assets", n)
+	}
+	fsyss := []fs.FS{}
+	for _, l := range options.Listings {
+		list, err := fakefs.ScanFileList(l, options.DateFormat)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the listing %s: %w", l, err)
+		}
+		fsyss = append(fsyss, list...)
+	}
+	if len(fsyss) == 0 {
+		return nil, errors.New("no file found in the listings: " + strings.Join(options.Listings, ","))
+	}
+	if byName {
+		fakefs.SameContentByName(fsyss)
+	}
+	return fsyss, nil
+}
+
+// seedServer puts the photos and videos of the server listing on the in-process server.
+// They have the same content as the files of the listings having the same path and size, or the same name and size when byName is set.
+func (options *SimulateOptions) seedServer(tz *time.Location, byName bool) (int, error) {
+	fsyss, err := fakefs.ScanFileList(options.ServerListing, options.DateFormat)
+	if err != nil {
+		return 0, fmt.Errorf("can't read the listing %s: %w", options.ServerListing, err)
+	}
+	if byName {
+		fakefs.SameContentByName(fsyss)
+	}
+	n := 0
+	seen := map[string]bool{} // the server has one asset per checksum
+	for _, fsys := range fsyss {
+		err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !filetypes.DefaultSupportedMedia.IsMedia(path.Ext(name)) {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			f, err := fsys.Open(name)
+			if err != nil {
+				return err
+			}
+			checksum, err := fakeimmich.ChecksumOf(f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			if seen[checksum] {
+				return nil
+			}
+			seen[checksum] = true
+			captureDate := filenames.TakeTimeFromName(name, tz)
+			if captureDate.IsZero() {
+				captureDate = info.ModTime()
+			}
+			options.server.AddAssetWithChecksum(path.Base(name), checksum, info.Size(), captureDate)
+			n++
+			return nil
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// writeDecisions writes the decisions in the decision file, or in w
+func (options *SimulateOptions) writeDecisions(w io.Writer) error {
+	if options.decisions == nil {
+		return nil
+	}
+	if options.Decisions == "" {
+		fmt.Fprintln(w, "Decisions:")
+		fmt.Fprintln(w, "----------")
+		return options.decisions.write(w)
+	}
+	f, err := os.Create(options.Decisions)
+	if err != nil {
+		return err
+	}
+	return errors.Join(options.decisions.write(f), f.Close())
+}
+
+// decisionRecorder collects the events of each file
+type decisionRecorder struct {
+	lock   sync.Mutex
+	events map[string][]string
+}
+
+func newDecisionRecorder() *decisionRecorder {
+	return &decisionRecorder{events: map[string][]string{}}
+}
+
+// record is a journal listener. The discovery of the files isn't a decision and isn't kept.
+func (dr *decisionRecorder) record(_ context.Context, code fileevent.Code, file slog.LogValuer, args ...any) {
+	switch code {
+	case fileevent.DiscoveredImage, fileevent.DiscoveredVideo, fileevent.DiscoveredSidecar:
+		return
+	}
+	name := ""
+	if file != nil {
+		name = file.LogValue().String()
+	}
+	sb := strings.Builder{}
+	sb.WriteString(code.String())
+	for i := 0; i+1 < len(args); i += 2 {
+		v := args[i+1]
+		if lv, ok := v.(slog.LogValuer); ok {
+			v = lv.LogValue().String()
+		}
+		fmt.Fprintf(&sb, ", %v: %v", args[i], v)
+	}
+	dr.lock.Lock()
+	dr.events[name] = append(dr.events[name], sb.String())
+	dr.lock.Unlock()
+}
+
+// write writes a line per event, sorted by file name
+func (dr *decisionRecorder) write(w io.Writer) error {
+	dr.lock.Lock()
+	defer dr.lock.Unlock()
+	names := make([]string, 0, len(dr.events))
+	for name := range dr.events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, e := range dr.events[name] {
+			if _, err := fmt.Fprintf(w, "%s\t%s\n", name, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/fileevent"
+)
+
+const simulatedTakeout = `Part: takeout-001.zip
+      350  2023-07-20 10:00   Takeout/Google Photos/Paris/metadata.json
+  2104348  2023-07-20 10:00   Takeout/Google Photos/Paris/IMG_20230720_100000.jpg
+      800  2023-07-20 10:00   Takeout/Google Photos/Paris/IMG_20230720_100000.jpg.json
+Part: takeout-002.zip
+  3104348  2023-07-21 10:00   Takeout/Google Photos/Photos from 2023/IMG_20230721_100000.jpg
+      800  2023-07-21 10:00   Takeout/Google Photos/Photos from 2023/IMG_20230721_100000.jpg.json
+  2104348  2023-07-20 10:00   Takeout/Google Photos/Photos from 2023/IMG_20230720_100000.jpg
+      800  2023-07-20 10:00   Takeout/Google Photos/Photos from 2023/IMG_20230720_100000.jpg.json
+`
+
+const simulatedServer = `Part: server
+  3104348  2023-07-21 10:00   IMG_20230721_100000.jpg
+`
+
+func TestSimulate(t *testing.T) {
+	dir := t.TempDir()
+	listing := filepath.Join(dir, "takeout.lst")
+	server := filepath.Join(dir, "server.lst")
+	decisions := filepath.Join(dir, "decisions.tsv")
+	for name, content := range map[string]string{listing: simulatedTakeout, server: simulatedServer} {
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	a := app.NewWithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	cmd := NewSimulateCommand(ctx, a)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"from-google-photos", "--listing", listing, "--server-listing", server, "--decisions", decisions})
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := a.Jnl().GetCounts()
+	for code, want := range map[fileevent.Code]int64{
+		fileevent.Uploaded:               1,
+		fileevent.UploadServerDuplicate:  1,
+		fileevent.AnalysisLocalDuplicate: 1,
+		fileevent.UploadAddToAlbum:       1,
+	} {
+		if counts[code] != want {
+			t.Errorf("%s: %d, want %d", code, counts[code], want)
+		}
+	}
+
+	b, err := os.ReadFile(decisions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"takeout-001.zip:Takeout/Google Photos/Paris/IMG_20230720_100000.jpg\tuploaded",
+		"takeout-002.zip:Takeout/Google Photos/Photos from 2023/IMG_20230720_100000.jpg\tfile duplicated in the input",
+		"takeout-002.zip:Takeout/Google Photos/Photos from 2023/IMG_20230721_100000.jpg\tserver has same asset",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("the decisions lack %q:\n%s", want, b)
+		}
+	}
+}
+
+const simulatedFolder = `Archive: photos
+  2104348  2023-07-20 10:00   photos/2023/IMG_0001.jpg
+  2104348  2023-07-20 10:00   photos/2024/IMG_0001.jpg
+`
+
+func TestSimulateFolder(t *testing.T) {
+	dir := t.TempDir()
+	listing := filepath.Join(dir, "folder.lst")
+	if err := os.WriteFile(listing, []byte(simulatedFolder), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	a := app.NewWithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	cmd := NewSimulateCommand(ctx, a)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"from-folder", "--listing", listing})
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// files of different folders are different files, even with the same name and size
+	counts := a.Jnl().GetCounts()
+	if counts[fileevent.Uploaded] != 2 || counts[fileevent.AnalysisLocalDuplicate] != 0 {
+		t.Errorf("uploaded: %d, local duplicates: %d, want 2 and 0", counts[fileevent.Uploaded], counts[fileevent.AnalysisLocalDuplicate])
+	}
+}
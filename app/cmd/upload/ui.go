@@ -37,6 +37,9 @@ type uiPage struct {
 	immichPrepare *tvxwidgets.PercentageModeGauge
 	immichUpload  *tvxwidgets.PercentageModeGauge
 
+	// current limitation of the upload
+	throttle *tview.TextView
+
 	watchJobs bool
 }
 
@@ -145,6 +148,7 @@ func (upCmd *UpCmd) runUI(ctx context.Context, app *app.Application) error {
 						}
 						ui.immichUpload.SetValue(int(app.Jnl().TotalProcessed(upCmd.takeoutOptions.KeepJSONLess)))
 					}
+					ui.throttle.SetText("Upload: " + upCmd.throttleStatus())
 				})
 			}
 		}
@@ -184,7 +188,7 @@ func (upCmd *UpCmd) runUI(ctx context.Context, app *app.Application) error {
 		})
 		processGrp.Go(func() error {
 			// Run Prepare
-			groupChan = upCmd.adapter.Browse(ctx)
+			groupChan = upCmd.prefetcher.Run(ctx, upCmd.browse(ctx))
 			return nil
 		})
 
@@ -327,15 +331,19 @@ func (upCmd *UpCmd) newUI(ctx context.Context, a *app.Application) *uiPage {
 	ui.immichUpload.SetMaxValue(0)
 	ui.immichUpload.SetValue(0)
 
+	ui.throttle = tview.NewTextView().SetText("Upload: " + upCmd.throttleStatus()).SetTextAlign(tview.AlignCenter)
+
 	ui.footer = tview.NewGrid()
 	ui.footer.AddItem(tview.NewTextView().SetText("Immich content:").SetTextAlign(tview.AlignCenter), 0, 0, 1, 1, 0, 0, false).AddItem(ui.immichReading, 0, 1, 1, 1, 0, 0, false)
 
 	if upCmd.Mode == UpModeGoogleTakeout {
 		ui.footer.AddItem(tview.NewTextView().SetText("Google Photo puzzle:").SetTextAlign(tview.AlignCenter), 0, 2, 1, 1, 0, 0, false).AddItem(ui.immichPrepare, 0, 3, 1, 1, 0, 0, false)
 		ui.footer.AddItem(tview.NewTextView().SetText("Uploading:").SetTextAlign(tview.AlignCenter), 0, 4, 1, 1, 0, 0, false).AddItem(ui.immichUpload, 0, 5, 1, 1, 0, 0, false)
-		ui.footer.SetColumns(25, 0, 25, 0, 25, 0)
+		ui.footer.AddItem(ui.throttle, 0, 6, 1, 1, 0, 0, false)
+		ui.footer.SetColumns(25, 0, 25, 0, 25, 0, 30)
 	} else {
-		ui.footer.SetColumns(25, 0)
+		ui.footer.AddItem(ui.throttle, 0, 2, 1, 1, 0, 0, false)
+		ui.footer.SetColumns(25, 0, 30)
 	}
 	ui.screen.AddItem(ui.footer, 3, 0, 1, 1, 0, 0, false)
 
@@ -0,0 +1,27 @@
+package upload
+
+import (
+	"context"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/afterupload"
+	"github.com/spf13/cobra"
+)
+
+// NewUndoAfterUploadCommand moves back the files moved by the option --after-upload=move:<dir>
+func NewUndoAfterUploadCommand(ctx context.Context, a *app.Application) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "undo-after-upload [flags] <journal>",
+		Short: "Move back the source files listed in an after-upload journal",
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Simulate all actions")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error { //nolint:contextcheck
+		n, err := afterupload.Undo(args[0], dryRun)
+		a.Log().Message("%d files moved back", n)
+		return err
+	}
+	return cmd
+}
@@ -2,11 +2,17 @@ package upload
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/internal/afterupload"
+	cliflags "github.com/simulot/immich-go/internal/cliFlags"
 	"github.com/simulot/immich-go/internal/fileevent"
 	"github.com/simulot/immich-go/internal/filters"
+	"github.com/simulot/immich-go/internal/geotrack"
+	"github.com/simulot/immich-go/internal/hooks"
+	"github.com/simulot/immich-go/internal/throttle"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +23,7 @@ const (
 	UpModeFolder
 	UpModeICloud
 	UpModePicasa
+	UpModeImmich
 )
 
 func (m UpLoadMode) String() string {
@@ -29,17 +36,70 @@ func (m UpLoadMode) String() string {
 		return "iCloud"
 	case UpModePicasa:
 		return "Picasa"
+	case UpModeImmich:
+		return "Immich"
 	default:
 		return "Unknown"
 	}
 }
 
+// Name gives the identifier of the mode used by the machine outputs
+func (m UpLoadMode) Name() string {
+	switch m {
+	case UpModeGoogleTakeout:
+		return "google_photos"
+	case UpModeFolder:
+		return "folder"
+	case UpModeICloud:
+		return "icloud"
+	case UpModePicasa:
+		return "picasa"
+	case UpModeImmich:
+		return "immich"
+	default:
+		return "unknown"
+	}
+}
+
 // UploadOptions represents a set of common flags used for filtering assets.
 type UploadOptions struct {
 	// TODO place this option at the top
 	NoUI bool // Disable UI
 
+	// Progress tells how the progression is reported: ui, text, or json
+	Progress string
+
 	Filters []filters.Filter
+
+	// Hooks are the user's executables called during the upload
+	Hooks hooks.Options
+
+	// Prefetch parameters: number of workers preparing the assets ahead of the upload,
+	// number of groups read in advance, and maximum size of the temporary files
+	PrefetchWorkers  int
+	PrefetchWindow   int
+	PrefetchMaxCache cliflags.ByteSize
+
+	// StreamZip uploads the zipped files while computing their checksum, without temporary copies
+	StreamZip bool
+
+	// MaxUploadRate limits the upload bandwidth, 0 for no limit
+	MaxUploadRate cliflags.ByteRate
+
+	// UploadWindows are the daily periods during which the uploads are allowed
+	UploadWindows throttle.Schedule
+
+	// AfterUpload tells what to do with the source files once uploaded
+	AfterUpload afterupload.Options
+
+	// Geotag gives the tracks used to set the position of the assets without GPS data
+	Geotag geotrack.Options
+
+	// PauseJobs pauses the server's heavy job queues during the upload
+	PauseJobs bool
+
+	// MetricsAddr is the address serving the Prometheus metrics, none when empty
+	MetricsAddr string
 }
 
 // NewUploadCommand adds the Upload command
@@ -52,6 +112,18 @@ func NewUploadCommand(ctx context.Context, a *app.Application) *cobra.Command {
 	app.AddClientFlags(ctx, cmd, a, false)
 	cmd.TraverseChildren = true
 	cmd.PersistentFlags().BoolVar(&options.NoUI, "no-ui", false, "Disable the user interface")
+	cmd.PersistentFlags().StringVar(&options.Progress, "progress", ProgressUI, "How the progression is reported: ui (interactive), text (a progress line, like --no-ui), json (newline-delimited JSON events on the standard output)")
+	hooks.AddHookFlags(cmd, &options.Hooks)
+	options.PrefetchMaxCache = 1 << 30
+	cmd.PersistentFlags().IntVar(&options.PrefetchWorkers, "prefetch-workers", 0, "Number of workers computing checksums and caching files ahead of the upload (0 to disable)")
+	cmd.PersistentFlags().IntVar(&options.PrefetchWindow, "prefetch-window", 20, "Maximum number of groups of assets prepared ahead of the upload")
+	cmd.PersistentFlags().Var(&options.PrefetchMaxCache, "prefetch-max-cache", "Maximum size of the temporary files created ahead of the upload for zipped sources (ex: 500MB, 2GB)")
+	cmd.PersistentFlags().BoolVar(&options.StreamZip, "stream-zip", false, "Upload the zipped files in a single pass, computing their checksum during the upload instead of copying them into temporary files")
+	cmd.PersistentFlags().Var(&options.MaxUploadRate, "max-upload-rate", "Maximum upload bandwidth (ex: 500KB/s, 5MB/s), 0 for no limit")
+	cmd.PersistentFlags().Var(&options.UploadWindows, "upload-window", "Daily time window during which the uploads are allowed (ex: 01:00-06:00). Can be specified multiple times")
+	geotrack.AddFlags(cmd.PersistentFlags(), &options.Geotag)
+	cmd.PersistentFlags().BoolVar(&options.PauseJobs, "pause-jobs", false, "Pause the server's heavy jobs (thumbnails, video conversion, machine learning) during the upload, and resume them at the end")
+	cmd.PersistentFlags().StringVar(&options.MetricsAddr, "metrics-addr", "", "Serve the Prometheus metrics of the upload on this address (ex: :9090), at the path /metrics")
 	cmd.PersistentPreRunE = app.ChainRunEFunctions(cmd.PersistentPreRunE, options.Open, ctx, cmd, a)
 
 	cmd.AddCommand(NewFromFolderCommand(ctx, cmd, a, options))
@@ -67,6 +139,13 @@ func (options *UploadOptions) Open(ctx context.Context, cmd *cobra.Command, app
 	if app.Jnl() == nil {
 		app.SetJnl(fileevent.NewRecorder(app.Log().Logger))
 	}
+	switch options.Progress {
+	case "", ProgressUI:
+	case ProgressText, ProgressJSON:
+		options.NoUI = true
+	default:
+		return fmt.Errorf("invalid value for --progress: %q, expected ui, text, or json", options.Progress)
+	}
 	app.SetTZ(time.Local)
 	if tz, err := cmd.Flags().GetString("time-zone"); err == nil && tz != "" {
 		if loc, err := time.LoadLocation(tz); err == nil {
@@ -0,0 +1,31 @@
+package app
+
+import "errors"
+
+// ExitError is an error ending immich-go with a given exit code.
+// Commands used in scripts return it to tell apart their results.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the process for the error: 0 without error,
+// the code of an ExitError, 1 otherwise
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var e *ExitError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 1
+}
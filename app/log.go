@@ -31,6 +31,7 @@ type Log struct {
 
 	mainWriter    io.Writer // the log writer to file
 	consoleWriter io.Writer
+	messageWriter io.Writer // where the messages are printed, stdout when nil
 }
 
 func AddLogFlags(ctx context.Context, cmd *cobra.Command, app *Application) {
@@ -78,7 +79,11 @@ func (log *Log) Open(ctx context.Context, cmd *cobra.Command, app *Application)
 		// No log for version command
 		return nil
 	}
-	fmt.Println(Banner())
+	if machineOutput(cmd) {
+		// keep stdout for the command's output
+		log.messageWriter = os.Stderr
+	}
+	fmt.Fprintln(log.messages(), Banner())
 	err := log.OpenLogFile()
 	if err != nil {
 		return err
@@ -104,6 +109,9 @@ func (log *Log) Open(ctx context.Context, cmd *cobra.Command, app *Application)
 		if strings.Contains(flag.Name, "api-key") && len(val) > 4 {
 			val = strings.Repeat("*", len(val)-4) + val[len(val)-4:]
 		}
+		if strings.HasSuffix(flag.Name, "header") {
+			val = redactHeaders(flag.Value)
+		}
 		log.Info("", "--"+flag.Name, val)
 	})
 
@@ -166,9 +174,39 @@ func (log *Log) SetLogWriter(w io.Writer) *slog.Logger {
 	return log.Logger
 }
 
+// machineOutput returns true when the command writes a JSON or CSV output on stdout
+func machineOutput(cmd *cobra.Command) bool {
+	if f := cmd.Flags().Lookup("json"); f != nil && f.Value.String() == "true" {
+		return true
+	}
+	if f := cmd.Flags().Lookup("progress"); f != nil && f.Value.String() == "json" {
+		return true
+	}
+	if f := cmd.Flags().Lookup("format"); f != nil {
+		switch strings.ToLower(f.Value.String()) {
+		case "json", "csv":
+			o := cmd.Flags().Lookup("output")
+			return o == nil || o.Value.String() == ""
+		}
+	}
+	return false
+}
+
+func (log *Log) messages() io.Writer {
+	if log.messageWriter != nil {
+		return log.messageWriter
+	}
+	return os.Stdout
+}
+
+// SetMessageWriter prints the messages on w instead of the standard output
+func (log *Log) SetMessageWriter(w io.Writer) {
+	log.messageWriter = w
+}
+
 func (log *Log) Message(msg string, values ...any) {
 	s := fmt.Sprintf(msg, values...)
-	fmt.Println(s)
+	fmt.Fprintln(log.messages(), s)
 	if log.Logger != nil {
 		log.Info(s)
 	}
@@ -192,3 +230,18 @@ func (log *Log) Close(ctx context.Context, cmd *cobra.Command, app *Application)
 func (log *Log) GetSLog() *slog.Logger {
 	return log.Logger
 }
+
+// redactHeaders keeps only the names of the headers, their values may be secrets
+func redactHeaders(v pflag.Value) string {
+	sv, ok := v.(pflag.SliceValue)
+	if !ok {
+		return ""
+	}
+	names := []string{}
+	for _, h := range sv.GetSlice() {
+		name, _, _ := strings.Cut(h, ":")
+		name, _, _ = strings.Cut(name, "=")
+		names = append(names, strings.TrimSpace(name)+": redacted")
+	}
+	return "[" + strings.Join(names, ",") + "]"
+}
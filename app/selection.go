@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/simulot/immich-go/immich"
+	cliflags "github.com/simulot/immich-go/internal/cliFlags"
+	"github.com/spf13/cobra"
+)
+
+// AssetSelection gives the criteria used to select the server's assets
+type AssetSelection struct {
+	DateRange cliflags.DateRange // Set capture date range
+	Make      string             // Camera make
+	Model     string             // Camera model
+	Albums    []string           // Album names or IDs
+	Name      string             // Glob pattern on the original file name
+}
+
+const searchTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// AddFlags adds the selection flags to the command
+func (s *AssetSelection) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().Var(&s.DateRange, "date-range", "Select the photos taken in the date range")
+	cmd.Flags().StringVar(&s.Make, "make", "", "Select the photos taken with a camera of this make")
+	cmd.Flags().StringVar(&s.Model, "model", "", "Select the photos taken with a camera of this model")
+	cmd.Flags().StringSliceVar(&s.Albums, "album", nil, "Select the photos of the album (name or ID)")
+	cmd.Flags().StringVar(&s.Name, "name", "", "Select the photos whose file name matches the pattern (ex: IMG_*.JPG)")
+}
+
+// IsSet returns true when at least one criterion is given
+func (s *AssetSelection) IsSet() bool {
+	return s.DateRange.IsSet() || s.Make != "" || s.Model != "" || len(s.Albums) > 0 || s.Name != ""
+}
+
+// Query builds the search query for the criteria
+func (s *AssetSelection) Query(ctx context.Context, client immich.ImmichInterface) (*immich.SearchMetadataQuery, error) {
+	if s.Name != "" {
+		if _, err := path.Match(s.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid file name pattern: %q: %w", s.Name, err)
+		}
+	}
+	query := &immich.SearchMetadataQuery{
+		WithExif:     true,
+		WithArchived: true,
+		Make:         s.Make,
+		Model:        s.Model,
+	}
+	if s.DateRange.IsSet() {
+		query.TakenAfter = s.DateRange.After.Format(searchTimeFormat)
+		query.TakenBefore = s.DateRange.Before.Format(searchTimeFormat)
+	}
+	if len(s.Albums) > 0 {
+		albums, err := client.GetAllAlbums(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("can't get the albums: %w", err)
+		}
+		for _, name := range s.Albums {
+			found := false
+			for _, al := range albums {
+				if al.ID == name || al.AlbumName == name {
+					query.AlbumIDs = append(query.AlbumIDs, al.ID)
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("album %q not found", name)
+			}
+		}
+	}
+	return query, nil
+}
+
+// Assets returns the selected assets, restricted to the assets having the given tags
+func (s *AssetSelection) Assets(ctx context.Context, client immich.ImmichInterface, tagIDs ...string) ([]*immich.Asset, error) {
+	query, err := s.Query(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	query.TagIDs = tagIDs
+	pattern := strings.ToLower(s.Name)
+	list := []*immich.Asset{}
+	err = client.GetAllAssetsWithFilter(ctx, query, func(as *immich.Asset) error {
+		if as.IsTrashed {
+			return nil
+		}
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, strings.ToLower(as.OriginalFileName)); !ok {
+				return nil
+			}
+		}
+		list = append(list, as)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't get the assets: %w", err)
+	}
+	return list, nil
+}
@@ -52,6 +52,6 @@ require (
 	github.com/spf13/viper v1.20.1
 	golang.org/x/crypto v0.36.0 // indirect
 	golang.org/x/sys v0.31.0 // indirect
-	golang.org/x/term v0.30.0 // indirect
+	golang.org/x/term v0.30.0
 	golang.org/x/text v0.23.0 // indirect
 )
@@ -19,7 +19,7 @@ type AlbumSimplified struct {
 	// SharedUsers                []string  `json:"sharedUsers"`
 	// Owner                      User      `json:"owner"`
 	// Shared                     bool      `json:"shared"`
-	// AssetCount                 int       `json:"assetCount"`
+	AssetCount int `json:"assetCount,omitempty"`
 	// LastModifiedAssetTimestamp time.Time `json:"lastModifiedAssetTimestamp"
 	AssetIds []string `json:"assetIds,omitempty"`
 }
@@ -179,3 +179,20 @@ func (ic *ImmichClient) DeleteAlbum(ctx context.Context, id string) error {
 	}
 	return ic.newServerCall(ctx, EndPointDeleteAlbum).do(deleteRequest("/albums/" + id))
 }
+
+// UpdAlbumField is used to update the album with the fields given in the struct
+type UpdAlbumField struct {
+	AlbumName   string `json:"albumName,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func (ic *ImmichClient) UpdateAlbum(ctx context.Context, id string, param UpdAlbumField) (AlbumSimplified, error) {
+	if ic.dryRun {
+		return AlbumSimplified{ID: id, AlbumName: param.AlbumName, Description: param.Description}, nil
+	}
+	var r AlbumSimplified
+	err := ic.newServerCall(ctx, EndPointUpdateAlbum).do(
+		patchRequest("/albums/"+id, setAcceptJSON(), setJSONBody(param)),
+		responseJSON(&r))
+	return r, err
+}
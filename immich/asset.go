@@ -214,7 +214,7 @@ type UpdAssetField struct {
 	Longitude        float64   `json:"longitude,omitempty"`
 	Description      string    `json:"description,omitempty"`
 	Rating           int       `json:"rating,omitempty"`
-	DateTimeOriginal time.Time `json:"dateTimeOriginal,omitempty"`
+	DateTimeOriginal time.Time `json:"dateTimeOriginal,omitzero"`
 }
 
 // MarshalJSON customizes the JSON marshaling for the UpdAssetField struct.
@@ -229,7 +229,7 @@ func (u UpdAssetField) MarshalJSON() ([]byte, error) {
 		Longitude        float64   `json:"longitude"`
 		Description      string    `json:"description,omitempty"`
 		Rating           int       `json:"rating,omitempty"`
-		DateTimeOriginal time.Time `json:"dateTimeOriginal,omitempty"`
+		DateTimeOriginal time.Time `json:"dateTimeOriginal,omitzero"`
 	}
 
 	// alias is used to omit Latitude and Longitude when they are zero.
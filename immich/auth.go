@@ -0,0 +1,97 @@
+package immich
+
+import (
+	"context"
+)
+
+// OptionAccessToken authenticates the calls with a session token instead of an API key
+func OptionAccessToken(token string) ClientOption {
+	return func(ic *ImmichClient) error {
+		ic.accessToken = token
+		return nil
+	}
+}
+
+type LoginResponse struct {
+	AccessToken          string `json:"accessToken"`
+	UserID               string `json:"userId"`
+	UserEmail            string `json:"userEmail"`
+	Name                 string `json:"name"`
+	IsAdmin              bool   `json:"isAdmin"`
+	ShouldChangePassword bool   `json:"shouldChangePassword"`
+}
+
+// Login opens a session with the user's email and password.
+// The returned token authenticates the calls of a client created with OptionAccessToken.
+func (ic *ImmichClient) Login(ctx context.Context, email, password string) (LoginResponse, error) {
+	var r LoginResponse
+	body := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{Email: email, Password: password}
+	err := ic.newServerCall(ctx, EndPointLogin).do(postRequest("/auth/login", "application/json", setAcceptJSON(), setJSONBody(body)), responseJSON(&r))
+	return r, err
+}
+
+// Logout closes the session of the access token
+func (ic *ImmichClient) Logout(ctx context.Context) error {
+	return ic.newServerCall(ctx, EndPointLogout).do(postRequest("/auth/logout", "application/json", setAcceptJSON()))
+}
+
+// OAuthAuthorize gives the URL of the OAuth provider's login page.
+// The provider sends the browser back to the redirectURI once the user is authenticated.
+func (ic *ImmichClient) OAuthAuthorize(ctx context.Context, redirectURI string) (string, error) {
+	var r struct {
+		URL string `json:"url"`
+	}
+	body := struct {
+		RedirectURI string `json:"redirectUri"`
+	}{RedirectURI: redirectURI}
+	err := ic.newServerCall(ctx, EndPointOAuthAuthorize).do(postRequest("/oauth/authorize", "application/json", setAcceptJSON(), setJSONBody(body)), responseJSON(&r))
+	return r.URL, err
+}
+
+// OAuthCallback opens a session with the URL received by the redirectURI
+func (ic *ImmichClient) OAuthCallback(ctx context.Context, callbackURL string) (LoginResponse, error) {
+	var r LoginResponse
+	body := struct {
+		URL string `json:"url"`
+	}{URL: callbackURL}
+	err := ic.newServerCall(ctx, EndPointOAuthCallback).do(postRequest("/oauth/callback", "application/json", setAcceptJSON(), setJSONBody(body)), responseJSON(&r))
+	return r, err
+}
+
+type APIKey struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+// CreateAPIKey mints an API key with the given permissions, all of them when empty.
+// The secret is only given at the creation.
+func (ic *ImmichClient) CreateAPIKey(ctx context.Context, name string, permissions []string) (APIKey, string, error) {
+	if ic.dryRun {
+		return APIKey{Name: name, Permissions: permissions}, "", nil
+	}
+	if len(permissions) == 0 {
+		permissions = []string{"all"}
+	}
+	var r struct {
+		Secret string `json:"secret"`
+		APIKey APIKey `json:"apiKey"`
+	}
+	body := struct {
+		Name        string   `json:"name"`
+		Permissions []string `json:"permissions"`
+	}{Name: name, Permissions: permissions}
+	err := ic.newServerCall(ctx, EndPointCreateAPIKey).do(postRequest("/api-keys", "application/json", setAcceptJSON(), setJSONBody(body)), responseJSON(&r))
+	return r.APIKey, r.Secret, err
+}
+
+// DeleteAPIKey revokes the API key
+func (ic *ImmichClient) DeleteAPIKey(ctx context.Context, id string) error {
+	if ic.dryRun {
+		return nil
+	}
+	return ic.newServerCall(ctx, EndPointDeleteAPIKey).do(deleteRequest("/api-keys/" + id))
+}
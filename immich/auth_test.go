@@ -0,0 +1,76 @@
+package immich_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestLoginAndAPIKey(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization")+r.Header.Get("X-Api-Key"))
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/auth/login":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["email"] != "demo@immich.app" || body["password"] != "demo" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"message":"Incorrect email or password"}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"accessToken":"session","userEmail":"demo@immich.app"}`))
+		case "POST /api/api-keys":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"secret":"secret","apiKey":{"id":"key-id","name":"immich-go","permissions":["all"]}}`))
+		case "DELETE /api/api-keys/key-id":
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /api/auth/logout":
+			_, _ = w.Write([]byte(`{"successful":true}`))
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	client, _ := immich.NewImmichClient(server.URL, "")
+	if _, err := client.Login(ctx, "demo@immich.app", "wrong"); immich.StatusCode(err) != http.StatusUnauthorized {
+		t.Errorf("Login with a wrong password: %v", err)
+	}
+	session, err := client.Login(ctx, "demo@immich.app", "demo")
+	if err != nil || session.AccessToken != "session" {
+		t.Fatalf("Login = %+v, %v", session, err)
+	}
+
+	client, _ = immich.NewImmichClient(server.URL, "", immich.OptionAccessToken(session.AccessToken))
+	key, secret, err := client.CreateAPIKey(ctx, "immich-go", nil)
+	if err != nil || secret != "secret" || key.ID != "key-id" {
+		t.Fatalf("CreateAPIKey = %+v, %q, %v", key, secret, err)
+	}
+	if err := client.DeleteAPIKey(ctx, key.ID); err != nil {
+		t.Errorf("DeleteAPIKey: %v", err)
+	}
+	if err := client.Logout(ctx); err != nil {
+		t.Errorf("Logout: %v", err)
+	}
+
+	want := []string{
+		"POST /api/auth/login ",
+		"POST /api/auth/login ",
+		"POST /api/api-keys Bearer session",
+		"DELETE /api/api-keys/key-id Bearer session",
+		"POST /api/auth/logout Bearer session",
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %q, want %q", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %q, want %q", i, calls[i], want[i])
+		}
+	}
+}
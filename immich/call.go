@@ -26,6 +26,7 @@ const (
 	EndPointCreateAlbum            = "CreateAlbum"
 	EndPointGetAssetAlbums         = "GetAssetAlbums"
 	EndPointDeleteAlbum            = "DeleteAlbum"
+	EndPointUpdateAlbum            = "UpdateAlbum"
 	EndPointPingServer             = "PingServer"
 	EndPointValidateConnection     = "ValidateConnection"
 	EndPointGetServerStatistics    = "GetServerStatistics"
@@ -36,9 +37,18 @@ const (
 	EndPointTagAssets              = "TagAssets"
 	EndPointBulkTagAssets          = "BulkTagAssets"
 	EndPointGetAllTags             = "GetAllTags"
+	EndPointDeleteTag              = "DeleteTag"
+	EndPointUntagAssets            = "UntagAssets"
 	EndPointAssetUpload            = "AssetUpload"
 	EndPointAssetReplace           = "AssetReplace"
 	EndPointGetAboutInfo           = "GetAboutInfo"
+	EndPointGetServerFeatures      = "GetServerFeatures"
+	EndPointLogin                  = "Login"
+	EndPointLogout                 = "Logout"
+	EndPointOAuthAuthorize         = "OAuthAuthorize"
+	EndPointOAuthCallback          = "OAuthCallback"
+	EndPointCreateAPIKey           = "CreateAPIKey"
+	EndPointDeleteAPIKey           = "DeleteAPIKey"
 )
 
 type TooManyInternalError struct {
@@ -105,6 +115,15 @@ func (ce callError) Error() string {
 	return b.String()
 }
 
+// StatusCode returns the HTTP status of the server's response carried by the error, 0 when there is none
+func StatusCode(err error) int {
+	var ce callError
+	if errors.As(err, &ce) {
+		return ce.status
+	}
+	return 0
+}
+
 func (ic *ImmichClient) newServerCall(ctx context.Context, api string) *serverCall {
 	sc := &serverCall{
 		endPoint: api,
@@ -114,6 +133,19 @@ func (ic *ImmichClient) newServerCall(ctx context.Context, api string) *serverCa
 	return sc
 }
 
+// untracedEndPoints aren't written in the API trace: the jobs are polled too often,
+// and the others carry passwords, tokens or keys.
+var untracedEndPoints = map[string]bool{
+	EndPointGetJobs:       true,
+	EndPointLogin:         true,
+	EndPointOAuthCallback: true,
+	EndPointCreateAPIKey:  true,
+}
+
+func (sc *serverCall) traced() bool {
+	return sc.ic.apiTraceWriter != nil && !untracedEndPoints[sc.endPoint]
+}
+
 func (sc *serverCall) Err(req *http.Request, resp *http.Response, msg *ServerErrorMessage) error {
 	ce := callError{
 		endPoint: sc.endPoint,
@@ -148,7 +180,7 @@ func (sc *serverCall) request(
 	url string,
 	opts ...serverRequestOption,
 ) *http.Request {
-	if sc.ic.apiTraceWriter != nil && sc.endPoint != EndPointGetJobs {
+	if sc.traced() {
 		seq := callSequence.Add(1)
 		sc.ctx = context.WithValue(sc.ctx, ctxCallSequenceID, seq)
 	}
@@ -156,7 +188,7 @@ func (sc *serverCall) request(
 	if sc.joinError(err) != nil {
 		return nil
 	}
-	opts = append(opts, setAPIKey())
+	opts = append(opts, setHeaders(), setAPIKey())
 	for _, opt := range opts {
 		if opt != nil {
 			if sc.joinError(opt(sc, req)) != nil {
@@ -206,6 +238,15 @@ func putRequest(url string, opts ...serverRequestOption) requestFunction {
 	}
 }
 
+func patchRequest(url string, opts ...serverRequestOption) requestFunction {
+	return func(sc *serverCall) *http.Request {
+		if sc.err != nil {
+			return nil
+		}
+		return sc.request(http.MethodPatch, sc.ic.endPoint+url, opts...)
+	}
+}
+
 func (sc *serverCall) do(fnRequest requestFunction, opts ...serverResponseOption) error {
 	var (
 		resp *http.Response
@@ -217,11 +258,19 @@ func (sc *serverCall) do(fnRequest requestFunction, opts ...serverResponseOption
 		return sc.Err(req, nil, nil)
 	}
 
-	if sc.ic.apiTraceWriter != nil && sc.endPoint != EndPointGetJobs {
+	if sc.traced() {
 		_ = sc.joinError(setTraceRequest()(sc, req))
 	}
 
+	start := time.Now()
 	resp, err = sc.ic.client.Do(req)
+	if sc.ic.callObserver != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		sc.ic.callObserver(sc.endPoint, status, time.Since(start))
+	}
 	// any non nil error must be returned
 	if err != nil {
 		_ = sc.joinError(err)
@@ -236,7 +285,7 @@ func (sc *serverCall) do(fnRequest requestFunction, opts ...serverResponseOption
 			b := bytes.NewBuffer(nil)
 			_, _ = io.Copy(b, resp.Body)
 			if json.NewDecoder(b).Decode(&msg) == nil {
-				if sc.ic.apiTraceWriter != nil && sc.endPoint != EndPointGetJobs {
+				if sc.traced() {
 					seq := sc.ctx.Value(ctxCallSequenceID)
 					fmt.Fprintln(
 						sc.ic.apiTraceWriter,
@@ -255,7 +304,7 @@ func (sc *serverCall) do(fnRequest requestFunction, opts ...serverResponseOption
 				}
 				return sc.Err(req, resp, &msg)
 			} else {
-				if sc.ic.apiTraceWriter != nil && sc.endPoint != EndPointGetJobs {
+				if sc.traced() {
 					seq := sc.ctx.Value(ctxCallSequenceID)
 					fmt.Fprintln(
 						sc.ic.apiTraceWriter,
@@ -307,6 +356,18 @@ func setImmichChecksum(a *assets.Asset) serverRequestOption {
 	}
 }
 
+// setTrailer declares the trailer sent after the request's body.
+// The values are set by the body's writer before the end of the body.
+func setTrailer(trailer http.Header) serverRequestOption {
+	if trailer == nil {
+		return nil
+	}
+	return func(sc *serverCall, req *http.Request) error {
+		req.Trailer = trailer
+		return nil
+	}
+}
+
 func setAcceptJSON() serverRequestOption {
 	return func(sc *serverCall, req *http.Request) error {
 		req.Header.Add("Accept", "application/json")
@@ -323,7 +384,12 @@ func setOctetStream() serverRequestOption {
 
 func setAPIKey() serverRequestOption {
 	return func(sc *serverCall, req *http.Request) error {
-		req.Header.Set("x-api-key", sc.ic.key)
+		switch {
+		case sc.ic.key != "":
+			req.Header.Set("x-api-key", sc.ic.key)
+		case sc.ic.accessToken != "":
+			req.Header.Set("Authorization", "Bearer "+sc.ic.accessToken)
+		}
 		return nil
 	}
 }
@@ -360,7 +426,7 @@ func responseJSON[T any](object *T) serverResponseOption {
 					return nil
 				}
 
-				if sc.ic.apiTraceWriter != nil && sc.endPoint != EndPointGetJobs {
+				if sc.traced() {
 					sc.ic.apiTraceLock.Lock()
 					defer sc.ic.apiTraceLock.Unlock()
 					resp.Body = hijackBody(resp.Body, sc.ic.apiTraceWriter)
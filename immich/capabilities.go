@@ -0,0 +1,161 @@
+package immich
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a server version major.minor.patch
+type Version [3]int
+
+// MinimumVersion is the oldest server using the API paths of immich-go
+var MinimumVersion = Version{1, 106, 0}
+
+// ParseVersion reads a version given as v1.2.3 or 1.2.3
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("invalid server version %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return v, fmt.Errorf("invalid server version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// Less tells if the version v is older than o
+func (v Version) Less(o Version) bool {
+	for i := range v {
+		if v[i] != o[i] {
+			return v[i] < o[i]
+		}
+	}
+	return false
+}
+
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v[0], v[1], v[2])
+}
+
+// Capability is a feature of the server used by immich-go
+type Capability string
+
+const (
+	CapabilityTags               Capability = "tags"                // The /tags API
+	CapabilityStacks             Capability = "stacks"              // The /stacks API. Before, the stacks are made by updating the assets
+	CapabilitySmartSearch        Capability = "smart search"        // Enabled in the server's settings
+	CapabilityDuplicateDetection Capability = "duplicate detection" // Enabled in the server's settings
+)
+
+// capabilityVersions gives the first server version having the capability
+var capabilityVersions = map[Capability]Version{
+	CapabilityTags:   {1, 113, 0},
+	CapabilityStacks: {1, 116, 0},
+}
+
+// ServerFeatures are the features enabled in the server's settings
+type ServerFeatures struct {
+	SmartSearch        bool `json:"smartSearch"`
+	FacialRecognition  bool `json:"facialRecognition"`
+	DuplicateDetection bool `json:"duplicateDetection"`
+	Map                bool `json:"map"`
+	ReverseGeocoding   bool `json:"reverseGeocoding"`
+	Sidecar            bool `json:"sidecar"`
+	Search             bool `json:"search"`
+	Trash              bool `json:"trash"`
+	OAuth              bool `json:"oauth"`
+	OAuthAutoLaunch    bool `json:"oauthAutoLaunch"`
+	PasswordLogin      bool `json:"passwordLogin"`
+}
+
+// UnsupportedError is returned when the server doesn't have the capability needed by a call
+type UnsupportedError struct {
+	Capability Capability
+	Version    Version // Version of the server
+	Needed     Version // First version having the capability, zero when it's a setting of the server
+}
+
+func (e *UnsupportedError) Error() string {
+	if e.Needed.IsZero() {
+		return fmt.Sprintf("the %s is disabled on the server", e.Capability)
+	}
+	return fmt.Sprintf("the %s need an Immich server %s or later, the server is %s", e.Capability, e.Needed, e.Version)
+}
+
+func (e *UnsupportedError) Is(target error) bool {
+	_, ok := target.(*UnsupportedError)
+	return ok
+}
+
+func (ic *ImmichClient) GetServerFeatures(ctx context.Context) (ServerFeatures, error) {
+	var f ServerFeatures
+	err := ic.newServerCall(ctx, EndPointGetServerFeatures).do(getRequest("/server/features", setAcceptJSON()), responseJSON(&f))
+	return f, err
+}
+
+// NegotiateCapabilities gets the version and the features of the server, to select the API calls
+// matching the server. Until then, the client uses the API of the latest server.
+// A version that can't be parsed, like the one of a release candidate or a development build,
+// is unknown: ServerVersion stays zero and the client keeps the API of the latest server.
+func (ic *ImmichClient) NegotiateCapabilities(ctx context.Context) (AboutInfo, error) {
+	about, err := ic.GetAboutInfo(ctx)
+	if err != nil {
+		return about, err
+	}
+	if v, err := ParseVersion(about.Version); err == nil {
+		if v.Less(MinimumVersion) {
+			return about, fmt.Errorf("the Immich server %s is too old, immich-go needs %s or later", v, MinimumVersion)
+		}
+		ic.serverVersion = v
+	}
+	// The features are optional: the API key may not be allowed to read them
+	if f, err := ic.GetServerFeatures(ctx); err == nil {
+		ic.serverFeatures = &f
+	}
+	return about, nil
+}
+
+// ServerVersion returns the version of the server, zero before the negotiation
+func (ic *ImmichClient) ServerVersion() Version {
+	return ic.serverVersion
+}
+
+// Supports tells if the server has the capability
+func (ic *ImmichClient) Supports(c Capability) bool {
+	return ic.Require(c) == nil
+}
+
+// Require returns an *UnsupportedError when the server doesn't have the capability
+func (ic *ImmichClient) Require(c Capability) error {
+	if ic.serverVersion.IsZero() {
+		return nil
+	}
+	if needed, ok := capabilityVersions[c]; ok && ic.serverVersion.Less(needed) {
+		return &UnsupportedError{Capability: c, Version: ic.serverVersion, Needed: needed}
+	}
+	if ic.serverFeatures == nil {
+		return nil
+	}
+	enabled := true
+	switch c {
+	case CapabilitySmartSearch:
+		enabled = ic.serverFeatures.SmartSearch
+	case CapabilityDuplicateDetection:
+		enabled = ic.serverFeatures.DuplicateDetection
+	}
+	if !enabled {
+		return &UnsupportedError{Capability: c, Version: ic.serverVersion}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package immich_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		version   string
+		wantTags  bool
+		wantStack string // request used to create a stack
+	}{
+		{version: "v1.112.3", wantTags: false, wantStack: "PUT /api/assets"},
+		{version: "v1.113.0", wantTags: true, wantStack: "PUT /api/assets"},
+		{version: "v1.120.1", wantTags: true, wantStack: "POST /api/stacks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			var stackCall string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/server/about":
+					_ = json.NewEncoder(w).Encode(map[string]string{"version": tt.version})
+				case "/api/server/features":
+					_ = json.NewEncoder(w).Encode(map[string]bool{"smartSearch": true})
+				case "/api/stacks", "/api/assets":
+					stackCall = r.Method + " " + r.URL.Path
+					_ = json.NewEncoder(w).Encode(map[string]string{"id": "stack"})
+				case "/api/tags":
+					_, _ = w.Write([]byte("[]"))
+				}
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			client, _ := immich.NewImmichClient(server.URL, "test-key")
+			if _, err := client.NegotiateCapabilities(ctx); err != nil {
+				t.Fatalf("NegotiateCapabilities: %v", err)
+			}
+			if got := client.Supports(immich.CapabilityTags); got != tt.wantTags {
+				t.Errorf("Supports(tags) = %v, want %v", got, tt.wantTags)
+			}
+			if client.Supports(immich.CapabilityDuplicateDetection) {
+				t.Errorf("the duplicate detection should be disabled")
+			}
+			_, err := client.GetAllTags(ctx)
+			if tt.wantTags == (err != nil) {
+				t.Errorf("GetAllTags: %v", err)
+			}
+			if !tt.wantTags && !errors.Is(err, &immich.UnsupportedError{}) {
+				t.Errorf("GetAllTags: want an UnsupportedError, got %v", err)
+			}
+			id, err := client.CreateStack(ctx, []string{"cover", "child"})
+			if err != nil {
+				t.Fatalf("CreateStack: %v", err)
+			}
+			if stackCall != tt.wantStack {
+				t.Errorf("CreateStack called %q, want %q", stackCall, tt.wantStack)
+			}
+			if tt.wantStack == "PUT /api/assets" && id != "cover" {
+				t.Errorf("the legacy stack ID should be the cover's one, got %q", id)
+			}
+		})
+	}
+}
+
+func TestTooOldServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"version": "v1.105.0"})
+	}))
+	defer server.Close()
+	client, _ := immich.NewImmichClient(server.URL, "test-key")
+	if _, err := client.NegotiateCapabilities(context.Background()); err == nil {
+		t.Errorf("NegotiateCapabilities should refuse the server v1.105.0")
+	}
+}
+
+func TestUnparsableServerVersion(t *testing.T) {
+	for _, version := range []string{"v1.120.0-rc1", "dev"} {
+		t.Run(version, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(map[string]string{"version": version})
+			}))
+			defer server.Close()
+			client, _ := immich.NewImmichClient(server.URL, "test-key")
+			about, err := client.NegotiateCapabilities(context.Background())
+			if err != nil {
+				t.Fatalf("NegotiateCapabilities: %v", err)
+			}
+			if about.Version != version {
+				t.Errorf("version %q, want %q", about.Version, version)
+			}
+			if !client.ServerVersion().IsZero() {
+				t.Errorf("the version should be unknown, got %s", client.ServerVersion())
+			}
+			if !client.Supports(immich.CapabilityTags) {
+				t.Errorf("the latest API should be used for an unknown version")
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/simulot/immich-go/internal/filetypes"
+	"github.com/simulot/immich-go/internal/throttle"
 )
 
 /*
@@ -22,14 +23,20 @@ type ImmichClient struct {
 	roundTripper   *http.Transport
 	endPoint       string        // Server API url
 	key            string        // User KEY
+	accessToken    string        // Session token, used when there is no key
 	DeviceUUID     string        // Device
 	Retries        int           // Number of attempts on 500 errors
 	RetriesDelay   time.Duration // Duration between retries
 	apiTraceWriter io.Writer     // If not nil, logs API calls to this writer
 	apiTraceLock   sync.Mutex    // Lock for API trace
+	headers        http.Header   // Headers added to each request
 
 	supportedMediaTypes filetypes.SupportedMedia // Server's list of supported medias
 	dryRun              bool                     //  If true, do not send any data to the server
+	uploadLimiter       *throttle.Limiter        // If not nil, limits the upload bandwidth
+	serverVersion       Version                  // Version of the server, zero until the negotiation
+	serverFeatures      *ServerFeatures          // Features enabled on the server, nil when unknown
+	callObserver        CallObserver             // If not nil, receives the outcome of each call to the server
 }
 
 func (ic *ImmichClient) SetEndPoint(endPoint string) {
@@ -48,27 +55,42 @@ func (ic *ImmichClient) EnableAppTrace(w io.Writer) {
 	ic.apiTraceWriter = w
 }
 
+// SetUploadLimiter limits the bandwidth used by the uploads.
+// The limiter is shared by all concurrent uploads.
+func (ic *ImmichClient) SetUploadLimiter(l *throttle.Limiter) {
+	ic.uploadLimiter = l
+}
+
+// CallObserver receives the end point, the HTTP status, and the duration of each call to the server.
+// The status is 0 when the server hasn't responded.
+type CallObserver func(endPoint string, status int, d time.Duration)
+
+// SetCallObserver sets the function observing the calls to the server.
+func (ic *ImmichClient) SetCallObserver(o CallObserver) {
+	ic.callObserver = o
+}
+
 func (ic *ImmichClient) SupportedMedia() filetypes.SupportedMedia {
 	return ic.supportedMediaTypes
 }
 
-type clientOption func(ic *ImmichClient) error
+type ClientOption func(ic *ImmichClient) error
 
-func OptionVerifySSL(verify bool) clientOption {
+func OptionVerifySSL(verify bool) ClientOption {
 	return func(ic *ImmichClient) error {
 		ic.roundTripper.TLSClientConfig.InsecureSkipVerify = verify
 		return nil
 	}
 }
 
-func OptionConnectionTimeout(d time.Duration) clientOption {
+func OptionConnectionTimeout(d time.Duration) ClientOption {
 	return func(ic *ImmichClient) error {
 		ic.client.Timeout = d
 		return nil
 	}
 }
 
-func OptionDryRun(dryRun bool) clientOption {
+func OptionDryRun(dryRun bool) ClientOption {
 	return func(ic *ImmichClient) error {
 		ic.dryRun = dryRun
 		return nil
@@ -76,7 +98,7 @@ func OptionDryRun(dryRun bool) clientOption {
 }
 
 // Create a new ImmichClient
-func NewImmichClient(endPoint string, key string, options ...clientOption) (*ImmichClient, error) {
+func NewImmichClient(endPoint string, key string, options ...ClientOption) (*ImmichClient, error) {
 	var err error
 	deviceUUID, err := os.Hostname()
 	if err != nil {
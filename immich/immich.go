@@ -8,9 +8,13 @@ import (
 
 	"github.com/simulot/immich-go/internal/assets"
 	"github.com/simulot/immich-go/internal/filetypes"
+	"github.com/simulot/immich-go/internal/throttle"
 )
 
-var _ ImmichInterface = (*ImmichClient)(nil)
+var (
+	_ ImmichInterface              = (*ImmichClient)(nil)
+	_ ImmichUploadLimiterInterface = (*ImmichClient)(nil)
+)
 
 // ImmichInterface is an interface that implements the minimal immich client set of features for uploading
 // interface used to mock up the client
@@ -58,6 +62,15 @@ type ImmichClientInterface interface {
 	GetAssetStatistics(ctx context.Context) (UserStatistics, error)
 	SupportedMedia() filetypes.SupportedMedia
 	GetAboutInfo(ctx context.Context) (AboutInfo, error)
+	ImmichCapabilityInterface
+}
+
+// ImmichCapabilityInterface tells the capabilities of the server
+type ImmichCapabilityInterface interface {
+	NegotiateCapabilities(ctx context.Context) (AboutInfo, error)
+	ServerVersion() Version
+	Supports(c Capability) bool
+	Require(c Capability) error
 }
 
 type ImmichAlbumInterface interface {
@@ -73,6 +86,7 @@ type ImmichAlbumInterface interface {
 	// GetAssetAlbums get all albums that an asset belongs to
 	GetAssetAlbums(ctx context.Context, assetID string) ([]AlbumSimplified, error)
 	DeleteAlbum(ctx context.Context, id string) error
+	UpdateAlbum(ctx context.Context, id string, param UpdAlbumField) (AlbumSimplified, error)
 }
 type ImmichTagInterface interface {
 	GetAllTags(ctx context.Context) ([]TagSimplified, error)
@@ -89,6 +103,12 @@ type ImmichTagInterface interface {
 	) (struct {
 		Count int `json:"count"`
 	}, error)
+	UntagAssets(
+		ctx context.Context,
+		tagID string,
+		assetIDs []string,
+	) ([]TagAssetsResponse, error)
+	DeleteTag(ctx context.Context, id string) error
 }
 
 type ImmichStackInterface interface {
@@ -96,6 +116,16 @@ type ImmichStackInterface interface {
 	CreateStack(ctx context.Context, ids []string) (string, error)
 }
 
+// ImmichUploadLimiterInterface is implemented by the clients able to limit their upload bandwidth
+type ImmichUploadLimiterInterface interface {
+	SetUploadLimiter(l *throttle.Limiter)
+}
+
+// ImmichCallObserverInterface is implemented by the clients able to report their calls to the server
+type ImmichCallObserverInterface interface {
+	SetCallObserver(o CallObserver)
+}
+
 type ImmichJobInterface interface {
 	GetJobs(ctx context.Context) (map[string]Job, error)
 	SendJobCommand(
@@ -36,6 +36,14 @@ type JobID string
 
 const (
 	StorageTemplateMigration JobID = "storageTemplateMigration"
+	ThumbnailGeneration      JobID = "thumbnailGeneration"
+	MetadataExtraction       JobID = "metadataExtraction"
+	VideoConversion          JobID = "videoConversion"
+	FaceDetection            JobID = "faceDetection"
+	FacialRecognition        JobID = "facialRecognition"
+	SmartSearch              JobID = "smartSearch"
+	DuplicateDetection       JobID = "duplicateDetection"
+	Sidecar                  JobID = "sidecar"
 )
 
 type JobCommand string
@@ -69,6 +77,9 @@ func (ic *ImmichClient) SendJobCommand(
 	command JobCommand,
 	force bool,
 ) (resp SendJobCommandResponse, err error) {
+	if ic.dryRun {
+		return resp, nil
+	}
 	err = ic.newServerCall(ctx, EndPointSendJobCommand).do(putRequest("/jobs/"+string(jobID),
 		setJSONBody(struct {
 			Command JobCommand `json:"command"`
@@ -139,3 +139,12 @@ func Test_searchMetadataRequest(t *testing.T) {
 		t.Errorf("expecting next page, got: %d", rest.Assets.NextPage)
 	}
 }
+
+func Test_pageNumber(t *testing.T) {
+	for body, want := range map[string]pageNumber{`"2"`: 2, `3`: 3, `null`: 0} {
+		var p pageNumber
+		if err := json.Unmarshal([]byte(body), &p); err != nil || p != want {
+			t.Errorf("pageNumber %s: got %d, %v, want %d", body, p, err, want)
+		}
+	}
+}
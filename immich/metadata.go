@@ -2,33 +2,55 @@ package immich
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 )
 
 type searchMetadataResponse struct {
 	Assets struct {
-		Total    int      `json:"total"`
-		Count    int      `json:"count"`
-		Items    []*Asset `json:"items"`
-		NextPage int      `json:"nextPage,string"`
+		Total    int        `json:"total"`
+		Count    int        `json:"count"`
+		Items    []*Asset   `json:"items"`
+		NextPage pageNumber `json:"nextPage"`
 	}
 }
 
+// pageNumber is the next page of a search, given as a string, a number or null depending on the server version
+type pageNumber int
+
+func (p *pageNumber) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" || s == "" {
+		*p = 0
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid page number %s", b)
+	}
+	*p = pageNumber(n)
+	return nil
+}
+
 type SearchMetadataQuery struct {
 	// pagination
 	Page int `json:"page"`
 	Size int `json:"size,omitempty"`
 
 	// filters
-	WithExif         bool   `json:"withExif,omitempty"`
-	IsVisible        bool   `json:"isVisible,omitempty"` // For motion stuff you need to pass isVisible=true to hide the motion ones (dijrasm91 — https://discord.com/channels/979116623879368755/1178366369423700080/1201206313699508295)
-	WithDeleted      bool   `json:"withDeleted,omitempty"`
-	WithArchived     bool   `json:"withArchived,omitempty"`
-	TakenBefore      string `json:"takenBefore,omitempty"`
-	TakenAfter       string `json:"takenAfter,omitempty"`
-	Model            string `json:"model,omitempty"`
-	Make             string `json:"make,omitempty"`
-	Checksum         string `json:"checksum,omitempty"`
-	OriginalFileName string `json:"originalFileName,omitempty"`
+	WithExif         bool     `json:"withExif,omitempty"`
+	IsVisible        bool     `json:"isVisible,omitempty"` // For motion stuff you need to pass isVisible=true to hide the motion ones (dijrasm91 — https://discord.com/channels/979116623879368755/1178366369423700080/1201206313699508295)
+	WithDeleted      bool     `json:"withDeleted,omitempty"`
+	WithArchived     bool     `json:"withArchived,omitempty"`
+	TakenBefore      string   `json:"takenBefore,omitempty"`
+	TakenAfter       string   `json:"takenAfter,omitempty"`
+	Model            string   `json:"model,omitempty"`
+	Make             string   `json:"make,omitempty"`
+	Checksum         string   `json:"checksum,omitempty"`
+	OriginalFileName string   `json:"originalFileName,omitempty"`
+	TagIDs           []string `json:"tagIds,omitempty"`
+	AlbumIDs         []string `json:"albumIds,omitempty"`
 }
 
 func (ic *ImmichClient) callSearchMetadata(ctx context.Context, query *SearchMetadataQuery, filter func(*Asset) error) error {
@@ -55,7 +77,7 @@ func (ic *ImmichClient) callSearchMetadata(ctx context.Context, query *SearchMet
 			if resp.Assets.NextPage == 0 {
 				return nil
 			}
-			query.Page = resp.Assets.NextPage
+			query.Page = int(resp.Assets.NextPage)
 		}
 	}
 }
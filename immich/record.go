@@ -0,0 +1,391 @@
+package immich
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" // nolint:gosec
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+  The API record is a file of JSON lines, one per call, with the request and the server's response.
+  The media files sent or received are replaced by their SHA1 and size, and the secrets are redacted.
+  The replay serves the recorded responses to a client, without server.
+*/
+
+// APIRecord is a recorded call
+type APIRecord struct {
+	Seq      int64         `json:"seq"`
+	Time     time.Time     `json:"time"`
+	Method   string        `json:"method"`
+	URL      string        `json:"url"` // Path and query, without the server address
+	Request  *RecordedBody `json:"request,omitempty"`
+	Digest   string        `json:"digest,omitempty"` // Identifies the request's body for the replay
+	Status   int           `json:"status,omitempty"`
+	Header   http.Header   `json:"header,omitempty"` // Content-Type of the response
+	Response *RecordedBody `json:"response,omitempty"`
+	Error    string        `json:"error,omitempty"` // Error of the transport, like a timeout
+}
+
+// RecordedBody is a request or response body
+type RecordedBody struct {
+	JSON   json.RawMessage         `json:"json,omitempty"`   // JSON body, with redacted secrets
+	Text   string                  `json:"text,omitempty"`   // Text body
+	Fields map[string]string       `json:"fields,omitempty"` // Fields of a multipart form
+	Files  map[string]RecordedFile `json:"files,omitempty"`  // Files of a multipart form
+	File   *RecordedFile           `json:"file,omitempty"`   // Binary body
+}
+
+// RecordedFile replaces the content of a media file
+type RecordedFile struct {
+	Name string `json:"name,omitempty"`
+	Size int64  `json:"size"`
+	SHA1 string `json:"sha1"`
+}
+
+// redactedKeys are the JSON fields holding secrets
+var redactedKeys = map[string]bool{
+	"password":    true,
+	"accessToken": true,
+	"secret":      true,
+}
+
+// digestBody reads a body, keeping the JSON and the text, and replacing the files by their hash
+func digestBody(contentType string, r io.Reader) (*RecordedBody, error) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	b := &RecordedBody{}
+	switch {
+	case mediaType == "multipart/form-data":
+		b.Fields = map[string]string{}
+		b.Files = map[string]RecordedFile{}
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return b, err
+			}
+			if part.FileName() != "" {
+				f, err := hashFile(part)
+				if err != nil {
+					return b, err
+				}
+				f.Name = part.FileName()
+				b.Files[part.FormName()] = f
+				continue
+			}
+			v, err := io.ReadAll(part)
+			if err != nil {
+				return b, err
+			}
+			b.Fields[part.FormName()] = string(v)
+		}
+	case mediaType == "application/json":
+		v, err := io.ReadAll(r)
+		if err != nil {
+			return b, err
+		}
+		if len(bytes.TrimSpace(v)) == 0 {
+			return nil, nil
+		}
+		b.JSON = redactJSON(v)
+	case strings.HasPrefix(mediaType, "text/"):
+		v, err := io.ReadAll(r)
+		if err != nil {
+			return b, err
+		}
+		if len(v) == 0 {
+			return nil, nil
+		}
+		b.Text = string(v)
+	default:
+		f, err := hashFile(r)
+		if err != nil || f.Size == 0 {
+			return nil, err
+		}
+		b.File = &f
+	}
+	return b, nil
+}
+
+func hashFile(r io.Reader) (RecordedFile, error) {
+	h := sha1.New() // nolint:gosec
+	n, err := io.Copy(h, r)
+	return RecordedFile{Size: n, SHA1: hex.EncodeToString(h.Sum(nil))}, err
+}
+
+// redactJSON removes the secrets of a JSON document, and compacts it
+func redactJSON(b []byte) json.RawMessage {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return json.RawMessage(fmt.Sprintf("%q", "invalid JSON"))
+	}
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, e := range v {
+				if redactedKeys[k] {
+					v[k] = "redacted"
+					continue
+				}
+				walk(e)
+			}
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	walk(v)
+	r, _ := json.Marshal(v)
+	return r
+}
+
+// digest identifies a request by its body
+func (b *RecordedBody) digest() string {
+	if b == nil {
+		return ""
+	}
+	j, _ := json.Marshal(b)
+	h := sha1.Sum(j) // nolint:gosec
+	return hex.EncodeToString(h[:])
+}
+
+// apiRecorder is a transport writing the calls in the record
+type apiRecorder struct {
+	next http.RoundTripper
+	lock sync.Mutex
+	enc  *json.Encoder
+	seq  int64
+}
+
+// OptionAPIRecord writes the calls and the server's responses in w, for a replay with OptionAPIReplay
+func OptionAPIRecord(w io.Writer) ClientOption {
+	return func(ic *ImmichClient) error {
+		ic.client.Transport = &apiRecorder{next: ic.client.Transport, enc: json.NewEncoder(w)}
+		return nil
+	}
+}
+
+func (ar *apiRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := &APIRecord{Time: time.Now(), Method: req.Method, URL: req.URL.RequestURI()}
+
+	// The request's body is read by the transport, and digested on the fly
+	var requestDone chan struct{}
+	if req.Body != nil && req.Body != http.NoBody {
+		pr, pw := io.Pipe()
+		requestDone = make(chan struct{})
+		contentType := req.Header.Get("Content-Type")
+		go func() {
+			defer close(requestDone)
+			rec.Request, _ = digestBody(contentType, pr)
+			_, _ = io.Copy(io.Discard, pr)
+		}()
+		req = req.Clone(req.Context())
+		req.Body = &teeBody{r: io.TeeReader(req.Body, pw), body: req.Body, pw: pw}
+	}
+
+	resp, err := ar.next.RoundTrip(req)
+	if err != nil {
+		rec.Error = err.Error()
+		ar.write(rec, requestDone)
+		return resp, err
+	}
+	rec.Status = resp.StatusCode
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		rec.Header = http.Header{"Content-Type": {ct}}
+	}
+
+	// The JSON and text responses are small, the media files are digested while they are read
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/json" || strings.HasPrefix(mediaType, "text/") || resp.Body == nil || resp.Body == http.NoBody {
+		var b []byte
+		if resp.Body != nil {
+			b, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(b))
+		}
+		rec.Response, _ = digestBody(resp.Header.Get("Content-Type"), bytes.NewReader(b))
+		ar.write(rec, requestDone)
+		return resp, err
+	}
+	h := sha1.New() // nolint:gosec
+	resp.Body = &hashBody{body: resp.Body, h: h, done: func(n int64) {
+		rec.Response = &RecordedBody{File: &RecordedFile{Size: n, SHA1: hex.EncodeToString(h.Sum(nil))}}
+		ar.write(rec, requestDone)
+	}}
+	return resp, nil
+}
+
+// write adds the record once the request's body is digested
+func (ar *apiRecorder) write(rec *APIRecord, requestDone chan struct{}) {
+	if requestDone != nil {
+		<-requestDone
+	}
+	rec.Digest = rec.Request.digest()
+	ar.lock.Lock()
+	defer ar.lock.Unlock()
+	ar.seq++
+	rec.Seq = ar.seq
+	_ = ar.enc.Encode(rec)
+}
+
+// teeBody copies the request's body to the digest
+type teeBody struct {
+	r    io.Reader
+	body io.ReadCloser
+	pw   *io.PipeWriter
+}
+
+func (t *teeBody) Read(b []byte) (int, error) {
+	n, err := t.r.Read(b)
+	if errors.Is(err, io.EOF) {
+		_ = t.pw.Close()
+	}
+	return n, err
+}
+
+func (t *teeBody) Close() error {
+	_ = t.pw.Close()
+	return t.body.Close()
+}
+
+// hashBody hashes a response's body, and calls done at the end of the body or when it's closed
+type hashBody struct {
+	body io.ReadCloser
+	h    io.Writer
+	n    int64
+	once sync.Once
+	done func(n int64)
+}
+
+func (hb *hashBody) Read(b []byte) (int, error) {
+	n, err := hb.body.Read(b)
+	_, _ = hb.h.Write(b[:n])
+	hb.n += int64(n)
+	if errors.Is(err, io.EOF) {
+		hb.once.Do(func() { hb.done(hb.n) })
+	}
+	return n, err
+}
+
+func (hb *hashBody) Close() error {
+	hb.once.Do(func() { hb.done(hb.n) })
+	return hb.body.Close()
+}
+
+// apiReplayer is a transport serving the recorded responses
+type apiReplayer struct {
+	lock    sync.Mutex
+	pending map[string][]*APIRecord // records not yet served, by method and URL
+}
+
+// OptionAPIReplay serves the responses recorded by OptionAPIRecord instead of calling the server.
+// The requests are matched by method, URL, and body. When the body has changed, the next
+// record of the same method and URL is served.
+func OptionAPIReplay(r io.Reader) ClientOption {
+	return func(ic *ImmichClient) error {
+		ar := &apiReplayer{pending: map[string][]*APIRecord{}}
+		s := bufio.NewScanner(r)
+		s.Buffer(nil, 64<<20)
+		line := 0
+		for s.Scan() {
+			line++
+			if len(bytes.TrimSpace(s.Bytes())) == 0 {
+				continue
+			}
+			rec := &APIRecord{}
+			if err := json.Unmarshal(s.Bytes(), rec); err != nil {
+				return fmt.Errorf("can't read the API record, line %d: %w", line, err)
+			}
+			k := rec.Method + " " + rec.URL
+			ar.pending[k] = append(ar.pending[k], rec)
+		}
+		if err := s.Err(); err != nil {
+			return fmt.Errorf("can't read the API record: %w", err)
+		}
+		ic.client.Transport = ar
+		return nil
+	}
+}
+
+func (ar *apiReplayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	digest := ""
+	if req.Body != nil && req.Body != http.NoBody {
+		b, _ := digestBody(req.Header.Get("Content-Type"), req.Body)
+		_, _ = io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+		digest = b.digest()
+	}
+
+	k := req.Method + " " + req.URL.RequestURI()
+	ar.lock.Lock()
+	records := ar.pending[k]
+	pick := -1
+	for i, rec := range records {
+		if rec.Digest == digest {
+			pick = i
+			break
+		}
+	}
+	if pick < 0 && len(records) > 0 {
+		pick = 0
+	}
+	if pick < 0 {
+		ar.lock.Unlock()
+		return nil, fmt.Errorf("api-replay: no recorded response for %s", k)
+	}
+	rec := records[pick]
+	ar.pending[k] = append(records[:pick:pick], records[pick+1:]...)
+	ar.lock.Unlock()
+
+	if rec.Error != "" {
+		return nil, errors.New(rec.Error)
+	}
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode: rec.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     rec.Header,
+		Body:       http.NoBody,
+		Request:    req,
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	if b := rec.Response; b != nil {
+		switch {
+		case b.JSON != nil:
+			resp.Body = io.NopCloser(bytes.NewReader(b.JSON))
+		case b.Text != "":
+			resp.Body = io.NopCloser(strings.NewReader(b.Text))
+		case b.File != nil:
+			// the content of the media isn't recorded, only its size
+			resp.Body = io.NopCloser(io.LimitReader(zeros{}, b.File.Size))
+			resp.ContentLength = b.File.Size
+		}
+	}
+	return resp, nil
+}
+
+type zeros struct{}
+
+func (zeros) Read(b []byte) (int, error) {
+	clear(b)
+	return len(b), nil
+}
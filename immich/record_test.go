@@ -0,0 +1,110 @@
+package immich_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/fshelper"
+)
+
+// recordedCalls connects, uploads two photos, downloads one, and logs in
+func recordedCalls(t *testing.T, client *immich.ImmichClient) []string {
+	ctx := context.Background()
+	user, err := client.ValidateConnection(ctx)
+	if err != nil {
+		t.Fatalf("ValidateConnection: %v", err)
+	}
+	results := []string{user.Email}
+	fsys := fstest.MapFS{
+		"a.jpg": &fstest.MapFile{Data: []byte("photo A content"), ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"b.jpg": &fstest.MapFile{Data: []byte("photo B content"), ModTime: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, name := range []string{"b.jpg", "a.jpg"} {
+		a := &assets.Asset{File: fshelper.FSName(fsys, name), OriginalFileName: name, FileSize: len(fsys[name].Data), CaptureDate: fsys[name].ModTime}
+		r, err := client.AssetUpload(ctx, a)
+		if err != nil {
+			t.Fatalf("AssetUpload(%s): %v", name, err)
+		}
+		results = append(results, name+"="+r.ID)
+	}
+	rc, err := client.DownloadAsset(ctx, "id-a.jpg")
+	if err != nil {
+		t.Fatalf("DownloadAsset: %v", err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	results = append(results, "download="+strconv.Itoa(len(b)))
+	_, err = client.Login(ctx, "demo@immich.app", "wrong")
+	results = append(results, "login status="+http.StatusText(immich.StatusCode(err)))
+	return results
+}
+
+func TestAPIRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/users/me":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id":"1","email":"user@example.com"}`))
+		case r.URL.Path == "/api/server/media-types":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"image":[".jpg"],"video":[".mp4"]}`))
+		case r.URL.Path == "/api/assets" && r.Method == http.MethodPost:
+			_ = r.ParseMultipartForm(1 << 20)
+			_, h, _ := r.FormFile("assetData")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]string{"id": "id-" + h.Filename, "status": "created"})
+		case r.URL.Path == "/api/assets/id-a.jpg/original":
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write([]byte("photo A content"))
+		case r.URL.Path == "/api/auth/login":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"Incorrect email or password","statusCode":401}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	record := bytes.NewBuffer(nil)
+	client, err := immich.NewImmichClient(server.URL, "key", immich.OptionAPIRecord(record))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := recordedCalls(t, client)
+
+	for _, secret := range []string{"photo A content", "photo B content", "wrong"} {
+		if strings.Contains(record.String(), secret) {
+			t.Errorf("the record contains %q", secret)
+		}
+	}
+	if n := strings.Count(record.String(), "\n"); n != 6 {
+		t.Errorf("the record has %d calls, want 6", n)
+	}
+
+	// the replay doesn't need the server
+	server.Close()
+	client, err = immich.NewImmichClient("http://api-replay", "", immich.OptionAPIReplay(bytes.NewReader(record.Bytes())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := recordedCalls(t, client)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("replay = %q, want %q", got, want)
+	}
+	if _, err := client.GetAboutInfo(context.Background()); err == nil {
+		t.Errorf("a call not recorded should fail")
+	}
+}
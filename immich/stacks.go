@@ -23,6 +23,9 @@ func (ic *ImmichClient) CreateStack(ctx context.Context, ids []string) (string,
 	if ic.dryRun {
 		return uuid.NewString(), nil
 	}
+	if !ic.Supports(CapabilityStacks) {
+		return ic.createLegacyStack(ctx, ids)
+	}
 
 	param := struct {
 		AssetIds []string `json:"assetIds"`
@@ -38,3 +41,17 @@ func (ic *ImmichClient) CreateStack(ctx context.Context, ids []string) (string,
 	err := ic.newServerCall(ctx, "createStack").do(postRequest("/stacks", "application/json", setAcceptJSON(), setJSONBody(param)), responseJSON(&result))
 	return result.ID, err
 }
+
+// createLegacyStack stacks the assets on the servers older than the /stacks API,
+// by giving the cover as parent to the other assets. The stack ID is the cover's ID.
+func (ic *ImmichClient) createLegacyStack(ctx context.Context, ids []string) (string, error) {
+	param := struct {
+		IDs           []string `json:"ids"`
+		StackParentID string   `json:"stackParentId"`
+	}{
+		IDs:           ids[1:],
+		StackParentID: ids[0],
+	}
+	err := ic.newServerCall(ctx, "createStack").do(putRequest("/assets", setJSONBody(param)))
+	return ids[0], err
+}
@@ -29,6 +29,9 @@ type TagAssetsResponse struct {
 }
 
 func (ic *ImmichClient) UpsertTags(ctx context.Context, tags []string) ([]TagSimplified, error) {
+	if err := ic.Require(CapabilityTags); err != nil {
+		return nil, err
+	}
 	if ic.dryRun {
 		resp := make([]TagSimplified, len(tags))
 		for i, t := range tags {
@@ -57,6 +60,9 @@ func (ic *ImmichClient) TagAssets(
 	tagID string,
 	assetIDs []string,
 ) ([]TagAssetsResponse, error) {
+	if err := ic.Require(CapabilityTags); err != nil {
+		return nil, err
+	}
 	if ic.dryRun {
 		resp := make([]TagAssetsResponse, len(assetIDs))
 		for i, a := range assetIDs {
@@ -81,6 +87,39 @@ func (ic *ImmichClient) TagAssets(
 	return resp, nil
 }
 
+// UntagAssets removes the tag from the assets
+func (ic *ImmichClient) UntagAssets(
+	ctx context.Context,
+	tagID string,
+	assetIDs []string,
+) ([]TagAssetsResponse, error) {
+	if err := ic.Require(CapabilityTags); err != nil {
+		return nil, err
+	}
+	if ic.dryRun {
+		resp := make([]TagAssetsResponse, len(assetIDs))
+		for i, a := range assetIDs {
+			resp[i] = TagAssetsResponse{
+				ID:      a,
+				Success: true,
+			}
+		}
+		return resp, nil
+	}
+
+	var resp []TagAssetsResponse
+
+	body := struct {
+		IDs []string `json:"ids"`
+	}{IDs: assetIDs}
+	err := ic.newServerCall(ctx, EndPointUntagAssets).
+		do(deleteRequest(fmt.Sprintf("/tags/%s/assets", tagID), setJSONBody(body), setAcceptJSON()), responseJSON(&resp))
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (ic *ImmichClient) BulkTagAssets(
 	ctx context.Context,
 	tagIDs []string,
@@ -89,6 +128,11 @@ func (ic *ImmichClient) BulkTagAssets(
 	Count int `json:"count"`
 }, error,
 ) {
+	if err := ic.Require(CapabilityTags); err != nil {
+		return struct {
+			Count int `json:"count"`
+		}{}, err
+	}
 	if ic.dryRun {
 		return struct {
 			Count int `json:"count"`
@@ -113,6 +157,9 @@ func (ic *ImmichClient) BulkTagAssets(
 }
 
 func (ic *ImmichClient) GetAllTags(ctx context.Context) ([]TagSimplified, error) {
+	if err := ic.Require(CapabilityTags); err != nil {
+		return nil, err
+	}
 	var resp []TagSimplified
 	err := ic.newServerCall(ctx, EndPointGetAllTags).
 		do(getRequest("/tags"), responseJSON(&resp))
@@ -121,3 +168,13 @@ func (ic *ImmichClient) GetAllTags(ctx context.Context) ([]TagSimplified, error)
 	}
 	return resp, nil
 }
+
+func (ic *ImmichClient) DeleteTag(ctx context.Context, id string) error {
+	if err := ic.Require(CapabilityTags); err != nil {
+		return err
+	}
+	if ic.dryRun {
+		return nil
+	}
+	return ic.newServerCall(ctx, EndPointDeleteTag).do(deleteRequest("/tags/" + id))
+}
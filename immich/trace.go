@@ -87,7 +87,7 @@ func setTraceRequest() serverRequestOption {
 		seq := sc.ctx.Value(ctxCallSequenceID)
 		fmt.Fprintln(sc.ic.apiTraceWriter, time.Now().Format(time.RFC3339), "QUERY", seq, sc.endPoint, req.Method, req.URL.String())
 		for h, v := range req.Header {
-			if h == "X-Api-Key" {
+			if _, custom := sc.ic.headers[h]; h == "X-Api-Key" || h == "Authorization" || custom {
 				fmt.Fprintln(sc.ic.apiTraceWriter, "  ", h, "redacted")
 			} else {
 				fmt.Fprintln(sc.ic.apiTraceWriter, "  ", h, v)
@@ -0,0 +1,116 @@
+package immich
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// TransportOptions are the network settings of the connection to the server
+type TransportOptions struct {
+	SkipVerify bool              // Skip the verification of the server's certificate
+	CACert     string            // PEM file of certificate authorities trusted in addition to the system ones
+	ClientCert string            // PEM file of the client certificate, for the mutual TLS authentication
+	ClientKey  string            // PEM file of the client certificate's key
+	Proxy      string            // URL of an http, https or socks5 proxy, no proxy when empty
+	Headers    map[string]string // Headers added to each request, like the Cloudflare Access ones
+}
+
+// TLSConfig gives the TLS configuration matching the options
+func (o TransportOptions) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: o.SkipVerify} // nolint:gosec
+	if o.CACert != "" {
+		pem, err := os.ReadFile(o.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in the CA bundle %s", o.CACert)
+		}
+		config.RootCAs = pool
+	}
+	switch {
+	case o.ClientCert != "" && o.ClientKey != "":
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("can't load the client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	case o.ClientCert != "" || o.ClientKey != "":
+		return nil, fmt.Errorf("the client certificate needs both the certificate and the key files")
+	}
+	return config, nil
+}
+
+// ProxyFunc gives the proxy selection function of the http.Transport, nil without proxy
+func (o TransportOptions) ProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if o.Proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(o.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy address: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy address %q, expected http://, https:// or socks5://", o.Proxy)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// Apply sets the options on the transport
+func (o TransportOptions) Apply(t *http.Transport) error {
+	config, err := o.TLSConfig()
+	if err != nil {
+		return err
+	}
+	proxy, err := o.ProxyFunc()
+	if err != nil {
+		return err
+	}
+	t.TLSClientConfig = config
+	t.Proxy = proxy
+	return nil
+}
+
+// OptionTransport sets the network settings of the client. It replaces OptionVerifySSL.
+func OptionTransport(o TransportOptions) ClientOption {
+	return func(ic *ImmichClient) error {
+		err := o.Apply(ic.roundTripper)
+		if err != nil {
+			return err
+		}
+		ic.headers = http.Header{}
+		for k, v := range o.Headers {
+			ic.headers.Set(k, v)
+		}
+		return nil
+	}
+}
+
+// ParseHeader reads a header given as "Name: value" or "Name=value"
+func ParseHeader(s string) (string, string, error) {
+	i := strings.IndexAny(s, ":=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("invalid header %q, expected Name: value", s)
+	}
+	return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), nil
+}
+
+func setHeaders() serverRequestOption {
+	return func(sc *serverCall, req *http.Request) error {
+		for k, v := range sc.ic.headers {
+			req.Header[k] = v
+		}
+		return nil
+	}
+}
@@ -0,0 +1,146 @@
+package immich_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+func pong(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(`{"res":"pong"}`))
+}
+
+func TestTransportHeadersAndProxy(t *testing.T) {
+	var header, proxied string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("CF-Access-Client-Id")
+		pong(w, r)
+	}))
+	defer server.Close()
+	// the proxy forwards the requests to the server
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		_, _ = w.Write([]byte(`{"res":"pong"}`))
+	}))
+	defer proxy.Close()
+
+	client, err := immich.NewImmichClient(server.URL, "key", immich.OptionTransport(immich.TransportOptions{
+		Proxy:   proxy.URL,
+		Headers: map[string]string{"CF-Access-Client-Id": "id.access"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PingServer(context.Background()); err != nil {
+		t.Fatalf("PingServer: %v", err)
+	}
+	if header != "id.access" {
+		t.Errorf("the header isn't sent: %q", header)
+	}
+	if proxied != server.URL+"/api/server/ping" {
+		t.Errorf("the request isn't sent through the proxy: %q", proxied)
+	}
+
+	_, err = immich.NewImmichClient(server.URL, "key", immich.OptionTransport(immich.TransportOptions{Proxy: "ftp://proxy"}))
+	if err == nil {
+		t.Errorf("the ftp proxy should be refused")
+	}
+}
+
+// newCert creates a certificate signed by the parent, self-signed when parent is nil
+func newCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	_ = os.WriteFile(filepath.Join(dir, name+".pem"), certPEM, 0o600)
+	_ = os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0o600)
+	pair, _ := tls.X509KeyPair(certPEM, keyPEM)
+	return cert, key, pair
+}
+
+func TestTransportMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, _ := newCert(t, dir, "ca", nil, nil)
+	_, _, serverPair := newCert(t, dir, "server", ca, caKey)
+	newCert(t, dir, "client", ca, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(pong))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{serverPair}, ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		options immich.TransportOptions
+		wantErr bool
+	}{
+		{name: "unknown CA", options: immich.TransportOptions{}, wantErr: true},
+		{name: "no client certificate", options: immich.TransportOptions{CACert: filepath.Join(dir, "ca.pem")}, wantErr: true},
+		{name: "client certificate", options: immich.TransportOptions{
+			CACert:     filepath.Join(dir, "ca.pem"),
+			ClientCert: filepath.Join(dir, "client.pem"),
+			ClientKey:  filepath.Join(dir, "client.key"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := immich.NewImmichClient(server.URL, "key", immich.OptionTransport(tt.options))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = client.PingServer(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PingServer: %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/fshelper/osfs"
 )
 
 type callValues string
@@ -57,13 +58,31 @@ func (ic *ImmichClient) uploadAsset(ctx context.Context, la *assets.Asset, endPo
 		return ar, fmt.Errorf("type file not supported: %s", path.Ext(la.OriginalFileName))
 	}
 
-	f, err := la.OpenFile()
-	if err != nil {
-		return ar, err
+	var (
+		f       io.ReadCloser
+		s       fs.FileInfo
+		err     error
+		trailer http.Header
+	)
+	if la.Checksum == "" && la.IsStreamable() {
+		// The file is read only once: the checksum is computed while the file is sent,
+		// and given to the server in the request's trailer
+		f, err = la.StreamFile()
+		if err != nil {
+			return ar, err
+		}
+		s, err = la.File.Stat()
+		trailer = http.Header{"X-Immich-Checksum": nil}
+	} else {
+		var of osfs.OSFS
+		of, err = la.OpenFile()
+		if err != nil {
+			return ar, err
+		}
+		f = of
+		s, err = of.Stat()
 	}
 	defer f.Close()
-
-	s, err := f.Stat()
 	if err != nil {
 		return ar, err
 	}
@@ -72,10 +91,12 @@ func (ic *ImmichClient) uploadAsset(ctx context.Context, la *assets.Asset, endPo
 	body, pw := io.Pipe()
 	m := multipart.NewWriter(pw)
 
+	done := make(chan struct{})
 	go func() {
 		defer func() {
 			m.Close()
 			pw.Close()
+			close(done)
 		}()
 
 		err = ic.writeMultipartFields(m, callValues)
@@ -87,6 +108,9 @@ func (ic *ImmichClient) uploadAsset(ctx context.Context, la *assets.Asset, endPo
 		if err != nil {
 			return
 		}
+		if trailer != nil && la.Checksum != "" {
+			trailer.Set("X-Immich-Checksum", la.Checksum)
+		}
 
 		if la.FromSideCar != nil && strings.HasSuffix(strings.ToLower(la.FromSideCar.File.Name()), ".xmp") {
 			err = ic.writeSideCarPart(m, la)
@@ -96,15 +120,26 @@ func (ic *ImmichClient) uploadAsset(ctx context.Context, la *assets.Asset, endPo
 		}
 	}()
 
+	var reqBody io.ReadCloser = body
+	if ic.uploadLimiter != nil {
+		reqBody = struct {
+			io.Reader
+			io.Closer
+		}{ic.uploadLimiter.Reader(ctx, body), body}
+	}
+
 	var errCall error
 	switch endPoint {
 	case EndPointAssetUpload:
 		errCall = ic.newServerCall(ctx, EndPointAssetUpload).
-			do(postRequest("/assets", m.FormDataContentType(), setContextValue(callValues), setAcceptJSON(), setImmichChecksum(la), setBody(body)), responseJSON(&ar))
+			do(postRequest("/assets", m.FormDataContentType(), setContextValue(callValues), setAcceptJSON(), setImmichChecksum(la), setTrailer(trailer), setBody(reqBody)), responseJSON(&ar))
 	case EndPointAssetReplace:
 		errCall = ic.newServerCall(ctx, EndPointAssetReplace).
-			do(putRequest("/assets/"+replaceID+"/original", setContextValue(callValues), setAcceptJSON(), setImmichChecksum(la), setContentType(m.FormDataContentType()), setBody(body)), responseJSON(&ar))
+			do(putRequest("/assets/"+replaceID+"/original", setContextValue(callValues), setAcceptJSON(), setImmichChecksum(la), setTrailer(trailer), setContentType(m.FormDataContentType()), setBody(reqBody)), responseJSON(&ar))
 	}
+	// unblock the writer when the body hasn't been read completely, and wait for its end
+	body.Close()
+	<-done
 	if ar.Status == "duplicate" && errors.Is(err, io.ErrClosedPipe) {
 		err = nil // immich closes the connection when we upload the x-immich-checksum header and it finds a duplicate
 	}
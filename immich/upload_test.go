@@ -0,0 +1,79 @@
+package immich
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/filetypes"
+	"github.com/simulot/immich-go/internal/fshelper"
+	"github.com/simulot/immich-go/internal/fshelper/hash"
+)
+
+func TestStreamedUpload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10000)
+	want, _ := hash.Base64Encode(hash.GetSHA1Hash(bytes.NewReader(content)))
+
+	var header, trailer string
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("x-immich-checksum")
+		mr, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if p.FormName() == "assetData" {
+				received, _ = io.ReadAll(p)
+			}
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+		trailer = r.Trailer.Get("x-immich-checksum")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"1234","status":"created"}`))
+	}))
+	defer server.Close()
+
+	ic, err := NewImmichClient(server.URL, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic.supportedMediaTypes = filetypes.DefaultSupportedMedia
+
+	fsys := fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: content}}
+	a := &assets.Asset{
+		File:             fshelper.FSName(fsys, "photo.jpg"),
+		OriginalFileName: "photo.jpg",
+		FileSize:         len(content),
+	}
+
+	ar, err := ic.AssetUpload(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ar.ID != "1234" {
+		t.Errorf("unexpected response: %+v", ar)
+	}
+	if header != "" {
+		t.Errorf("the checksum header must not be sent, got %q", header)
+	}
+	if trailer != want {
+		t.Errorf("trailer checksum = %q, want %q", trailer, want)
+	}
+	if a.Checksum != want {
+		t.Errorf("asset checksum = %q, want %q", a.Checksum, want)
+	}
+	if !bytes.Equal(received, content) {
+		t.Errorf("the server received %d bytes, want %d", len(received), len(content))
+	}
+}
@@ -0,0 +1,313 @@
+// Package afterupload removes or moves the source files once the server has confirmed their upload.
+//
+// The files are touched only when their checksum matches the checksum of the server's asset.
+// Each operation is written into a journal, one JSON object per line, to keep a trace of
+// the moved files and to undo the moves.
+package afterupload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simulot/immich-go/internal/fshelper"
+	"github.com/spf13/cobra"
+)
+
+// Action is what to do with the source files after their upload
+type Action int
+
+const (
+	Keep   Action = iota // the files are left untouched
+	Move                 // the files are moved into a folder
+	Delete               // the files are deleted
+)
+
+// Policy gives the action and the destination folder of moves.
+// It is given as keep, delete or move:<dir>
+// Implement the interface pflag.Value
+type Policy struct {
+	Action Action
+	Dir    string
+}
+
+func (p Policy) String() string {
+	switch p.Action {
+	case Move:
+		return "move:" + p.Dir
+	case Delete:
+		return "delete"
+	}
+	return "keep"
+}
+
+func (p *Policy) Set(v string) error {
+	v = strings.TrimSpace(v)
+	switch {
+	case strings.EqualFold(v, "keep"):
+		*p = Policy{Action: Keep}
+	case strings.EqualFold(v, "delete"):
+		*p = Policy{Action: Delete}
+	case strings.HasPrefix(strings.ToLower(v), "move:"):
+		dir := strings.TrimSpace(v[len("move:"):])
+		if dir == "" {
+			return errors.New("the move action needs a destination folder: move:<dir>")
+		}
+		*p = Policy{Action: Move, Dir: dir}
+	default:
+		return fmt.Errorf("invalid after-upload action: %q, expected keep, delete or move:<dir>", v)
+	}
+	return nil
+}
+
+func (p Policy) Type() string {
+	return "action"
+}
+
+// Options are the parameters of the after-upload processing
+type Options struct {
+	Policy  Policy
+	Journal string // file where the operations are written
+}
+
+// AddFlags adds the after-upload flags to the command
+func AddFlags(cmd *cobra.Command, o *Options) {
+	cmd.Flags().Var(&o.Policy, "after-upload", "What to do with the source files once the server has confirmed their upload: keep, delete or move:<dir>")
+	cmd.Flags().StringVar(&o.Journal, "after-upload-journal", "", "File listing the moved and deleted files (default: next to the log file)")
+}
+
+// CheckSources verifies that the source files can be moved or removed.
+// Zipped archives and read-only folders are refused.
+func (o Options) CheckSources(fsyss []fs.FS) error {
+	if o.Policy.Action == Keep {
+		return nil
+	}
+	for _, fsys := range fsyss {
+		d, ok := fsys.(fshelper.FSOnDisk)
+		if !ok {
+			name := "the source"
+			if n, ok := fsys.(fshelper.NameFS); ok {
+				name = n.Name()
+			}
+			return fmt.Errorf("--after-upload=%s can't be used with %s: only folders of the local file system are accepted", o.Policy, name)
+		}
+		root := d.OSPath(".")
+		f, err := os.CreateTemp(root, ".immich-go-*")
+		if err != nil {
+			return fmt.Errorf("--after-upload=%s can't be used with the read-only folder %s: %w", o.Policy, root, err)
+		}
+		f.Close()
+		_ = os.Remove(f.Name())
+	}
+	if o.Policy.Action == Move {
+		if err := os.MkdirAll(o.Policy.Dir, 0o755); err != nil {
+			return fmt.Errorf("can't create the folder %s: %w", o.Policy.Dir, err)
+		}
+	}
+	return nil
+}
+
+// Entry is a line of the journal
+type Entry struct {
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination,omitempty"`
+	AssetID     string    `json:"assetId,omitempty"`
+}
+
+// Processor moves or removes the files and writes the journal
+type Processor struct {
+	options Options
+	dryRun  bool
+
+	lock    sync.Mutex
+	journal io.WriteCloser
+}
+
+// NewProcessor creates a processor. In dry-run mode, the files are left untouched,
+// but the journal is written.
+func NewProcessor(options Options, dryRun bool) *Processor {
+	return &Processor{
+		options: options,
+		dryRun:  dryRun,
+	}
+}
+
+// Enabled returns true when the files are moved or deleted
+func (p *Processor) Enabled() bool {
+	return p != nil && p.options.Policy.Action != Keep
+}
+
+// Apply moves or removes the files of an asset: the main file first, then its sidecars.
+// It returns the action done.
+func (p *Processor) Apply(assetID string, files ...fshelper.FSAndName) (Action, error) {
+	if !p.Enabled() {
+		return Keep, nil
+	}
+	var errs error
+	for _, f := range files {
+		d, ok := f.FS().(fshelper.FSOnDisk)
+		if !ok {
+			errs = errors.Join(errs, fmt.Errorf("%s isn't a file of the local file system", f.FullName()))
+			continue
+		}
+		src := d.OSPath(f.Name())
+		e := Entry{
+			Time:    time.Now(),
+			Source:  src,
+			AssetID: assetID,
+		}
+		var err error
+		switch p.options.Policy.Action {
+		case Move:
+			e.Action = "move"
+			e.Destination = filepath.Join(p.options.Policy.Dir, filepath.FromSlash(f.Name()))
+			if !p.dryRun {
+				err = moveFile(src, e.Destination)
+			}
+		case Delete:
+			e.Action = "delete"
+			if !p.dryRun {
+				err = os.Remove(src)
+			}
+		}
+		if p.dryRun {
+			e.Action += " (dry-run)"
+		}
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		errs = errors.Join(errs, p.write(e))
+	}
+	return p.options.Policy.Action, errs
+}
+
+// write adds an entry to the journal
+func (p *Processor) write(e Entry) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.journal == nil {
+		if err := os.MkdirAll(filepath.Dir(p.options.Journal), 0o755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(p.options.Journal, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("can't open the after-upload journal: %w", err)
+		}
+		p.journal = f
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = p.journal.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes the journal
+func (p *Processor) Close() error {
+	if p == nil {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.journal == nil {
+		return nil
+	}
+	err := p.journal.Close()
+	p.journal = nil
+	return err
+}
+
+// JournalName returns the name of the journal file
+func (p *Processor) JournalName() string {
+	return p.options.Journal
+}
+
+// moveFile moves the file, copying it when the destination is on another volume.
+// An existing destination is never overwritten.
+func moveFile(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("can't move %s: the file %s already exists", src, dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	// rename fails across volumes
+	if err := copyFile(src, dst); err != nil {
+		_ = os.Remove(dst)
+		return fmt.Errorf("can't move %s: %w", src, err)
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	s, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, s.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	err = errors.Join(err, out.Close())
+	if err == nil && n != s.Size() {
+		err = fmt.Errorf("incomplete copy: %d bytes written, %d expected", n, s.Size())
+	}
+	if err == nil {
+		_ = os.Chtimes(dst, s.ModTime(), s.ModTime())
+	}
+	return err
+}
+
+// Undo moves back the files listed in a journal. Deleted files can't be restored.
+func Undo(journal string, dryRun bool) (int, error) {
+	b, err := os.ReadFile(journal)
+	if err != nil {
+		return 0, err
+	}
+	var errs error
+	n := 0
+	for _, l := range strings.Split(string(b), "\n") {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(l), &e); err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if e.Action != "move" {
+			continue
+		}
+		if !dryRun {
+			if err := moveFile(e.Destination, e.Source); err != nil {
+				errs = errors.Join(errs, err)
+				continue
+			}
+		}
+		n++
+	}
+	return n, errs
+}
@@ -0,0 +1,153 @@
+package afterupload
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/internal/fshelper"
+)
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Policy
+		wantErr bool
+	}{
+		{in: "keep", want: Policy{Action: Keep}},
+		{in: "DELETE", want: Policy{Action: Delete}},
+		{in: "move:/mnt/done", want: Policy{Action: Move, Dir: "/mnt/done"}},
+		{in: "move:", wantErr: true},
+		{in: "erase", wantErr: true},
+	}
+	for _, tt := range tests {
+		var p Policy
+		err := p.Set(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && p != tt.want {
+			t.Errorf("Set(%q) = %+v, want %+v", tt.in, p, tt.want)
+		}
+	}
+}
+
+func makeSource(t *testing.T, files ...string) (string, fs.FS) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fsys, err := fshelper.NewGlobWalkFS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, fsys
+}
+
+func TestMoveAndUndo(t *testing.T) {
+	src, fsys := makeSource(t, "2024/photo.jpg", "2024/photo.jpg.xmp")
+	dst := filepath.Join(t.TempDir(), "done")
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	o := Options{Policy: Policy{Action: Move, Dir: dst}, Journal: journal}
+	if err := o.CheckSources([]fs.FS{fsys}); err != nil {
+		t.Fatal(err)
+	}
+	p := NewProcessor(o, false)
+	_, err := p.Apply("id1", fshelper.FSName(fsys, "2024/photo.jpg"), fshelper.FSName(fsys, "2024/photo.jpg.xmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+
+	for _, f := range []string{"2024/photo.jpg", "2024/photo.jpg.xmp"} {
+		if _, err := os.Stat(filepath.Join(src, f)); !os.IsNotExist(err) {
+			t.Errorf("%s must be moved", f)
+		}
+		if _, err := os.Stat(filepath.Join(dst, f)); err != nil {
+			t.Errorf("%s not found in the destination: %s", f, err)
+		}
+	}
+
+	b, err := os.ReadFile(journal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines in the journal, got %d", len(lines))
+	}
+	var e Entry
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Action != "move" || e.AssetID != "id1" || e.Source != filepath.Join(src, "2024", "photo.jpg") {
+		t.Errorf("unexpected journal entry: %+v", e)
+	}
+
+	n, err := Undo(journal, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 files moved back, got %d", n)
+	}
+	if _, err := os.Stat(filepath.Join(src, "2024", "photo.jpg")); err != nil {
+		t.Errorf("the file isn't restored: %s", err)
+	}
+}
+
+func TestDeleteDryRun(t *testing.T) {
+	src, fsys := makeSource(t, "photo.jpg")
+	journal := filepath.Join(t.TempDir(), "journal.jsonl")
+	p := NewProcessor(Options{Policy: Policy{Action: Delete}, Journal: journal}, true)
+	if _, err := p.Apply("id1", fshelper.FSName(fsys, "photo.jpg")); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	if _, err := os.Stat(filepath.Join(src, "photo.jpg")); err != nil {
+		t.Errorf("the file must be kept in dry-run mode: %s", err)
+	}
+	b, _ := os.ReadFile(journal)
+	if !strings.Contains(string(b), "delete (dry-run)") {
+		t.Errorf("unexpected journal: %s", b)
+	}
+}
+
+func TestMoveNeverOverwrites(t *testing.T) {
+	src, fsys := makeSource(t, "photo.jpg")
+	dst := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dst, "photo.jpg"), []byte("other"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := NewProcessor(Options{Policy: Policy{Action: Move, Dir: dst}, Journal: filepath.Join(t.TempDir(), "j")}, false)
+	if _, err := p.Apply("id1", fshelper.FSName(fsys, "photo.jpg")); err == nil {
+		t.Error("expected an error when the destination exists")
+	}
+	if _, err := os.Stat(filepath.Join(src, "photo.jpg")); err != nil {
+		t.Errorf("the source must be kept: %s", err)
+	}
+}
+
+func TestCheckSourcesRefusesZip(t *testing.T) {
+	o := Options{Policy: Policy{Action: Delete}}
+	if err := o.CheckSources([]fs.FS{fstest.MapFS{}}); err == nil {
+		t.Error("a file system not on the disk must be refused")
+	}
+	o.Policy.Action = Keep
+	if err := o.CheckSources([]fs.FS{fstest.MapFS{}}); err != nil {
+		t.Errorf("keep must accept all sources: %s", err)
+	}
+}
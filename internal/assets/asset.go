@@ -61,6 +61,7 @@ type Asset struct {
 
 	// buffer management
 	cacheReader *cachereader.CacheReader
+	onOSFS      int8 // the file is on the local file system: 0 not checked yet, 1 yes, -1 no
 }
 
 // Kind is the probable type of the image
@@ -34,6 +34,25 @@ func (a *Asset) OpenFile() (osfs.OSFS, error) {
 	return a.cacheReader.OpenFile()
 }
 
+// Prepare reads the asset's file ahead of its use: the content of files that aren't
+// on the local file system is copied into a temporary file, and the checksum is computed.
+// It returns the number of bytes held in the temporary file.
+func (a *Asset) Prepare() (int64, error) {
+	f, err := a.OpenFile()
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	if a.cacheReader.IsTemporary() {
+		if s, err := f.Stat(); err == nil {
+			size = s.Size()
+		}
+	}
+	f.Close()
+	_, err = a.GetChecksum()
+	return size, err
+}
+
 // Close close the temporary file  and close the source
 func (a *Asset) Close() error {
 	if a.cacheReader == nil {
@@ -0,0 +1,72 @@
+package assets
+
+import (
+	"crypto/sha1" // nolint:gosec
+	"encoding/base64"
+	"hash"
+	"io"
+
+	"github.com/simulot/immich-go/internal/fshelper/debugfiles"
+	"github.com/simulot/immich-go/internal/fshelper/osfs"
+)
+
+// IsStreamable returns true when the asset's file isn't on the local file system
+// and hasn't been cached yet. Such a file can be read once, while it is uploaded.
+// The file is opened once to know its file system, the result is kept.
+func (a *Asset) IsStreamable() bool {
+	if a.cacheReader != nil || a.File.FS() == nil {
+		return false
+	}
+	if a.onOSFS == 0 {
+		f, err := a.File.Open()
+		if err != nil {
+			return false
+		}
+		a.onOSFS = -1
+		if _, ok := f.(osfs.OSFS); ok {
+			a.onOSFS = 1
+		}
+		f.Close()
+	}
+	return a.onOSFS < 0
+}
+
+// StreamFile returns a reader on the asset's file that computes the checksum while the file is read.
+// The asset's checksum is set when the end of the file is reached.
+//
+// When the file is already cached, the cached copy is read instead.
+func (a *Asset) StreamFile() (io.ReadCloser, error) {
+	if a.cacheReader != nil {
+		return a.OpenFile()
+	}
+	f, err := a.File.Open()
+	if err != nil {
+		return nil, err
+	}
+	debugfiles.TrackOpenFile(f, a.File.FullName())
+	return &streamReader{
+		rc: f,
+		h:  sha1.New(), // nolint:gosec
+		a:  a,
+	}, nil
+}
+
+type streamReader struct {
+	rc io.ReadCloser
+	h  hash.Hash
+	a  *Asset
+}
+
+func (sr *streamReader) Read(b []byte) (int, error) {
+	n, err := sr.rc.Read(b)
+	sr.h.Write(b[:n])
+	if err == io.EOF && sr.a.Checksum == "" {
+		sr.a.Checksum = base64.StdEncoding.EncodeToString(sr.h.Sum(nil))
+	}
+	return n, err
+}
+
+func (sr *streamReader) Close() error {
+	debugfiles.TrackCloseFile(sr.rc)
+	return sr.rc.Close()
+}
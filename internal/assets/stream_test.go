@@ -0,0 +1,91 @@
+package assets
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/simulot/immich-go/internal/fshelper"
+	"github.com/simulot/immich-go/internal/fshelper/hash"
+	"github.com/simulot/immich-go/internal/fshelper/osfs"
+)
+
+func TestStreamFile(t *testing.T) {
+	content := []byte("not really a jpeg file, but it will do the job")
+	want, _ := hash.Base64Encode(hash.GetSHA1Hash(bytes.NewReader(content)))
+
+	fsys := fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: content}}
+	a := &Asset{File: fshelper.FSName(fsys, "photo.jpg")}
+	if !a.IsStreamable() {
+		t.Fatal("a file from an in-memory file system must be streamable")
+	}
+
+	r, err := a.StreamFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(content) {
+		t.Errorf("unexpected content: %q", b)
+	}
+	if a.Checksum != want {
+		t.Errorf("checksum = %q, want %q", a.Checksum, want)
+	}
+}
+
+func TestStreamFilePartialRead(t *testing.T) {
+	fsys := fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: []byte("0123456789")}}
+	a := &Asset{File: fshelper.FSName(fsys, "photo.jpg")}
+	r, err := a.StreamFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = r.Read(make([]byte, 4))
+	r.Close()
+	if a.Checksum != "" {
+		t.Errorf("the checksum must not be set when the file isn't read completely")
+	}
+}
+
+func TestIsStreamableLocalFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "photo.jpg"), []byte("content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	a := &Asset{File: fshelper.FSName(osfs.DirFS(dir), "photo.jpg")}
+	if a.IsStreamable() {
+		t.Error("a file from the local file system must not be streamed")
+	}
+}
+
+// countingFS counts the opened files
+type countingFS struct {
+	fstest.MapFS
+	opened int
+}
+
+func (fsys *countingFS) Open(name string) (fs.File, error) {
+	fsys.opened++
+	return fsys.MapFS.Open(name)
+}
+
+func TestIsStreamableOpensOnce(t *testing.T) {
+	fsys := &countingFS{MapFS: fstest.MapFS{"photo.jpg": &fstest.MapFile{Data: []byte("content")}}}
+	a := &Asset{File: fshelper.FSName(fsys, "photo.jpg")}
+	for range 3 {
+		if !a.IsStreamable() {
+			t.Fatal("a file from an in-memory file system must be streamable")
+		}
+	}
+	if fsys.opened != 1 {
+		t.Errorf("the file is opened %d times, want 1", fsys.opened)
+	}
+}
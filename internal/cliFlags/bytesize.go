@@ -0,0 +1,98 @@
+package cliflags
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a quantity of bytes given as 500KB, 10MB, 2GB...
+// Units are powers of 1024. A number without unit is a number of bytes.
+// Implement the interface pflag.Value
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	value  int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+func ParseByteSize(s string) (ByteSize, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	if v == "" {
+		return 0, nil
+	}
+	mult := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			mult = u.value
+			v = strings.TrimSpace(strings.TrimSuffix(v, u.suffix))
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return ByteSize(f * float64(mult)), nil
+}
+
+func (b ByteSize) String() string {
+	if b == 0 {
+		return "0"
+	}
+	for _, u := range byteUnits[:4] {
+		if int64(b) >= u.value && int64(b)%u.value == 0 {
+			return fmt.Sprintf("%d%s", int64(b)/u.value, u.suffix)
+		}
+	}
+	return strconv.FormatInt(int64(b), 10)
+}
+
+func (b *ByteSize) Set(s string) error {
+	v, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+func (b ByteSize) Type() string {
+	return "size"
+}
+
+// ByteRate is a bandwidth given as 500KB/s, 5MB/s... The "/s" suffix is optional.
+// Implement the interface pflag.Value
+type ByteRate int64
+
+func (r ByteRate) String() string {
+	if r == 0 {
+		return "0"
+	}
+	return ByteSize(r).String() + "/s"
+}
+
+func (r *ByteRate) Set(s string) error {
+	v := strings.TrimSpace(s)
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "/s"), "/S")
+	b, err := ParseByteSize(v)
+	if err != nil {
+		return fmt.Errorf("invalid rate: %q", s)
+	}
+	*r = ByteRate(b)
+	return nil
+}
+
+func (r ByteRate) Type() string {
+	return "rate"
+}
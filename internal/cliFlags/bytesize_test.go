@@ -0,0 +1,74 @@
+package cliflags
+
+import "testing"
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ByteSize
+		str     string
+		wantErr bool
+	}{
+		{in: "", want: 0, str: "0"},
+		{in: "512", want: 512, str: "512"},
+		{in: "10KB", want: 10 << 10, str: "10KB"},
+		{in: "10k", want: 10 << 10, str: "10KB"},
+		{in: "1.5MB", want: 3 << 19, str: "1536KB"},
+		{in: "2 GB", want: 2 << 30, str: "2GB"},
+		{in: "1TB", want: 1 << 40, str: "1TB"},
+		{in: "12B", want: 12, str: "12"},
+		{in: "abc", wantErr: true},
+		{in: "-1MB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var b ByteSize
+			err := b.Set(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if b != tt.want {
+				t.Errorf("Set(%q) = %d, want %d", tt.in, b, tt.want)
+			}
+			if b.String() != tt.str {
+				t.Errorf("String() = %q, want %q", b.String(), tt.str)
+			}
+		})
+	}
+}
+
+func TestByteRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ByteRate
+		str     string
+		wantErr bool
+	}{
+		{in: "0", want: 0, str: "0"},
+		{in: "5MB/s", want: 5 << 20, str: "5MB/s"},
+		{in: "500KB", want: 500 << 10, str: "500KB/s"},
+		{in: "1g/S", want: 1 << 30, str: "1GB/s"},
+		{in: "fast", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var r ByteRate
+			err := r.Set(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if r != tt.want {
+				t.Errorf("Set(%q) = %d, want %d", tt.in, r, tt.want)
+			}
+			if r.String() != tt.str {
+				t.Errorf("String() = %q, want %q", r.String(), tt.str)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package configuration
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
@@ -11,6 +14,62 @@ type Configuration struct {
 	APIURL    string `json:",omitempty"`
 	ServerURL string `json:",omitempty"`
 	APIKey    string
+	Profiles  map[string]Profile `json:"profiles,omitempty"`
+}
+
+// DefaultProfile is the profile used when none is given
+const DefaultProfile = "default"
+
+// Profile is a named set of connection settings. They are used when the
+// corresponding flags aren't given.
+type Profile struct {
+	Server     string            `json:"server,omitempty"`
+	APIKey     string            `json:"api-key,omitempty"`
+	SkipSSL    bool              `json:"skip-verify-ssl,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Proxy      string            `json:"proxy,omitempty"`
+	CACert     string            `json:"ca-cert,omitempty"`
+	ClientCert string            `json:"client-cert,omitempty"`
+	ClientKey  string            `json:"client-key,omitempty"`
+
+	AccessToken string `json:"access-token,omitempty"` // Session token given by the login command
+	APIKeyID    string `json:"api-key-id,omitempty"`   // ID of the API key minted by the login command, revoked by the logout
+}
+
+// ReadProfile gets the profile from the configuration file. A missing file or profile
+// isn't an error when the default profile is asked.
+func ReadProfile(file, name string) (Profile, error) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	c, err := ConfigRead(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && name == DefaultProfile {
+			return Profile{}, nil
+		}
+		return Profile{}, fmt.Errorf("can't read the profile %q: %w", name, err)
+	}
+	p, ok := c.Profiles[name]
+	if !ok && name != DefaultProfile {
+		return Profile{}, fmt.Errorf("the profile %q isn't in the file %s", name, file)
+	}
+	return p, nil
+}
+
+// WriteProfile saves the profile in the configuration file, keeping the other profiles
+func WriteProfile(file, name string, p Profile) error {
+	if name == "" {
+		name = DefaultProfile
+	}
+	c, err := ConfigRead(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("can't read the configuration file: %w", err)
+	}
+	if c.Profiles == nil {
+		c.Profiles = map[string]Profile{}
+	}
+	c.Profiles[name] = p
+	return c.Write(file)
 }
 
 // DefaultConfigFile return the default configuration file name
@@ -50,7 +109,7 @@ func (c Configuration) Write(name string) error {
 			return err
 		}
 	}
-	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o700)
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o600)
 	if err != nil {
 		return err
 	}
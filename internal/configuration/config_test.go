@@ -0,0 +1,48 @@
+package configuration
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReadProfile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "immich-go.json")
+	c := Configuration{Profiles: map[string]Profile{
+		"work": {Server: "https://photos.example.com", Headers: map[string]string{"CF-Access-Client-Id": "id"}, Proxy: "socks5://localhost:1080"},
+	}}
+	if err := c.Write(file); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := ReadProfile(file, "work")
+	if err != nil || p.Server != "https://photos.example.com" || p.Headers["CF-Access-Client-Id"] != "id" || p.Proxy != "socks5://localhost:1080" {
+		t.Errorf("ReadProfile(work) = %+v, %v", p, err)
+	}
+	if _, err := ReadProfile(file, "home"); err == nil {
+		t.Errorf("ReadProfile(home) should fail")
+	}
+	if p, err := ReadProfile(file, DefaultProfile); err != nil || p.Server != "" {
+		t.Errorf("the missing default profile should be empty: %+v, %v", p, err)
+	}
+	if _, err := ReadProfile(filepath.Join(t.TempDir(), "missing.json"), ""); err != nil {
+		t.Errorf("a missing file is allowed for the default profile: %v", err)
+	}
+}
+
+func TestWriteProfile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sub", "immich-go.json")
+	if err := WriteProfile(file, "", Profile{Server: "https://a", AccessToken: "token"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteProfile(file, "work", Profile{Server: "https://b"}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := ReadProfile(file, "")
+	if err != nil || p.Server != "https://a" || p.AccessToken != "token" {
+		t.Errorf("the default profile isn't kept: %+v, %v", p, err)
+	}
+	p, err = ReadProfile(file, "work")
+	if err != nil || p.Server != "https://b" {
+		t.Errorf("ReadProfile(work) = %+v, %v", p, err)
+	}
+}
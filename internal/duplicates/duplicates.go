@@ -0,0 +1,144 @@
+// Package duplicates finds the server's assets that are copies of each other.
+//
+// Two assets are considered as duplicates when they have:
+//   - the same file name and the same capture date, or
+//   - the same capture date, the same type and the same dimensions.
+//
+// Assets with identical checksums are refused by the server, so the copies come
+// from re-encoded files, edited metadata or imports done by other tools.
+package duplicates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// Group is a set of duplicated assets. The asset to keep is the first one.
+type Group struct {
+	Assets []*immich.Asset
+	Reason string // why the assets are grouped
+}
+
+// Keeper returns the asset to keep
+func (g Group) Keeper() *immich.Asset {
+	return g.Assets[0]
+}
+
+// Others returns the copies of lesser quality
+func (g Group) Others() []*immich.Asset {
+	return g.Assets[1:]
+}
+
+// Trashable returns the copies that can be trashed safely: those having the name and the date
+// of the asset to keep, or the size of its file.
+// The date and the dimensions alone aren't enough: the frames of a burst have the same ones
+// when the camera doesn't record the sub-seconds.
+func (g Group) Trashable() []*immich.Asset {
+	keeper := g.Keeper()
+	l := []*immich.Asset{}
+	for _, a := range g.Others() {
+		if sameNameAndDate(keeper, a) || (keeper.ExifInfo.FileSizeInByte > 0 && keeper.ExifInfo.FileSizeInByte == a.ExifInfo.FileSizeInByte) {
+			l = append(l, a)
+		}
+	}
+	return l
+}
+
+func dateKey(a *immich.Asset) string {
+	return a.ExifInfo.DateTimeOriginal.UTC().Format("2006-01-02T15:04:05.000")
+}
+
+func sameNameAndDate(a, b *immich.Asset) bool {
+	return strings.EqualFold(a.OriginalFileName, b.OriginalFileName) && dateKey(a) == dateKey(b)
+}
+
+const (
+	ReasonNameAndDate      = "same name and date"
+	ReasonDateAndDimension = "same date and dimensions"
+)
+
+// Find groups the duplicated assets and sorts each group with the rule.
+// Trashed assets and assets without capture date are ignored.
+func Find(list []*immich.Asset, rule Rule) []Group {
+	candidates := make([]*immich.Asset, 0, len(list))
+	for _, a := range list {
+		if a.IsTrashed || a.ExifInfo.DateTimeOriginal.IsZero() {
+			continue
+		}
+		candidates = append(candidates, a)
+	}
+
+	parent := make([]int, len(candidates))
+	reason := make([]string, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int, why string) {
+		ri, rj := find(i), find(j)
+		if ri == rj {
+			return
+		}
+		parent[rj] = ri
+		if reason[ri] == "" {
+			reason[ri] = why
+		} else if reason[ri] != why {
+			reason[ri] = ReasonNameAndDate + ", " + ReasonDateAndDimension
+		}
+	}
+
+	byName := map[string]int{}
+	byDimension := map[string]int{}
+	for i, a := range candidates {
+		date := dateKey(a)
+		k := strings.ToLower(a.OriginalFileName) + "|" + date
+		if j, ok := byName[k]; ok {
+			union(j, i, ReasonNameAndDate)
+		} else {
+			byName[k] = i
+		}
+		w, h := a.ExifInfo.ExifImageWidth, a.ExifInfo.ExifImageHeight
+		if w > 0 && h > 0 {
+			if w < h {
+				w, h = h, w // the orientation doesn't matter
+			}
+			k = fmt.Sprintf("%s|%s|%dx%d", a.Type, date, w, h)
+			if j, ok := byDimension[k]; ok {
+				union(j, i, ReasonDateAndDimension)
+			} else {
+				byDimension[k] = i
+			}
+		}
+	}
+
+	members := map[int][]*immich.Asset{}
+	for i, a := range candidates {
+		r := find(i)
+		members[r] = append(members[r], a)
+	}
+	groups := []Group{}
+	for r, l := range members {
+		if len(l) < 2 {
+			continue
+		}
+		rule.Sort(l)
+		groups = append(groups, Group{Assets: l, Reason: reason[r]})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		di, dj := groups[i].Keeper().ExifInfo.DateTimeOriginal, groups[j].Keeper().ExifInfo.DateTimeOriginal
+		if !di.Equal(dj.Time) {
+			return di.Before(dj.Time)
+		}
+		return groups[i].Keeper().ID < groups[j].Keeper().ID
+	})
+	return groups
+}
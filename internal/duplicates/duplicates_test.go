@@ -0,0 +1,124 @@
+package duplicates
+
+import (
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+func asset(id, name string, date time.Time, w, h int, size int64) *immich.Asset {
+	a := &immich.Asset{
+		ID:               id,
+		Type:             "IMAGE",
+		OriginalFileName: name,
+	}
+	a.ExifInfo.DateTimeOriginal = immich.ImmichExifTime{Time: date}
+	a.ExifInfo.ExifImageWidth = w
+	a.ExifInfo.ExifImageHeight = h
+	a.ExifInfo.FileSizeInByte = size
+	return a
+}
+
+func ids(g Group) []string {
+	l := []string{}
+	for _, a := range g.Assets {
+		l = append(l, a.ID)
+	}
+	return l
+}
+
+func TestFind(t *testing.T) {
+	d1 := time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)
+	d2 := time.Date(2023, 7, 14, 10, 0, 1, 0, time.UTC)
+	list := []*immich.Asset{
+		asset("a1", "IMG_0001.JPG", d1, 4000, 3000, 3_000_000),
+		asset("a2", "img_0001.jpg", d1, 2000, 1500, 800_000), // same name, same date, resized
+		asset("a3", "copy.jpg", d1, 3000, 4000, 2_000_000),   // same date, rotated dimensions
+		asset("b1", "IMG_0002.JPG", d2, 4000, 3000, 3_000_000),
+		asset("b2", "IMG_0002.JPG", d2.Add(time.Hour), 4000, 3000, 3_000_000), // another date
+		asset("c1", "IMG_0003.JPG", time.Time{}, 4000, 3000, 3_000_000),       // no date
+		asset("c2", "IMG_0003.JPG", time.Time{}, 4000, 3000, 3_000_000),
+	}
+
+	groups := Find(list, DefaultRule)
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group, got %d", len(groups))
+	}
+	got := ids(groups[0])
+	want := []string{"a1", "a3", "a2"}
+	if len(got) != len(want) {
+		t.Fatalf("group = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("group = %v, want %v", got, want)
+		}
+	}
+	if groups[0].Keeper().ID != "a1" {
+		t.Errorf("keeper = %s, want a1", groups[0].Keeper().ID)
+	}
+}
+
+func TestFindIgnoresTrashed(t *testing.T) {
+	d := time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)
+	list := []*immich.Asset{
+		asset("a1", "IMG_0001.JPG", d, 4000, 3000, 3_000_000),
+		asset("a2", "IMG_0001.JPG", d, 4000, 3000, 2_000_000),
+	}
+	list[1].IsTrashed = true
+	if groups := Find(list, DefaultRule); len(groups) != 0 {
+		t.Errorf("expected no group, got %d", len(groups))
+	}
+}
+
+func TestTrashable(t *testing.T) {
+	d := time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)
+	list := []*immich.Asset{
+		asset("a1", "IMG_0001.JPG", d, 4000, 3000, 3_000_000),
+		asset("a2", "img_0001.jpg", d, 2000, 1500, 800_000),   // same name and date
+		asset("a3", "copy.jpg", d, 3000, 4000, 3_000_000),     // same date, dimensions and size
+		asset("a4", "IMG_0002.JPG", d, 4000, 3000, 2_900_000), // burst frame: same date and dimensions only
+	}
+	groups := Find(list, DefaultRule)
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group, got %d", len(groups))
+	}
+	got := []string{}
+	for _, a := range groups[0].Trashable() {
+		got = append(got, a.ID)
+	}
+	if len(got) != 2 || got[0] != "a3" || got[1] != "a2" {
+		t.Errorf("trashable = %v, want [a3 a2]", got)
+	}
+}
+
+func TestRule(t *testing.T) {
+	d := time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)
+	small := asset("small", "a.jpg", d, 2000, 1500, 900_000)
+	big := asset("big", "a.jpg", d, 4000, 3000, 800_000)
+	small.ExifInfo.Latitude = 48.8
+
+	var r Rule
+	if err := r.Set("size, resolution"); err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != "size,resolution" {
+		t.Errorf("String() = %q", r.String())
+	}
+	if r.Compare(small, big) <= 0 {
+		t.Error("the largest file must win with the rule size")
+	}
+	if DefaultRule.Compare(small, big) >= 0 {
+		t.Error("the largest image must win with the default rule")
+	}
+	if err := r.Set("metadata"); err != nil {
+		t.Fatal(err)
+	}
+	if r.Compare(small, big) <= 0 {
+		t.Error("the asset with GPS data must win with the rule metadata")
+	}
+	if err := r.Set("quality"); err == nil {
+		t.Error("an unknown criterion must be refused")
+	}
+}
@@ -0,0 +1,135 @@
+package duplicates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// Criterion is a quality criterion used to choose the asset to keep
+type Criterion string
+
+const (
+	Resolution Criterion = "resolution" // the largest image
+	Size       Criterion = "size"       // the largest file
+	Metadata   Criterion = "metadata"   // the most complete metadata: date, GPS, camera, description
+	Favorite   Criterion = "favorite"   // the favorite, then the best rated
+	Oldest     Criterion = "oldest"     // the oldest file
+	Newest     Criterion = "newest"     // the most recent file
+)
+
+var criteria = []Criterion{Resolution, Size, Metadata, Favorite, Oldest, Newest}
+
+// Rule is a list of criteria in order of priority.
+// The next criterion is used when the assets are equal for the previous one.
+// Implement the interface pflag.Value
+type Rule []Criterion
+
+// DefaultRule keeps the largest image, then the largest file, then the one with the most metadata
+var DefaultRule = Rule{Resolution, Size, Metadata, Oldest}
+
+func (r Rule) String() string {
+	l := make([]string, len(r))
+	for i, c := range r {
+		l[i] = string(c)
+	}
+	return strings.Join(l, ",")
+}
+
+// Set replaces the rule by the comma separated list of criteria
+func (r *Rule) Set(v string) error {
+	rule := Rule{}
+	for _, p := range strings.Split(v, ",") {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		found := false
+		for _, c := range criteria {
+			if string(c) == p {
+				rule = append(rule, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid quality criterion: %q, expected one of %s", p, Rule(criteria))
+		}
+	}
+	if len(rule) == 0 {
+		return fmt.Errorf("the quality rule is empty")
+	}
+	*r = rule
+	return nil
+}
+
+func (r Rule) Type() string {
+	return "rule"
+}
+
+// Compare returns a positive number when a is better than b, a negative number when b is better,
+// and 0 when they are equal for all criteria
+func (r Rule) Compare(a, b *immich.Asset) int {
+	for _, c := range r {
+		var d int64
+		switch c {
+		case Resolution:
+			d = int64(a.ExifInfo.ExifImageWidth)*int64(a.ExifInfo.ExifImageHeight) - int64(b.ExifInfo.ExifImageWidth)*int64(b.ExifInfo.ExifImageHeight)
+		case Size:
+			d = a.ExifInfo.FileSizeInByte - b.ExifInfo.FileSizeInByte
+		case Metadata:
+			d = int64(metadataScore(a) - metadataScore(b))
+		case Favorite:
+			d = int64(favoriteScore(a) - favoriteScore(b))
+		case Oldest:
+			d = int64(b.FileCreatedAt.Compare(a.FileCreatedAt.Time))
+		case Newest:
+			d = int64(a.FileCreatedAt.Compare(b.FileCreatedAt.Time))
+		}
+		if d > 0 {
+			return 1
+		}
+		if d < 0 {
+			return -1
+		}
+	}
+	return 0
+}
+
+// Sort puts the best asset first. Equal assets are sorted by ID.
+func (r Rule) Sort(l []*immich.Asset) {
+	sort.SliceStable(l, func(i, j int) bool {
+		c := r.Compare(l[i], l[j])
+		if c != 0 {
+			return c > 0
+		}
+		return l[i].ID < l[j].ID
+	})
+}
+
+func metadataScore(a *immich.Asset) int {
+	s := 0
+	if !a.ExifInfo.DateTimeOriginal.IsZero() {
+		s++
+	}
+	if a.ExifInfo.Latitude != 0 || a.ExifInfo.Longitude != 0 {
+		s++
+	}
+	if a.ExifInfo.Make != "" || a.ExifInfo.Model != "" {
+		s++
+	}
+	if a.ExifInfo.Description != "" {
+		s++
+	}
+	return s
+}
+
+func favoriteScore(a *immich.Asset) int {
+	s := a.Rating
+	if a.IsFavorite {
+		s += 10
+	}
+	return s
+}
@@ -0,0 +1,224 @@
+package fakeimmich
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// album is an album of the server
+type album struct {
+	id          string
+	name        string
+	description string
+	assetIDs    []string
+	createdAt   time.Time
+}
+
+func (al *album) has(id string) bool {
+	return slices.Contains(al.assetIDs, id)
+}
+
+func (al *album) remove(id string) {
+	al.assetIDs = slices.DeleteFunc(al.assetIDs, func(a string) bool { return a == id })
+}
+
+// albumDTO is the album as given by the API
+type albumDTO struct {
+	ID          string     `json:"id"`
+	AlbumName   string     `json:"albumName"`
+	Description string     `json:"description"`
+	OwnerID     string     `json:"ownerId"`
+	CreatedAt   string     `json:"createdAt"`
+	Shared      bool       `json:"shared"`
+	AssetCount  int        `json:"assetCount"`
+	Assets      []assetDTO `json:"assets"`
+}
+
+// albumDTO gives the album as sent by the server, the lock is held
+func (s *Server) albumDTO(al *album, withAssets bool) albumDTO {
+	d := albumDTO{
+		ID:          al.id,
+		AlbumName:   al.name,
+		Description: al.description,
+		OwnerID:     s.user.ID,
+		CreatedAt:   formatTime(al.createdAt),
+		AssetCount:  len(al.assetIDs),
+		Assets:      []assetDTO{},
+	}
+	if withAssets {
+		for _, id := range al.assetIDs {
+			d.Assets = append(d.Assets, s.dto(s.assets[id]))
+		}
+	}
+	return d
+}
+
+func (s *Server) routeAlbums(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/albums", s.getAlbums)
+	mux.HandleFunc("GET /api/albums/{id}", s.getAlbum)
+	mux.HandleFunc("POST /api/albums", s.createAlbum)
+	mux.HandleFunc("PUT /api/albums/{id}/assets", s.addAlbumAssets)
+	mux.HandleFunc("PATCH /api/albums/{id}", s.updateAlbum)
+	mux.HandleFunc("DELETE /api/albums/{id}", s.deleteAlbum)
+}
+
+func (s *Server) getAlbums(w http.ResponseWriter, r *http.Request) {
+	assetID := r.URL.Query().Get("assetId")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	list := []albumDTO{}
+	for _, al := range s.sortedAlbums() {
+		if assetID != "" && !al.has(assetID) {
+			continue
+		}
+		list = append(list, s.albumDTO(al, false))
+	}
+	reply(w, http.StatusOK, list)
+}
+
+// sortedAlbums gives the albums by creation order, the lock is held
+func (s *Server) sortedAlbums() []*album {
+	list := make([]*album, 0, len(s.albums))
+	for _, al := range s.albums {
+		list = append(list, al)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].createdAt.Equal(list[j].createdAt) {
+			return list[i].createdAt.Before(list[j].createdAt)
+		}
+		return list[i].id < list[j].id
+	})
+	return list
+}
+
+func (s *Server) getAlbum(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	al, ok := s.albums[r.PathValue("id")]
+	if !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no album.read access")
+		return
+	}
+	reply(w, http.StatusOK, s.albumDTO(al, r.URL.Query().Get("withoutAssets") != "true"))
+}
+
+func (s *Server) createAlbum(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AlbumName   string   `json:"albumName"`
+		Description string   `json:"description"`
+		AssetIDs    []string `json:"assetIds"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.AlbumName == "" {
+		replyError(w, http.StatusBadRequest, "albumName should not be empty")
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	al := &album{id: uuid.NewString(), name: req.AlbumName, description: req.Description, createdAt: time.Now()}
+	for _, id := range req.AssetIDs {
+		if _, ok := s.assets[id]; ok && !al.has(id) {
+			al.assetIDs = append(al.assetIDs, id)
+		}
+	}
+	s.albums[al.id] = al
+	reply(w, http.StatusCreated, s.albumDTO(al, true))
+}
+
+// addAlbumAssets adds the assets to the album, the result tells for each asset if it was added
+func (s *Server) addAlbumAssets(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	type result struct {
+		ID      string `json:"id"`
+		Success bool   `json:"success"`
+		Error   string `json:"error,omitempty"`
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	al, ok := s.albums[r.PathValue("id")]
+	if !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no album.addAsset access")
+		return
+	}
+	results := []result{}
+	for _, id := range req.IDs {
+		switch {
+		case al.has(id):
+			results = append(results, result{ID: id, Error: "duplicate"})
+		case s.assets[id] == nil:
+			results = append(results, result{ID: id, Error: "not_found"})
+		default:
+			al.assetIDs = append(al.assetIDs, id)
+			results = append(results, result{ID: id, Success: true})
+		}
+	}
+	reply(w, http.StatusOK, results)
+}
+
+func (s *Server) updateAlbum(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AlbumName   *string `json:"albumName"`
+		Description *string `json:"description"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	al, ok := s.albums[r.PathValue("id")]
+	if !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no album.update access")
+		return
+	}
+	setIf(&al.name, req.AlbumName)
+	setIf(&al.description, req.Description)
+	reply(w, http.StatusOK, s.albumDTO(al, false))
+}
+
+func (s *Server) deleteAlbum(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	id := r.PathValue("id")
+	if _, ok := s.albums[id]; !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no album.delete access")
+		return
+	}
+	delete(s.albums, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddAlbum creates an album on the server, as if created before the test. It returns the album's ID.
+func (s *Server) AddAlbum(name string, assetIDs ...string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	al := &album{id: uuid.NewString(), name: name, assetIDs: assetIDs, createdAt: time.Now()}
+	s.albums[al.id] = al
+	return al.id
+}
+
+// Albums gives the file names of the assets of each album, by album name
+func (s *Server) Albums() map[string][]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	albums := map[string][]string{}
+	for _, al := range s.albums {
+		names := []string{}
+		for _, id := range al.assetIDs {
+			names = append(names, s.assets[id].fileName)
+		}
+		sort.Strings(names)
+		albums[al.name] = append(albums[al.name], names...)
+	}
+	return albums
+}
@@ -0,0 +1,810 @@
+package fakeimmich
+
+import (
+	"crypto/sha1" // nolint:gosec
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/filetypes"
+)
+
+// asset is an asset of the server
+type asset struct {
+	id               string
+	deviceAssetID    string
+	deviceID         string
+	fileName         string
+	assetType        string // IMAGE or VIDEO
+	checksum         string // base64 encoded SHA1 of the content
+	content          []byte
+	size             int64 // size of the file when the content is a placeholder, 0 for the size of the content
+	sidecar          []byte
+	fileCreatedAt    time.Time
+	fileModifiedAt   time.Time
+	updatedAt        time.Time
+	dateTimeOriginal time.Time
+	isFavorite       bool
+	isArchived       bool
+	isTrashed        bool
+	rating           int
+	description      string
+	latitude         float64
+	longitude        float64
+	stackID          string
+	tags             map[string]bool // IDs of the tags
+	width            int             // dimensions of the image, 0 when unknown
+	height           int
+	hidden           bool // motion part of a live photo, not visible in the timeline
+}
+
+type exifDTO struct {
+	ExifImageWidth   int     `json:"exifImageWidth,omitempty"`
+	ExifImageHeight  int     `json:"exifImageHeight,omitempty"`
+	FileSizeInByte   int64   `json:"fileSizeInByte"`
+	DateTimeOriginal string  `json:"dateTimeOriginal,omitempty"`
+	Latitude         float64 `json:"latitude,omitempty"`
+	Longitude        float64 `json:"longitude,omitempty"`
+	Description      string  `json:"description"`
+}
+
+type stackDTO struct {
+	ID             string `json:"id"`
+	PrimaryAssetID string `json:"primaryAssetId"`
+	AssetCount     int    `json:"assetCount"`
+}
+
+// assetDTO is the asset as given by the API
+type assetDTO struct {
+	ID               string                 `json:"id"`
+	DeviceAssetID    string                 `json:"deviceAssetId"`
+	OwnerID          string                 `json:"ownerId"`
+	DeviceID         string                 `json:"deviceId"`
+	Type             string                 `json:"type"`
+	OriginalPath     string                 `json:"originalPath"`
+	OriginalFileName string                 `json:"originalFileName"`
+	FileCreatedAt    string                 `json:"fileCreatedAt"`
+	FileModifiedAt   string                 `json:"fileModifiedAt"`
+	UpdatedAt        string                 `json:"updatedAt"`
+	IsFavorite       bool                   `json:"isFavorite"`
+	IsArchived       bool                   `json:"isArchived"`
+	IsTrashed        bool                   `json:"isTrashed"`
+	Duration         string                 `json:"duration"`
+	Rating           int                    `json:"rating"`
+	ExifInfo         exifDTO                `json:"exifInfo"`
+	Checksum         string                 `json:"checksum"`
+	StackParentID    string                 `json:"stackParentId,omitempty"`
+	Stack            *stackDTO              `json:"stack,omitempty"`
+	Tags             []immich.TagSimplified `json:"tags"`
+}
+
+// dto gives the asset as sent by the server, the lock is held
+func (s *Server) dto(a *asset) assetDTO {
+	d := assetDTO{
+		ID:               a.id,
+		DeviceAssetID:    a.deviceAssetID,
+		OwnerID:          s.user.ID,
+		DeviceID:         a.deviceID,
+		Type:             a.assetType,
+		OriginalPath:     "upload/library/" + s.user.ID + "/" + a.fileName,
+		OriginalFileName: a.fileName,
+		FileCreatedAt:    formatTime(a.fileCreatedAt),
+		FileModifiedAt:   formatTime(a.fileModifiedAt),
+		UpdatedAt:        formatTime(a.updatedAt),
+		IsFavorite:       a.isFavorite,
+		IsArchived:       a.isArchived,
+		IsTrashed:        a.isTrashed,
+		Duration:         "0:00:00.00000",
+		Rating:           a.rating,
+		Checksum:         a.checksum,
+		ExifInfo: exifDTO{
+			ExifImageWidth:  a.width,
+			ExifImageHeight: a.height,
+			FileSizeInByte:  a.fileSize(),
+			Latitude:        a.latitude,
+			Longitude:       a.longitude,
+			Description:     a.description,
+		},
+		Tags: []immich.TagSimplified{},
+	}
+	if !a.dateTimeOriginal.IsZero() {
+		d.ExifInfo.DateTimeOriginal = a.dateTimeOriginal.UTC().Format("2006-01-02T15:04:05.000+00:00")
+	}
+	if st, ok := s.stacks[a.stackID]; ok {
+		d.Stack = &stackDTO{ID: st.id, PrimaryAssetID: st.assetIDs[0], AssetCount: len(st.assetIDs)}
+		if st.assetIDs[0] != a.id {
+			d.StackParentID = st.assetIDs[0]
+		}
+	}
+	for id := range a.tags {
+		if t, ok := s.tags[id]; ok {
+			d.Tags = append(d.Tags, t.simplified())
+		}
+	}
+	sort.Slice(d.Tags, func(i, j int) bool { return d.Tags[i].Value < d.Tags[j].Value })
+	return d
+}
+
+// fileSize gives the size of the asset's file
+func (a *asset) fileSize() int64 {
+	if a.size > 0 {
+		return a.size
+	}
+	return int64(len(a.content))
+}
+
+// Checksum gives the checksum of the content, as computed by the server
+func Checksum(content []byte) string {
+	h := sha1.Sum(content) // nolint:gosec
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// ChecksumOf gives the checksum of the content read from r, as computed by the server
+func ChecksumOf(r io.Reader) (string, error) {
+	h := sha1.New() // nolint:gosec
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// byChecksum finds the asset having the checksum, the lock is held
+func (s *Server) byChecksum(checksum string) *asset {
+	for _, a := range s.assets {
+		if a.checksum == checksum {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Server) routeAssets(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/assets", s.uploadAsset)
+	mux.HandleFunc("PUT /api/assets/{id}/original", s.replaceAsset)
+	mux.HandleFunc("POST /api/assets/bulk-upload-check", s.bulkUploadCheck)
+	mux.HandleFunc("POST /api/search/metadata", s.searchMetadata)
+	mux.HandleFunc("GET /api/assets/statistics", s.assetStatistics)
+	mux.HandleFunc("GET /api/assets/{id}", s.getAsset)
+	mux.HandleFunc("GET /api/assets/{id}/original", s.downloadAsset)
+	mux.HandleFunc("PUT /api/assets/{id}", s.updateAsset)
+	mux.HandleFunc("PUT /api/assets", s.updateAssets)
+	mux.HandleFunc("DELETE /api/assets", s.deleteAssets)
+	mux.HandleFunc("POST /api/stacks", s.createStack)
+}
+
+// upload is the content of an upload form
+type upload struct {
+	fields   map[string]string
+	fileName string
+	content  []byte
+	sidecar  []byte
+}
+
+// readUpload reads the multipart form of an upload. It answers 413 when the upload is too large.
+func (s *Server) readUpload(w http.ResponseWriter, r *http.Request) (*upload, bool) {
+	if s.maxUploadSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadSize)
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		replyError(w, http.StatusBadRequest, "multipart/form-data expected")
+		return nil, false
+	}
+	u := &upload{fields: map[string]string{}}
+	mr, err := r.MultipartReader()
+	if err == nil {
+		for {
+			part, perr := mr.NextPart()
+			if errors.Is(perr, io.EOF) {
+				break
+			}
+			if perr != nil {
+				err = perr
+				break
+			}
+			b, rerr := io.ReadAll(part)
+			if rerr != nil {
+				err = rerr
+				break
+			}
+			switch part.FormName() {
+			case "assetData":
+				u.fileName, u.content = part.FileName(), b
+			case "sidecarData":
+				u.sidecar = b
+			default:
+				u.fields[part.FormName()] = string(b)
+			}
+		}
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			replyError(w, http.StatusRequestEntityTooLarge, "request entity too large")
+			return nil, false
+		}
+		replyError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if u.fileName == "" {
+		replyError(w, http.StatusBadRequest, "the assetData file is missing")
+		return nil, false
+	}
+	if !filetypes.DefaultSupportedMedia.IsMedia(strings.ToLower(path.Ext(u.fileName))) {
+		replyError(w, http.StatusBadRequest, "Unsupported file type "+u.fileName)
+		return nil, false
+	}
+	return u, true
+}
+
+func parseFormTime(s string) time.Time {
+	t, _ := time.Parse(immich.TimeFormat, s)
+	return t
+}
+
+func (s *Server) uploadAsset(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.readUpload(w, r)
+	if !ok {
+		return
+	}
+	checksum := Checksum(u.content)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if dup := s.byChecksum(checksum); dup != nil {
+		reply(w, http.StatusOK, immich.AssetResponse{ID: dup.id, Status: immich.UploadDuplicate})
+		return
+	}
+	a := &asset{
+		id:             uuid.NewString(),
+		deviceAssetID:  u.fields["deviceAssetId"],
+		deviceID:       u.fields["deviceId"],
+		fileName:       u.fileName,
+		assetType:      strings.ToUpper(filetypes.DefaultSupportedMedia.TypeFromName(u.fileName)),
+		checksum:       checksum,
+		content:        u.content,
+		sidecar:        u.sidecar,
+		fileCreatedAt:  parseFormTime(u.fields["fileCreatedAt"]),
+		fileModifiedAt: parseFormTime(u.fields["fileModifiedAt"]),
+		updatedAt:      time.Now(),
+		isFavorite:     u.fields["isFavorite"] == "true",
+		isArchived:     u.fields["isArchived"] == "true",
+		tags:           map[string]bool{},
+	}
+	a.dateTimeOriginal = a.fileCreatedAt
+	s.assets[a.id] = a
+	reply(w, http.StatusCreated, immich.AssetResponse{ID: a.id, Status: immich.UploadCreated})
+}
+
+func (s *Server) replaceAsset(w http.ResponseWriter, r *http.Request) {
+	u, ok := s.readUpload(w, r)
+	if !ok {
+		return
+	}
+	checksum := Checksum(u.content)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	a, ok := s.assets[r.PathValue("id")]
+	if !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no asset.update access")
+		return
+	}
+	if dup := s.byChecksum(checksum); dup != nil {
+		reply(w, http.StatusOK, immich.AssetResponse{ID: dup.id, Status: immich.UploadDuplicate})
+		return
+	}
+	a.fileName, a.content, a.checksum, a.sidecar = u.fileName, u.content, checksum, u.sidecar
+	a.size = 0
+	a.fileModifiedAt = parseFormTime(u.fields["fileModifiedAt"])
+	a.updatedAt = time.Now()
+	reply(w, http.StatusOK, immich.AssetResponse{ID: a.id, Status: immich.UploadReplaced})
+}
+
+func (s *Server) bulkUploadCheck(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Assets []struct {
+			ID       string `json:"id"`
+			Checksum string `json:"checksum"`
+		} `json:"assets"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	type result struct {
+		ID        string `json:"id"`
+		Action    string `json:"action"`
+		Reason    string `json:"reason,omitempty"`
+		AssetID   string `json:"assetId,omitempty"`
+		IsTrashed bool   `json:"isTrashed,omitempty"`
+	}
+	resp := struct {
+		Results []result `json:"results"`
+	}{Results: []result{}}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, c := range req.Assets {
+		// the checksum is given in base64 or in hexadecimal
+		checksum := c.Checksum
+		if len(checksum) == 40 {
+			if b, err := hexDecode(checksum); err == nil {
+				checksum = base64.StdEncoding.EncodeToString(b)
+			}
+		}
+		if dup := s.byChecksum(checksum); dup != nil {
+			resp.Results = append(resp.Results, result{ID: c.ID, Action: "reject", Reason: "duplicate", AssetID: dup.id, IsTrashed: dup.isTrashed})
+			continue
+		}
+		resp.Results = append(resp.Results, result{ID: c.ID, Action: "accept"})
+	}
+	reply(w, http.StatusOK, resp)
+}
+
+func hexDecode(s string) ([]byte, error) {
+	b := make([]byte, len(s)/2)
+	for i := range b {
+		v, err := strconv.ParseUint(s[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		b[i] = byte(v)
+	}
+	return b, nil
+}
+
+func (s *Server) searchMetadata(w http.ResponseWriter, r *http.Request) {
+	var q immich.SearchMetadataQuery
+	if !decode(w, r, &q) {
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.Size < 1 {
+		q.Size = 250
+	}
+	takenBefore, _ := time.Parse(time.RFC3339, q.TakenBefore)
+	takenAfter, _ := time.Parse(time.RFC3339, q.TakenAfter)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	list := []*asset{}
+	for _, a := range s.assets {
+		switch {
+		case a.isTrashed && !q.WithDeleted,
+			a.hidden && q.IsVisible,
+			q.Checksum != "" && a.checksum != q.Checksum,
+			q.OriginalFileName != "" && !strings.Contains(strings.ToLower(a.fileName), strings.ToLower(q.OriginalFileName)),
+			!takenBefore.IsZero() && !a.fileCreatedAt.Before(takenBefore),
+			!takenAfter.IsZero() && a.fileCreatedAt.Before(takenAfter),
+			!s.inAlbums(a, q.AlbumIDs),
+			!s.hasTags(a, q.TagIDs):
+			continue
+		}
+		list = append(list, a)
+	}
+	// the most recent first, like the server
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].fileCreatedAt.Equal(list[j].fileCreatedAt) {
+			return list[i].fileCreatedAt.After(list[j].fileCreatedAt)
+		}
+		return list[i].id < list[j].id
+	})
+
+	start := min((q.Page-1)*q.Size, len(list))
+	end := min(start+q.Size, len(list))
+	items := make([]assetDTO, 0, end-start)
+	for _, a := range list[start:end] {
+		items = append(items, s.dto(a))
+	}
+	var nextPage *string
+	if end < len(list) {
+		p := strconv.Itoa(q.Page + 1)
+		nextPage = &p
+	}
+	resp := map[string]any{
+		"assets": map[string]any{
+			"total":    len(items),
+			"count":    len(items),
+			"items":    items,
+			"facets":   []any{},
+			"nextPage": nextPage,
+		},
+		"albums": map[string]any{"total": 0, "count": 0, "items": []any{}, "facets": []any{}, "nextPage": nil},
+	}
+	reply(w, http.StatusOK, resp)
+}
+
+func (s *Server) inAlbums(a *asset, albumIDs []string) bool {
+	for _, id := range albumIDs {
+		al, ok := s.albums[id]
+		if !ok || !al.has(a.id) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTags tells if the asset has the tags, or one of their children like the server. The lock is held.
+func (s *Server) hasTags(a *asset, tagIDs []string) bool {
+	for _, id := range tagIDs {
+		t, ok := s.tags[id]
+		if !ok {
+			return false
+		}
+		found := false
+		for aid := range a.tags {
+			if at, ok := s.tags[aid]; ok && (at.id == t.id || strings.HasPrefix(at.value, t.value+"/")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) assetStatistics(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := immich.UserStatistics{}
+	for _, a := range s.assets {
+		if a.isTrashed {
+			continue
+		}
+		if a.assetType == "VIDEO" {
+			st.Videos++
+		} else {
+			st.Images++
+		}
+		st.Total++
+	}
+	reply(w, http.StatusOK, st)
+}
+
+func (s *Server) getAsset(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	a, ok := s.assets[r.PathValue("id")]
+	if !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no asset.read access")
+		return
+	}
+	reply(w, http.StatusOK, s.dto(a))
+}
+
+func (s *Server) downloadAsset(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	a, ok := s.assets[r.PathValue("id")]
+	var content []byte
+	if ok {
+		content = a.content
+	}
+	s.lock.Unlock()
+	if !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no asset.download access")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	_, _ = w.Write(content)
+}
+
+func (s *Server) updateAsset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IsArchived       *bool      `json:"isArchived"`
+		IsFavorite       *bool      `json:"isFavorite"`
+		Latitude         *float64   `json:"latitude"`
+		Longitude        *float64   `json:"longitude"`
+		Description      *string    `json:"description"`
+		Rating           *int       `json:"rating"`
+		DateTimeOriginal *time.Time `json:"dateTimeOriginal"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	a, ok := s.assets[r.PathValue("id")]
+	if !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no asset.update access")
+		return
+	}
+	setIf(&a.isArchived, req.IsArchived)
+	setIf(&a.isFavorite, req.IsFavorite)
+	setIf(&a.latitude, req.Latitude)
+	setIf(&a.longitude, req.Longitude)
+	setIf(&a.description, req.Description)
+	setIf(&a.rating, req.Rating)
+	setIf(&a.dateTimeOriginal, req.DateTimeOriginal)
+	a.updatedAt = time.Now()
+	reply(w, http.StatusOK, s.dto(a))
+}
+
+func setIf[T any](v *T, p *T) {
+	if p != nil {
+		*v = *p
+	}
+}
+
+// updateAssets updates the fields given for all the assets.
+// The legacy stacks are made by giving the cover as stackParentId of the other assets.
+func (s *Server) updateAssets(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs           []string `json:"ids"`
+		IsArchived    *bool    `json:"isArchived"`
+		IsFavorite    *bool    `json:"isFavorite"`
+		Latitude      *float64 `json:"latitude"`
+		Longitude     *float64 `json:"longitude"`
+		RemoveParent  bool     `json:"removeParent"`
+		StackParentID string   `json:"stackParentId"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, id := range req.IDs {
+		if _, ok := s.assets[id]; !ok {
+			replyError(w, http.StatusBadRequest, "Not found or no asset.update access")
+			return
+		}
+	}
+	for _, id := range req.IDs {
+		a := s.assets[id]
+		setIf(&a.isArchived, req.IsArchived)
+		setIf(&a.isFavorite, req.IsFavorite)
+		setIf(&a.latitude, req.Latitude)
+		setIf(&a.longitude, req.Longitude)
+		if req.RemoveParent {
+			s.unstack(a)
+		}
+		a.updatedAt = time.Now()
+	}
+	if req.StackParentID != "" {
+		if _, ok := s.assets[req.StackParentID]; !ok {
+			replyError(w, http.StatusBadRequest, "Not found or no asset.update access")
+			return
+		}
+		s.stack(append([]string{req.StackParentID}, req.IDs...))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteAssets(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IDs   []string `json:"ids"`
+		Force bool     `json:"force"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, id := range req.IDs {
+		a, ok := s.assets[id]
+		if !ok {
+			continue
+		}
+		if !req.Force {
+			a.isTrashed = true
+			continue
+		}
+		s.unstack(a)
+		for _, al := range s.albums {
+			al.remove(id)
+		}
+		delete(s.assets, id)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// stack groups assets, the first one is the cover. The lock is held.
+type stack struct {
+	id       string
+	assetIDs []string
+}
+
+func (s *Server) stack(ids []string) *stack {
+	for _, id := range ids {
+		s.unstack(s.assets[id])
+	}
+	st := &stack{id: uuid.NewString(), assetIDs: ids}
+	s.stacks[st.id] = st
+	for _, id := range ids {
+		s.assets[id].stackID = st.id
+	}
+	return st
+}
+
+// unstack removes the asset from its stack, the lock is held
+func (s *Server) unstack(a *asset) {
+	st, ok := s.stacks[a.stackID]
+	a.stackID = ""
+	if !ok {
+		return
+	}
+	for i, id := range st.assetIDs {
+		if id == a.id {
+			st.assetIDs = append(st.assetIDs[:i:i], st.assetIDs[i+1:]...)
+			break
+		}
+	}
+	if len(st.assetIDs) < 2 {
+		for _, id := range st.assetIDs {
+			s.assets[id].stackID = ""
+		}
+		delete(s.stacks, st.id)
+	}
+}
+
+func (s *Server) createStack(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AssetIDs []string `json:"assetIds"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if len(req.AssetIDs) < 2 {
+		replyError(w, http.StatusBadRequest, "assetIds must contain at least 2 elements")
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, id := range req.AssetIDs {
+		if _, ok := s.assets[id]; !ok {
+			replyError(w, http.StatusBadRequest, "Not found or no asset.update access")
+			return
+		}
+	}
+	st := s.stack(req.AssetIDs)
+	assets := []assetDTO{}
+	for _, id := range st.assetIDs {
+		assets = append(assets, s.dto(s.assets[id]))
+	}
+	reply(w, http.StatusCreated, map[string]any{"id": st.id, "primaryAssetId": st.assetIDs[0], "assets": assets})
+}
+
+// AddAsset puts an asset on the server, as if uploaded before the test. It returns the asset's ID.
+func (s *Server) AddAsset(fileName string, content []byte, captureDate time.Time) string {
+	return s.addAsset(fileName, content, Checksum(content), int64(len(content)), captureDate)
+}
+
+// AddAssetWithChecksum puts an asset without content on the server, known by its checksum and size.
+func (s *Server) AddAssetWithChecksum(fileName string, checksum string, size int64, captureDate time.Time) string {
+	return s.addAsset(fileName, nil, checksum, size, captureDate)
+}
+
+func (s *Server) addAsset(fileName string, content []byte, checksum string, size int64, captureDate time.Time) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	a := &asset{
+		id:               uuid.NewString(),
+		deviceAssetID:    path.Base(fileName) + "-" + strconv.FormatInt(size, 10),
+		deviceID:         "fake",
+		fileName:         fileName,
+		assetType:        strings.ToUpper(filetypes.DefaultSupportedMedia.TypeFromName(fileName)),
+		checksum:         checksum,
+		content:          content,
+		size:             size,
+		fileCreatedAt:    captureDate,
+		fileModifiedAt:   captureDate,
+		dateTimeOriginal: captureDate,
+		updatedAt:        time.Now(),
+		tags:             map[string]bool{},
+	}
+	s.assets[a.id] = a
+	return a.id
+}
+
+// SetDimensions sets the dimensions of the image given in its EXIF information
+func (s *Server) SetDimensions(id string, width, height int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if a, ok := s.assets[id]; ok {
+		a.width, a.height = width, height
+	}
+}
+
+// SetHidden hides the asset from the timeline, like the motion part of a live photo
+func (s *Server) SetHidden(id string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if a, ok := s.assets[id]; ok {
+		a.hidden = true
+	}
+}
+
+// Asset is the state of an asset, for the assertions
+type Asset struct {
+	ID          string
+	FileName    string
+	Checksum    string
+	Size        int
+	CaptureDate time.Time
+	Favorite    bool
+	Archived    bool
+	Trashed     bool
+	Rating      int
+	Description string
+	Latitude    float64
+	Longitude   float64
+	HasSidecar  bool
+	Albums      []string // Names of the albums
+	Tags        []string // Values of the tags
+	StackCover  string   // File name of the stack's cover, empty out of a stack
+}
+
+// Assets lists the assets of the server, sorted by file name
+func (s *Server) Assets() []Asset {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	list := make([]Asset, 0, len(s.assets))
+	for _, a := range s.assets {
+		list = append(list, s.inspect(a))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].FileName != list[j].FileName {
+			return list[i].FileName < list[j].FileName
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Asset gives the state of the asset with the ID
+func (s *Server) Asset(id string) (Asset, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	a, ok := s.assets[id]
+	if !ok {
+		return Asset{}, false
+	}
+	return s.inspect(a), true
+}
+
+func (s *Server) inspect(a *asset) Asset {
+	r := Asset{
+		ID:          a.id,
+		FileName:    a.fileName,
+		Checksum:    a.checksum,
+		Size:        int(a.fileSize()),
+		CaptureDate: a.dateTimeOriginal,
+		Favorite:    a.isFavorite,
+		Archived:    a.isArchived,
+		Trashed:     a.isTrashed,
+		Rating:      a.rating,
+		Description: a.description,
+		Latitude:    a.latitude,
+		Longitude:   a.longitude,
+		HasSidecar:  a.sidecar != nil,
+	}
+	for _, al := range s.albums {
+		if al.has(a.id) {
+			r.Albums = append(r.Albums, al.name)
+		}
+	}
+	sort.Strings(r.Albums)
+	for id := range a.tags {
+		if t, ok := s.tags[id]; ok {
+			r.Tags = append(r.Tags, t.value)
+		}
+	}
+	sort.Strings(r.Tags)
+	if st, ok := s.stacks[a.stackID]; ok {
+		r.StackCover = s.assets[st.assetIDs[0]].fileName
+	}
+	return r
+}
@@ -0,0 +1,69 @@
+// Package cmdtest runs the commands of immich-go against the fake Immich server in the tests.
+package cmdtest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/simulot/immich-go/app"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+	"github.com/spf13/cobra"
+)
+
+// Command makes the command to run, like NewSessionCommand
+type Command func(ctx context.Context, a *app.Application) *cobra.Command
+
+// NewServer starts a fake server, closed at the end of the test
+func NewServer(t testing.TB, options ...fakeimmich.Option) *fakeimmich.Server {
+	t.Helper()
+	s := fakeimmich.NewServer(options...)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// NewApp gives an application without log
+func NewApp() *app.Application {
+	return app.NewWithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// Run runs the command with the arguments against the server, with an empty standard input.
+// It gives the output of the command and its messages.
+func Run(s *fakeimmich.Server, newCmd Command, args ...string) (string, error) {
+	return RunApp(NewApp(), s, newCmd, args...)
+}
+
+// RunApp is Run with the application a, to inspect its journal after the run
+func RunApp(a *app.Application, s *fakeimmich.Server, newCmd Command, args ...string) (string, error) {
+	ctx := context.Background()
+	out := &output{}
+	a.Log().SetMessageWriter(out)
+	cmd := newCmd(ctx, a)
+	cmd.SetOut(out)
+	cmd.SetErr(io.Discard)
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetArgs(append(args, "--server", s.URL, "--api-key", s.APIKey))
+	err := cmd.ExecuteContext(ctx)
+	return out.String(), err
+}
+
+// output collects what the command prints, from any goroutine
+type output struct {
+	lock sync.Mutex
+	sb   strings.Builder
+}
+
+func (o *output) Write(b []byte) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.sb.Write(b)
+}
+
+func (o *output) String() string {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.sb.String()
+}
@@ -0,0 +1,73 @@
+package fakeimmich
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+func (s *Server) routeJobs(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/jobs", s.getJobs)
+	mux.HandleFunc("PUT /api/jobs/{id}", s.sendJobCommand)
+	mux.HandleFunc("POST /api/jobs", s.createJob)
+}
+
+func (s *Server) getJobs(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	reply(w, http.StatusOK, s.jobs)
+}
+
+// sendJobCommand pauses or resumes a queue. The other commands are accepted without effect.
+func (s *Server) sendJobCommand(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Command immich.JobCommand `json:"command"`
+		Force   bool              `json:"force"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	j, ok := s.jobs[r.PathValue("id")]
+	if !ok {
+		replyError(w, http.StatusBadRequest, "invalid job name")
+		return
+	}
+	switch req.Command {
+	case immich.Pause:
+		j.QueueStatus.IsPaused = true
+	case immich.Resume:
+		j.QueueStatus.IsPaused = false
+	case immich.Start, immich.Empty, immich.ClearFailed:
+	default:
+		replyError(w, http.StatusBadRequest, "invalid job command")
+		return
+	}
+	reply(w, http.StatusOK, j)
+}
+
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name immich.JobName `json:"name"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PausedJobs lists the paused queues
+func (s *Server) PausedJobs() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	list := []string{}
+	for id, j := range s.jobs {
+		if j.QueueStatus.IsPaused {
+			list = append(list, id)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
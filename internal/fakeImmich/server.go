@@ -0,0 +1,277 @@
+// Package fakeimmich is an in-process Immich server, keeping its state in memory.
+//
+// It implements the endpoints used by immich-go, with the duplicate semantics of the real server:
+// an asset is a duplicate of another one of the user having the same checksum, even when it's in the trash.
+// The inspection helpers give the state of the server for the assertions, and the faults change its answers
+// to test the error paths.
+package fakeimmich
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/filetypes"
+)
+
+// Server is a fake Immich server listening on a local address
+type Server struct {
+	*httptest.Server
+	APIKey      string // API key accepted by the server
+	AccessToken string // session token accepted in the header "Authorization: Bearer <token>"
+
+	version       string
+	maxUploadSize int64
+	user          immich.User
+
+	lock   sync.Mutex
+	assets map[string]*asset
+	albums map[string]*album
+	tags   map[string]*tag
+	stacks map[string]*stack
+	jobs   map[string]*immich.Job
+	calls  []string
+	faults []*Fault
+}
+
+type Option func(s *Server)
+
+// OptionAPIKey sets the API key accepted by the server
+func OptionAPIKey(key string) Option {
+	return func(s *Server) { s.APIKey = key }
+}
+
+// OptionAccessToken sets the session token accepted by the server, like the one given by a login
+func OptionAccessToken(token string) Option {
+	return func(s *Server) { s.AccessToken = token }
+}
+
+// OptionVersion sets the version of the server, like v1.125.7
+func OptionVersion(version string) Option {
+	return func(s *Server) { s.version = version }
+}
+
+// OptionMaxUploadSize makes the server answer 413 to the uploads larger than size, like a reverse proxy
+func OptionMaxUploadSize(size int64) Option {
+	return func(s *Server) { s.maxUploadSize = size }
+}
+
+// NewServer starts a fake server. It must be closed after use.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		APIKey:      "fake-api-key",
+		AccessToken: "fake-access-token",
+		version:     "v1.125.7",
+		user: immich.User{
+			ID:      uuid.NewString(),
+			Email:   "demo@immich.app",
+			IsAdmin: true,
+		},
+		assets: map[string]*asset{},
+		albums: map[string]*album{},
+		tags:   map[string]*tag{},
+		stacks: map[string]*stack{},
+		jobs:   map[string]*immich.Job{},
+	}
+	for _, j := range []immich.JobID{
+		immich.StorageTemplateMigration, immich.ThumbnailGeneration, immich.MetadataExtraction, immich.VideoConversion,
+		immich.FaceDetection, immich.FacialRecognition, immich.SmartSearch, immich.DuplicateDetection, immich.Sidecar,
+	} {
+		s.jobs[string(j)] = &immich.Job{}
+	}
+	for _, o := range options {
+		o(s)
+	}
+
+	mux := http.NewServeMux()
+	s.routeServer(mux)
+	s.routeAssets(mux)
+	s.routeAlbums(mux)
+	s.routeTags(mux)
+	s.routeJobs(mux)
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// middleware records the calls, applies the faults, and checks the API key
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := r.Method + " " + r.URL.Path
+		s.lock.Lock()
+		s.calls = append(s.calls, call)
+		f := s.matchFault(r)
+		s.lock.Unlock()
+
+		if f != nil {
+			if f.Latency > 0 {
+				select {
+				case <-time.After(f.Latency):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			if f.Status != 0 {
+				replyError(w, f.Status, http.StatusText(f.Status))
+				return
+			}
+		}
+		if r.URL.Path != "/api/server/ping" && !s.authorized(r) {
+			replyError(w, http.StatusUnauthorized, "Invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorized checks the API key, or the session token
+func (s *Server) authorized(r *http.Request) bool {
+	if key := r.Header.Get("x-api-key"); key != "" {
+		return key == s.APIKey
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.AccessToken != "" && token == s.AccessToken
+}
+
+func (s *Server) routeServer(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/server/ping", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, map[string]string{"res": "pong"})
+	})
+	mux.HandleFunc("GET /api/server/about", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, immich.AboutInfo{Version: s.version})
+	})
+	mux.HandleFunc("GET /api/server/features", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, immich.ServerFeatures{SmartSearch: true, DuplicateDetection: true, Map: true, Search: true, Trash: true, PasswordLogin: true})
+	})
+	mux.HandleFunc("GET /api/server/media-types", func(w http.ResponseWriter, r *http.Request) {
+		types := map[string][]string{}
+		for ext, t := range filetypes.DefaultSupportedMedia {
+			types[t] = append(types[t], ext)
+		}
+		for _, l := range types {
+			sort.Strings(l)
+		}
+		reply(w, http.StatusOK, types)
+	})
+	mux.HandleFunc("GET /api/users/me", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, s.user)
+	})
+	mux.HandleFunc("GET /api/server/statistics", func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		st := immich.ServerStatistics{}
+		for _, a := range s.assets {
+			if a.assetType == "VIDEO" {
+				st.Videos++
+			} else {
+				st.Photos++
+			}
+			st.Usage += a.fileSize()
+		}
+		reply(w, http.StatusOK, st)
+	})
+}
+
+// Fault changes the answer of the server to the matching requests
+type Fault struct {
+	Method  string        // Method of the requests, any when empty
+	Path    string        // Path of the requests like /api/assets, any when empty. A trailing * matches the prefix
+	Latency time.Duration // Delay before answering
+	Status  int           // Status answered instead of processing the request, like 500 or 413. 0 to process it
+	Count   int           // Number of requests affected, all when 0
+}
+
+// InjectFault adds a fault. The first matching fault applies.
+func (s *Server) InjectFault(f Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes the faults
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = nil
+}
+
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(f.Path, "*"); ok {
+			if !strings.HasPrefix(r.URL.Path, prefix) {
+				continue
+			}
+		} else if f.Path != "" && f.Path != r.URL.Path {
+			continue
+		}
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// Calls returns the requests received, as "METHOD /path"
+func (s *Server) Calls() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+// CallCount counts the requests received for the call "METHOD /path"
+func (s *Server) CallCount(call string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := 0
+	for _, c := range s.calls {
+		if c == call {
+			n++
+		}
+	}
+	return n
+}
+
+func reply(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// replyError answers the error like the server
+func replyError(w http.ResponseWriter, status int, message string) {
+	reply(w, status, immich.ServerErrorMessage{
+		Error:      http.StatusText(status),
+		StatusCode: status,
+		Message:    message,
+	})
+}
+
+// decode reads the JSON body of the request, and answers 400 when it's invalid
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		replyError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+const timeFormat = "2006-01-02T15:04:05.000Z"
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeFormat)
+}
@@ -0,0 +1,200 @@
+package fakeimmich_test
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+	"github.com/simulot/immich-go/internal/assets"
+	fakeimmich "github.com/simulot/immich-go/internal/fakeImmich"
+	"github.com/simulot/immich-go/internal/fshelper"
+)
+
+var photos = fstest.MapFS{
+	"a.jpg": &fstest.MapFile{Data: []byte("photo A content"), ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	"b.jpg": &fstest.MapFile{Data: []byte("photo B content"), ModTime: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	"c.mp4": &fstest.MapFile{Data: []byte("video C content"), ModTime: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+	"d.jpg": &fstest.MapFile{Data: []byte("photo A content"), ModTime: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)},
+}
+
+func newClient(t *testing.T, s *fakeimmich.Server) *immich.ImmichClient {
+	t.Helper()
+	client, err := immich.NewImmichClient(s.URL, s.APIKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ValidateConnection(context.Background()); err != nil {
+		t.Fatalf("ValidateConnection: %v", err)
+	}
+	return client
+}
+
+func upload(ctx context.Context, client *immich.ImmichClient, name string) (immich.AssetResponse, error) {
+	a := &assets.Asset{
+		File:             fshelper.FSName(photos, name),
+		OriginalFileName: name,
+		FileSize:         len(photos[name].Data),
+		CaptureDate:      photos[name].ModTime,
+	}
+	return client.AssetUpload(ctx, a)
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	s := fakeimmich.NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	ids := map[string]string{}
+	for _, name := range []string{"a.jpg", "b.jpg", "c.mp4"} {
+		r, err := upload(ctx, client, name)
+		if err != nil {
+			t.Fatalf("upload %s: %v", name, err)
+		}
+		if r.Status != immich.UploadCreated {
+			t.Errorf("upload %s: status %s, want created", name, r.Status)
+		}
+		ids[name] = r.ID
+	}
+
+	// d.jpg has the content of a.jpg
+	r, err := upload(ctx, client, "d.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != immich.UploadDuplicate || r.ID != ids["a.jpg"] {
+		t.Errorf("upload d.jpg: %+v, want a duplicate of %s", r, ids["a.jpg"])
+	}
+
+	// the trashed assets are still duplicates
+	if err := client.DeleteAssets(ctx, []string{ids["b.jpg"]}, false); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := upload(ctx, client, "b.jpg"); r.Status != immich.UploadDuplicate {
+		t.Errorf("upload trashed b.jpg: status %s, want duplicate", r.Status)
+	}
+	st, err := client.GetAssetStatistics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Images != 1 || st.Videos != 1 {
+		t.Errorf("statistics: %+v, want 1 image and 1 video", st)
+	}
+
+	list, err := client.GetAllAssets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Errorf("GetAllAssets: %d assets, want 3", len(list))
+	}
+	info, err := client.GetAssetInfo(ctx, ids["a.jpg"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.OriginalFileName != "a.jpg" || info.ExifInfo.FileSizeInByte != int64(len(photos["a.jpg"].Data)) || !info.ExifInfo.DateTimeOriginal.Equal(photos["a.jpg"].ModTime) {
+		t.Errorf("GetAssetInfo: %+v", info)
+	}
+
+	album, err := client.CreateAlbum(ctx, "Holidays", "", []string{ids["a.jpg"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.AddAssetToAlbum(ctx, album.ID, []string{ids["a.jpg"], ids["c.mp4"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0].Error != "duplicate" || !res[1].Success {
+		t.Errorf("AddAssetToAlbum: %+v", res)
+	}
+
+	tags, err := client.UpsertTags(ctx, []string{"family/kids"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TagAssets(ctx, tags[0].ID, []string{ids["c.mp4"]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.CreateStack(ctx, []string{ids["c.mp4"], ids["a.jpg"]}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.SendJobCommand(ctx, immich.ThumbnailGeneration, immich.Pause, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := s.Albums(), map[string][]string{"Holidays": {"a.jpg", "c.mp4"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Albums() = %v, want %v", got, want)
+	}
+	if got, want := s.Tags(), map[string][]string{"family": {}, "family/kids": {"c.mp4"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+	a, _ := s.Asset(ids["a.jpg"])
+	if a.StackCover != "c.mp4" || a.Trashed {
+		t.Errorf("Asset(a.jpg) = %+v", a)
+	}
+	if got := s.PausedJobs(); !reflect.DeepEqual(got, []string{string(immich.ThumbnailGeneration)}) {
+		t.Errorf("PausedJobs() = %v", got)
+	}
+	if n := s.CallCount("POST /api/assets"); n != 5 {
+		t.Errorf("%d uploads, want 5", n)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	ctx := context.Background()
+	s := fakeimmich.NewServer(fakeimmich.OptionMaxUploadSize(2000))
+	defer s.Close()
+	client := newClient(t, s)
+
+	s.InjectFault(fakeimmich.Fault{Method: http.MethodPost, Path: "/api/assets", Status: http.StatusInternalServerError, Count: 1})
+	if _, err := upload(ctx, client, "a.jpg"); immich.StatusCode(err) != http.StatusInternalServerError {
+		t.Errorf("upload with a fault: %v, want a 500 error", err)
+	}
+	if _, err := upload(ctx, client, "a.jpg"); err != nil {
+		t.Errorf("upload after the fault: %v", err)
+	}
+
+	big := fstest.MapFS{"big.jpg": &fstest.MapFile{Data: make([]byte, 5000)}}
+	_, err := client.AssetUpload(ctx, &assets.Asset{File: fshelper.FSName(big, "big.jpg"), OriginalFileName: "big.jpg", FileSize: 5000})
+	if immich.StatusCode(err) != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload of a large file: %v, want a 413 error", err)
+	}
+
+	s.InjectFault(fakeimmich.Fault{Path: "/api/server/*", Latency: time.Second})
+	ctx2, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.GetAboutInfo(ctx2); err == nil || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("GetAboutInfo with latency: %v after %s, want a timeout", err, time.Since(start))
+	}
+	s.ClearFaults()
+	if _, err := client.GetAboutInfo(ctx); err != nil {
+		t.Errorf("GetAboutInfo without fault: %v", err)
+	}
+	if got := len(s.Assets()); got != 1 {
+		t.Errorf("%d assets on the server, want 1", got)
+	}
+}
+
+func TestServerAccessToken(t *testing.T) {
+	ctx := context.Background()
+	s := fakeimmich.NewServer(fakeimmich.OptionAccessToken("session"))
+	defer s.Close()
+
+	for token, wantErr := range map[string]bool{"session": false, "other": true} {
+		client, err := immich.NewImmichClient(s.URL, "", immich.OptionAccessToken(token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.GetAllTags(ctx)
+		if (err != nil) != wantErr {
+			t.Errorf("token %q: err = %v, want an error %v", token, err, wantErr)
+		}
+	}
+}
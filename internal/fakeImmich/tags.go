@@ -0,0 +1,217 @@
+package fakeimmich
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/simulot/immich-go/immich"
+)
+
+// tag is a tag of the server. The tags are hierarchical, the value is the full path like "family/kids".
+type tag struct {
+	id       string
+	name     string
+	value    string
+	parentID string
+}
+
+func (t *tag) simplified() immich.TagSimplified {
+	return immich.TagSimplified{ID: t.id, Name: t.name, Value: t.value}
+}
+
+func (s *Server) routeTags(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/tags", s.getTags)
+	mux.HandleFunc("PUT /api/tags", s.upsertTags)
+	mux.HandleFunc("PUT /api/tags/assets", s.bulkTagAssets)
+	mux.HandleFunc("PUT /api/tags/{id}/assets", s.tagAssets)
+	mux.HandleFunc("DELETE /api/tags/{id}/assets", s.untagAssets)
+	mux.HandleFunc("DELETE /api/tags/{id}", s.deleteTag)
+}
+
+// tagByValue finds the tag having the value, the lock is held
+func (s *Server) tagByValue(value string) *tag {
+	for _, t := range s.tags {
+		if t.value == value {
+			return t
+		}
+	}
+	return nil
+}
+
+// upsertTag gives the tag of the value, creating it and its parents when needed. The lock is held.
+func (s *Server) upsertTag(value string) *tag {
+	var parent *tag
+	path := ""
+	for _, name := range strings.Split(strings.Trim(value, "/"), "/") {
+		if path != "" {
+			path += "/"
+		}
+		path += name
+		t := s.tagByValue(path)
+		if t == nil {
+			t = &tag{id: uuid.NewString(), name: name, value: path}
+			if parent != nil {
+				t.parentID = parent.id
+			}
+			s.tags[t.id] = t
+		}
+		parent = t
+	}
+	return parent
+}
+
+func (s *Server) getTags(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	list := []immich.TagSimplified{}
+	for _, t := range s.tags {
+		list = append(list, t.simplified())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Value < list[j].Value })
+	reply(w, http.StatusOK, list)
+}
+
+func (s *Server) upsertTags(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	list := []immich.TagSimplified{}
+	for _, v := range req.Tags {
+		if strings.Trim(v, "/") == "" {
+			replyError(w, http.StatusBadRequest, "each value in tags should not be empty")
+			return
+		}
+		list = append(list, s.upsertTag(v).simplified())
+	}
+	reply(w, http.StatusOK, list)
+}
+
+// changeTag adds or removes the tag of the assets, the result tells for each asset if it was changed
+func (s *Server) changeTag(w http.ResponseWriter, r *http.Request, add bool) {
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t, ok := s.tags[r.PathValue("id")]
+	if !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no tag.asset access")
+		return
+	}
+	results := []immich.TagAssetsResponse{}
+	for _, id := range req.IDs {
+		a, ok := s.assets[id]
+		switch {
+		case !ok:
+			results = append(results, immich.TagAssetsResponse{ID: id, Error: "not_found"})
+		case a.tags[t.id] == add:
+			if add {
+				results = append(results, immich.TagAssetsResponse{ID: id, Error: "duplicate"})
+			} else {
+				results = append(results, immich.TagAssetsResponse{ID: id, Error: "not_found"})
+			}
+		default:
+			if add {
+				a.tags[t.id] = true
+			} else {
+				delete(a.tags, t.id)
+			}
+			results = append(results, immich.TagAssetsResponse{ID: id, Success: true})
+		}
+	}
+	reply(w, http.StatusOK, results)
+}
+
+func (s *Server) tagAssets(w http.ResponseWriter, r *http.Request) {
+	s.changeTag(w, r, true)
+}
+
+func (s *Server) untagAssets(w http.ResponseWriter, r *http.Request) {
+	s.changeTag(w, r, false)
+}
+
+func (s *Server) bulkTagAssets(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TagIDs   []string `json:"tagIds"`
+		AssetIDs []string `json:"assetIds"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	count := 0
+	for _, tagID := range req.TagIDs {
+		if _, ok := s.tags[tagID]; !ok {
+			continue
+		}
+		for _, id := range req.AssetIDs {
+			if a, ok := s.assets[id]; ok && !a.tags[tagID] {
+				a.tags[tagID] = true
+				count++
+			}
+		}
+	}
+	reply(w, http.StatusOK, map[string]int{"count": count})
+}
+
+// deleteTag deletes the tag and its children
+func (s *Server) deleteTag(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t, ok := s.tags[r.PathValue("id")]
+	if !ok {
+		replyError(w, http.StatusBadRequest, "Not found or no tag.delete access")
+		return
+	}
+	for id, c := range s.tags {
+		if c.value == t.value || strings.HasPrefix(c.value, t.value+"/") {
+			delete(s.tags, id)
+			for _, a := range s.assets {
+				delete(a.tags, id)
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Tags gives the file names of the tagged assets, by tag value
+func (s *Server) Tags() map[string][]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	tags := map[string][]string{}
+	for _, t := range s.tags {
+		names := []string{}
+		for _, a := range s.assets {
+			if a.tags[t.id] {
+				names = append(names, a.fileName)
+			}
+		}
+		sort.Strings(names)
+		tags[t.value] = names
+	}
+	return tags
+}
+
+// TagAssets tags the assets with the tag value like "family/kids", created when needed. It returns the tag's ID.
+func (s *Server) TagAssets(value string, ids ...string) string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t := s.upsertTag(value)
+	for _, id := range ids {
+		if a, ok := s.assets[id]; ok {
+			a.tags[t.id] = true
+		}
+	}
+	return t.id
+}
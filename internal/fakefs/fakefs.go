@@ -1,7 +1,6 @@
 package fakefs
 
 import (
-	"crypto/rand"
 	"fmt"
 	"io"
 	"io/fs"
@@ -48,6 +47,9 @@ func (f FakeFile) Stat() (fs.FileInfo, error) {
 
 func (f *FakeFile) Read(b []byte) (int, error) {
 	if f.pos < f.fi.size {
+		if rest := f.fi.size - f.pos; int64(len(b)) > rest {
+			b = b[:rest]
+		}
 		n, err := f.r.Read(b)
 		f.pos += int64(n)
 		return n, err
@@ -61,8 +63,20 @@ func (f *FakeFile) Close() error {
 }
 
 type FakeFS struct {
-	name  string
-	files map[string]map[string]FakeDirEntry
+	name          string
+	files         map[string]map[string]FakeDirEntry
+	contentByName bool // the files having the same base name and size have the same content
+}
+
+// SameContentByName gives the same content to the files having the same base name and size,
+// whatever their folder and file system, like the copies of a photo in a Google Photos takeout.
+// Otherwise, the content depends on the path of the file.
+func SameContentByName(fsyss []fs.FS) {
+	for _, fsys := range fsyss {
+		if f, ok := fsys.(*FakeFS); ok {
+			f.contentByName = true
+		}
+	}
 }
 
 func (fsys FakeFS) Name() string {
@@ -123,7 +137,11 @@ func (fsys FakeFS) Open(name string) (fs.File, error) {
 			r, fakeInfo.size = fakePhotoData(title, d)
 		}
 	} else {
-		r = rand.Reader
+		key := name
+		if fsys.contentByName {
+			key = path.Base(name)
+		}
+		r = fakeContent(key, fakeInfo.size)
 	}
 	return &FakeFile{fi: fakeInfo, r: r}, nil
 }
@@ -0,0 +1,46 @@
+package fakefs
+
+import (
+	"bytes"
+	"io/fs"
+	"testing"
+)
+
+const listing = `Part: part-1.zip
+     5000  2024-05-25 22:15   photos/2023/IMG_0001.jpg
+     5000  2024-05-25 22:15   photos/2024/IMG_0001.jpg
+Part: part-2.zip
+     5000  2024-05-25 22:15   photos/2023/IMG_0001.jpg
+`
+
+func TestContent(t *testing.T) {
+	read := func(fsys fs.FS, name string) []byte {
+		t.Helper()
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != 5000 {
+			t.Errorf("%s: read %d bytes, want 5000", name, len(b))
+		}
+		return b
+	}
+
+	fsyss, err := ScanStringList("2006-01-02 15:04", listing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := read(fsyss[0], "photos/2023/IMG_0001.jpg"), read(fsyss[0], "photos/2024/IMG_0001.jpg"), read(fsyss[1], "photos/2023/IMG_0001.jpg")
+	if bytes.Equal(a, b) {
+		t.Error("files in different folders should have different contents")
+	}
+	if !bytes.Equal(a, c) {
+		t.Error("files having the same path in different parts should have the same content")
+	}
+
+	SameContentByName(fsyss)
+	a, b = read(fsyss[0], "photos/2023/IMG_0001.jpg"), read(fsyss[0], "photos/2024/IMG_0001.jpg")
+	if !bytes.Equal(a, b) {
+		t.Error("files having the same name and size should have the same content")
+	}
+}
@@ -75,3 +75,26 @@ func fakeJSON() (io.Reader, int64) {
 	t := fakeJSONTemplate
 	return strings.NewReader(t), int64(len(t))
 }
+
+// fakeContent gives a content of the declared size, made of the key and the size repeated.
+// The key is the file's path, or its base name when the copies of a file in different folders
+// or parts of a takeout must have the same checksum.
+func fakeContent(key string, size int64) io.Reader {
+	return &repeatReader{pattern: []byte(fmt.Sprintf("%s %d\n", key, size))}
+}
+
+// repeatReader repeats the pattern endlessly
+type repeatReader struct {
+	pattern []byte
+	pos     int
+}
+
+func (r *repeatReader) Read(b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		c := copy(b[n:], r.pattern[r.pos:])
+		n += c
+		r.pos = (r.pos + c) % len(r.pattern)
+	}
+	return n, nil
+}
@@ -11,8 +11,11 @@ package fileevent
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 )
 
@@ -55,6 +58,13 @@ const (
 	Tagged // = "Tagged"
 
 	Error
+
+	// The codes are exported by pkg/immichgo, the new ones are added at the end to keep the values.
+
+	Geotagged     // = "Geotagged"
+	SourceMoved   // = "Source file moved"
+	SourceDeleted // = "Source file deleted"
+
 	MaxCode
 )
 
@@ -89,6 +99,49 @@ var _code = map[Code]string{
 
 	Tagged: "Tagged",
 	Error:  "error",
+
+	Geotagged:     "geotagged from a track",
+	SourceMoved:   "source file moved",
+	SourceDeleted: "source file deleted",
+}
+
+// _names are the identifiers of the codes for the machine outputs. They don't change across versions.
+var _names = map[Code]string{
+	NotHandled:            "not_handled",
+	DiscoveredImage:       "discovered_image",
+	DiscoveredVideo:       "discovered_video",
+	DiscoveredSidecar:     "discovered_sidecar",
+	DiscoveredDiscarded:   "discovered_discarded",
+	DiscoveredUnsupported: "discovered_unsupported",
+	DiscoveredUseless:     "discovered_useless",
+
+	AnalysisAssociatedMetadata:        "associated_metadata",
+	AnalysisMissingAssociatedMetadata: "missing_associated_metadata",
+	AnalysisLocalDuplicate:            "local_duplicate",
+
+	UploadNotSelected:     "not_selected",
+	UploadUpgraded:        "upgraded",
+	UploadServerDuplicate: "server_duplicate",
+	UploadServerBetter:    "server_better",
+	UploadAlbumCreated:    "album_created",
+	UploadAddToAlbum:      "added_to_album",
+	UploadLi:              "live_photo_linked",
+	UploadServerError:     "upload_error",
+
+	Uploaded:  "uploaded",
+	Stacked:   "stacked",
+	LivePhoto: "live_photo",
+	Metadata:  "metadata",
+	INFO:      "info",
+
+	Written: "written",
+
+	Tagged: "tagged",
+	Error:  "error",
+
+	Geotagged:     "geotagged",
+	SourceMoved:   "source_moved",
+	SourceDeleted: "source_deleted",
 }
 
 var _logLevels = map[Code]slog.Level{
@@ -113,6 +166,9 @@ var _logLevels = map[Code]slog.Level{
 	Written:                           slog.LevelInfo,
 	Tagged:                            slog.LevelInfo,
 	Error:                             slog.LevelError,
+	Geotagged:                         slog.LevelInfo,
+	SourceMoved:                       slog.LevelInfo,
+	SourceDeleted:                     slog.LevelInfo,
 }
 
 func (e Code) String() string {
@@ -122,9 +178,22 @@ func (e Code) String() string {
 	return fmt.Sprintf("unknown event code: %d", int(e))
 }
 
+// Name gives the identifier of the code used by the machine outputs
+func (e Code) Name() string {
+	if s, ok := _names[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("code_%d", int(e))
+}
+
+// Listener receives all events recorded by the Recorder
+type Listener func(ctx context.Context, code Code, file slog.LogValuer, args ...any)
+
 type Recorder struct {
-	counts counts
-	log    *slog.Logger
+	counts    counts
+	log       *slog.Logger
+	lock      sync.RWMutex
+	listeners []Listener
 }
 
 type counts []int64
@@ -143,6 +212,7 @@ func (r *Recorder) Log() *slog.Logger {
 
 func (r *Recorder) Record(ctx context.Context, code Code, file slog.LogValuer, args ...any) {
 	atomic.AddInt64(&r.counts[code], 1)
+	eventArgs := args
 	if r.log != nil {
 		level := _logLevels[code]
 		if file != nil {
@@ -161,13 +231,32 @@ func (r *Recorder) Record(ctx context.Context, code Code, file slog.LogValuer, a
 		}
 		r.log.Log(ctx, level, code.String(), args...)
 	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, l := range r.listeners {
+		l(ctx, code, file, eventArgs...)
+	}
+}
+
+// AddListener registers a function called for each recorded event.
+// Listeners are called synchronously and must return quickly.
+func (r *Recorder) AddListener(l Listener) {
+	r.lock.Lock()
+	r.listeners = append(r.listeners, l)
+	r.lock.Unlock()
 }
 
 func (r *Recorder) SetLogger(l *slog.Logger) {
 	r.log = l
 }
 
+// Report prints the counters on the standard output, and in the log
 func (r *Recorder) Report() {
+	r.ReportTo(os.Stdout)
+}
+
+// ReportTo prints the counters on w, and in the log
+func (r *Recorder) ReportTo(w io.Writer) {
 	sb := strings.Builder{}
 
 	countAnalysis := 0
@@ -227,7 +316,25 @@ func (r *Recorder) Report() {
 		} {
 			sb.WriteString(fmt.Sprintf("%-40s: %7d\n", c.String(), r.counts[c]))
 		}
-		fmt.Println(sb.String())
+		if r.counts[Geotagged] > 0 {
+			sb.WriteString(fmt.Sprintf("%-40s: %7d\n", Geotagged.String(), r.counts[Geotagged]))
+		}
+	}
+
+	countsSource := r.counts[SourceMoved] + r.counts[SourceDeleted]
+	if countsSource > 0 {
+		sb.WriteString("\n")
+		sb.WriteString("After upload:\n")
+		sb.WriteString("-------------\n")
+		for _, c := range []Code{
+			SourceMoved,
+			SourceDeleted,
+		} {
+			sb.WriteString(fmt.Sprintf("%-40s: %7d\n", c.String(), r.counts[c]))
+		}
+	}
+	if countsUpload > 0 || countsSource > 0 {
+		fmt.Fprintln(w, sb.String())
 	}
 
 	if countsUpload > 0 || countAnalysis > 0 {
@@ -0,0 +1,72 @@
+package fileevent
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/simulot/immich-go/internal/fshelper"
+)
+
+func TestRecorderListener(t *testing.T) {
+	r := NewRecorder(nil)
+
+	type event struct {
+		code Code
+		file string
+		args []any
+	}
+	var got []event
+	r.AddListener(func(ctx context.Context, code Code, file slog.LogValuer, args ...any) {
+		e := event{code: code, args: args}
+		if file != nil {
+			e.file = file.LogValue().String()
+		}
+		got = append(got, e)
+	})
+
+	ctx := context.Background()
+	r.Record(ctx, Uploaded, fshelper.FSName(nil, "photo.jpg"))
+	r.Record(ctx, UploadServerError, fshelper.FSName(nil, "movie.mp4"), "error", "boom")
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(got))
+	}
+	if got[0].code != Uploaded || got[0].file != "photo.jpg" || len(got[0].args) != 0 {
+		t.Errorf("unexpected 1st event: %+v", got[0])
+	}
+	if got[1].code != UploadServerError || got[1].file != "movie.mp4" || len(got[1].args) != 2 {
+		t.Errorf("unexpected 2nd event: %+v", got[1])
+	}
+	if c := r.GetCounts(); c[Uploaded] != 1 || c[UploadServerError] != 1 {
+		t.Errorf("unexpected counts: %v", c)
+	}
+}
+
+func TestCodes(t *testing.T) {
+	// the values are exported by pkg/immichgo, they must not change
+	for c, want := range map[Code]int{
+		DiscoveredImage:   1,
+		UploadServerError: 17,
+		Uploaded:          18,
+		Tagged:            24,
+		Error:             25,
+	} {
+		if int(c) != want {
+			t.Errorf("%s = %d, want %d", c.Name(), int(c), want)
+		}
+	}
+
+	names := map[string]Code{}
+	for c := NotHandled; c < MaxCode; c++ {
+		n, ok := _names[c]
+		if !ok {
+			t.Errorf("the code %d has no name", int(c))
+			continue
+		}
+		if other, ok := names[n]; ok {
+			t.Errorf("the codes %d and %d have the same name %q", int(other), int(c), n)
+		}
+		names[n] = c
+	}
+}
@@ -10,11 +10,11 @@ Applies filters to a group of assets.
 
 type Filter func(g *assets.Group) *assets.Group
 
+// ApplyFilters gives all the groups to the filters, the groups of a single asset too.
+// Each filter checks the kind of groups it handles.
 func ApplyFilters(g *assets.Group, filters ...Filter) *assets.Group {
-	if g.Grouping != assets.GroupByNone {
-		for _, f := range filters {
-			g = f(g)
-		}
+	for _, f := range filters {
+		g = f(g)
 	}
 	return g
 }
@@ -77,6 +77,11 @@ func (cr *CacheReader) OpenFile() (*tempFile, error) {
 	return &tempFile{File: f, cr: cr}, nil
 }
 
+// IsTemporary returns true when the content is copied into a temporary file
+func (cr *CacheReader) IsTemporary() bool {
+	return cr.shouldRemove
+}
+
 // Close closes the temporary file only if it was created by NewCacheReader
 func (cr *CacheReader) Close() error {
 	debugfiles.TrackCloseFile(cr.tmpFile)
@@ -33,6 +33,12 @@ type FSCanLink interface {
 	MkSymlink(name, target string) error
 }
 
+// FSOnDisk is implemented by file systems backed by a folder of the local file system
+type FSOnDisk interface {
+	// OSPath returns the path of the file on the local file system
+	OSPath(name string) string
+}
+
 type FileCanWrite interface {
 	Write(b []byte) (ret int, err error)
 }
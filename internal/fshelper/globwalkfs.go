@@ -97,6 +97,11 @@ func (gw GlobWalkFS) match(name string) bool {
 	return true
 }
 
+// OSPath returns the path of the file on the local file system
+func (gw GlobWalkFS) OSPath(name string) string {
+	return filepath.Join(gw.dir, filepath.FromSlash(name))
+}
+
 // Open the name only if the name matches with the pattern
 func (gw GlobWalkFS) Open(name string) (fs.File, error) {
 	return gw.rootFS.Open(name)
@@ -0,0 +1,150 @@
+package geotrack
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadGPX reads the points having a time from a GPX file: track points, route points and waypoints
+func ReadGPX(r io.Reader) ([]Point, error) {
+	type gpxPoint struct {
+		Lat  float64 `xml:"lat,attr"`
+		Lon  float64 `xml:"lon,attr"`
+		Time string  `xml:"time"`
+	}
+
+	points := []Point{}
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return points, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "trkpt", "rtept", "wpt":
+			var p gpxPoint
+			if err := d.DecodeElement(&p, &se); err != nil {
+				return nil, err
+			}
+			if p.Time == "" {
+				continue
+			}
+			t, err := parseTime(p.Time)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, Point{Time: t, Latitude: p.Lat, Longitude: p.Lon})
+		}
+	}
+}
+
+type kmlTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"coord"`
+}
+
+type kmlPlacemark struct {
+	TimeStamp struct {
+		When string `xml:"when"`
+	} `xml:"TimeStamp"`
+	Point struct {
+		Coordinates string `xml:"coordinates"`
+	} `xml:"Point"`
+	Tracks      []kmlTrack `xml:"Track"`
+	MultiTracks []struct {
+		Tracks []kmlTrack `xml:"Track"`
+	} `xml:"MultiTrack"`
+}
+
+// ReadKML reads the points from a KML file: the gx:Track elements, as exported
+// by Google Maps timeline, and the placemarks having a time stamp
+func ReadKML(r io.Reader) ([]Point, error) {
+	points := []Point{}
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return points, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "Placemark" {
+			continue
+		}
+		var pm kmlPlacemark
+		if err := d.DecodeElement(&pm, &se); err != nil {
+			return nil, err
+		}
+		tracks := pm.Tracks
+		for _, mt := range pm.MultiTracks {
+			tracks = append(tracks, mt.Tracks...)
+		}
+		for _, tr := range tracks {
+			if len(tr.When) != len(tr.Coord) {
+				return nil, fmt.Errorf("the track has %d times for %d coordinates", len(tr.When), len(tr.Coord))
+			}
+			for i := range tr.When {
+				p, err := kmlPoint(tr.When[i], strings.Fields(tr.Coord[i]))
+				if err != nil {
+					return nil, err
+				}
+				points = append(points, p)
+			}
+		}
+		if pm.TimeStamp.When != "" && pm.Point.Coordinates != "" {
+			p, err := kmlPoint(pm.TimeStamp.When, strings.Split(strings.TrimSpace(pm.Point.Coordinates), ","))
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, p)
+		}
+	}
+}
+
+// kmlPoint reads a point given as longitude, latitude, and optional altitude
+func kmlPoint(when string, coord []string) (Point, error) {
+	if len(coord) < 2 {
+		return Point{}, fmt.Errorf("invalid coordinates: %q", strings.Join(coord, " "))
+	}
+	t, err := parseTime(when)
+	if err != nil {
+		return Point{}, err
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(coord[0]), 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid longitude: %w", err)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(coord[1]), 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid latitude: %w", err)
+	}
+	return Point{Time: t, Latitude: lat, Longitude: lon}, nil
+}
+
+// parseTime reads the times of the tracks. They are UTC when the zone is missing.
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t, nil
+	}
+	t, err2 := time.ParseInLocation("2006-01-02T15:04:05", s, time.UTC)
+	if err2 == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %w", err)
+}
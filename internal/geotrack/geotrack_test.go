@@ -0,0 +1,133 @@
+package geotrack
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><trkseg>
+    <trkpt lat="48.8584" lon="2.2945"><ele>35</ele><time>2023-07-14T10:00:00Z</time></trkpt>
+    <trkpt lat="48.8606" lon="2.3376"><time>2023-07-14T10:10:00Z</time></trkpt>
+    <trkpt lat="48.8530" lon="2.3499"><time>2023-07-14T11:30:00.500Z</time></trkpt>
+  </trkseg></trk>
+  <wpt lat="1" lon="1"><name>no time</name></wpt>
+</gpx>`
+
+const sampleKML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+<Document>
+  <Placemark>
+    <gx:Track>
+      <when>2023-07-14T10:00:00Z</when>
+      <when>2023-07-14T10:10:00Z</when>
+      <gx:coord>2.2945 48.8584 35</gx:coord>
+      <gx:coord>2.3376 48.8606 0</gx:coord>
+    </gx:Track>
+  </Placemark>
+  <Placemark>
+    <TimeStamp><when>2023-07-14T12:00:00+02:00</when></TimeStamp>
+    <Point><coordinates>2.3499,48.8530,0</coordinates></Point>
+  </Placemark>
+</Document>
+</kml>`
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestReadGPX(t *testing.T) {
+	points, err := ReadGPX(strings.NewReader(sampleGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(points))
+	}
+	if !near(points[1].Latitude, 48.8606) || !near(points[1].Longitude, 2.3376) {
+		t.Errorf("unexpected point: %+v", points[1])
+	}
+}
+
+func TestReadKML(t *testing.T) {
+	points, err := ReadKML(strings.NewReader(sampleKML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(points))
+	}
+	if !points[2].Time.Equal(time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)) || !near(points[2].Latitude, 48.8530) {
+		t.Errorf("unexpected point: %+v", points[2])
+	}
+}
+
+func TestLocate(t *testing.T) {
+	points, err := ReadGPX(strings.NewReader(sampleGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := NewTrack(points...)
+	at := func(h, m int) time.Time { return time.Date(2023, 7, 14, h, m, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		tm       time.Time
+		ok       bool
+		lat, lon float64
+	}{
+		{name: "on a point", tm: at(10, 0), ok: true, lat: 48.8584, lon: 2.2945},
+		{name: "interpolated", tm: at(10, 5), ok: true, lat: (48.8584 + 48.8606) / 2, lon: (2.2945 + 2.3376) / 2},
+		{name: "gap too large, near a point", tm: at(10, 15), ok: true, lat: 48.8606, lon: 2.3376},
+		{name: "gap too large", tm: at(10, 50), ok: false},
+		{name: "before the track", tm: at(9, 55), ok: true, lat: 48.8584, lon: 2.2945},
+		{name: "long before the track", tm: at(8, 0), ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := tr.Locate(tt.tm, 10*time.Minute)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && (!near(p.Latitude, tt.lat) || !near(p.Longitude, tt.lon)) {
+				t.Errorf("got %f,%f, want %f,%f", p.Latitude, p.Longitude, tt.lat, tt.lon)
+			}
+		})
+	}
+}
+
+func TestLocateAntimeridian(t *testing.T) {
+	tr := NewTrack(
+		Point{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Latitude: -17, Longitude: 179.5},
+		Point{Time: time.Date(2023, 1, 1, 0, 10, 0, 0, time.UTC), Latitude: -17, Longitude: -179.5},
+	)
+	p, ok := tr.Locate(time.Date(2023, 1, 1, 0, 5, 0, 0, time.UTC), time.Hour)
+	if !ok || !near(math.Abs(p.Longitude), 180) {
+		t.Errorf("got %f, %v, want ±180", p.Longitude, ok)
+	}
+}
+
+func TestGeotaggerOffset(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "track.GPX")
+	if err := os.WriteFile(name, []byte(sampleGPX), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGeotagger(Options{Files: []string{name}, MaxGap: 10 * time.Minute, TimeOffset: -2 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the camera clock is set on the local time (UTC+2) and the capture date is read as UTC
+	p, ok := g.Locate(time.Date(2023, 7, 14, 12, 10, 0, 0, time.UTC))
+	if !ok || !near(p.Latitude, 48.8606) {
+		t.Errorf("got %+v, %v", p, ok)
+	}
+
+	if _, err := NewGeotagger(Options{Files: []string{filepath.Join(t.TempDir(), "track.txt")}}); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
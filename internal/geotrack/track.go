@@ -0,0 +1,204 @@
+// Package geotrack gives the GPS position of photos taken by devices without GPS,
+// using the tracks recorded by a phone or a GPS logger (GPX or KML files).
+//
+// The position is interpolated between the two track points surrounding the capture time.
+package geotrack
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// Point is a position of the track at a given time
+type Point struct {
+	Time      time.Time
+	Latitude  float64
+	Longitude float64
+}
+
+// Track is a list of points sorted by time
+type Track struct {
+	points []Point
+}
+
+// NewTrack creates a track with the points
+func NewTrack(points ...Point) *Track {
+	t := &Track{}
+	t.Add(points...)
+	return t
+}
+
+// Add adds points to the track
+func (t *Track) Add(points ...Point) {
+	t.points = append(t.points, points...)
+	sort.SliceStable(t.points, func(i, j int) bool {
+		return t.points[i].Time.Before(t.points[j].Time)
+	})
+}
+
+// Len returns the number of points of the track
+func (t *Track) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.points)
+}
+
+// Span returns the time of the first and the last point
+func (t *Track) Span() (time.Time, time.Time) {
+	if t.Len() == 0 {
+		return time.Time{}, time.Time{}
+	}
+	return t.points[0].Time, t.points[len(t.points)-1].Time
+}
+
+// Locate returns the position at the time tm.
+// When the time falls between two points distant of less than maxGap, the position is interpolated.
+// Otherwise, the nearest point is used when it's closer than maxGap.
+func (t *Track) Locate(tm time.Time, maxGap time.Duration) (Point, bool) {
+	if t.Len() == 0 || tm.IsZero() {
+		return Point{}, false
+	}
+	i := sort.Search(len(t.points), func(i int) bool {
+		return !t.points[i].Time.Before(tm)
+	})
+	// points[i-1] < tm <= points[i]
+	if i < len(t.points) && t.points[i].Time.Equal(tm) {
+		return t.points[i], true
+	}
+	if i > 0 && i < len(t.points) {
+		a, b := t.points[i-1], t.points[i]
+		gap := b.Time.Sub(a.Time)
+		if gap <= maxGap {
+			r := float64(tm.Sub(a.Time)) / float64(gap)
+			return Point{
+				Time:      tm,
+				Latitude:  a.Latitude + (b.Latitude-a.Latitude)*r,
+				Longitude: a.Longitude + interpolateLongitude(a.Longitude, b.Longitude, r),
+			}, true
+		}
+	}
+
+	var nearest Point
+	best := time.Duration(math.MaxInt64)
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(t.points) {
+			continue
+		}
+		d := absDuration(t.points[j].Time.Sub(tm))
+		if d < best {
+			best, nearest = d, t.points[j]
+		}
+	}
+	if best <= maxGap {
+		nearest.Time = tm
+		return nearest, true
+	}
+	return Point{}, false
+}
+
+// interpolateLongitude returns the move in longitude, taking the shortest way around the antimeridian
+func interpolateLongitude(a, b float64, r float64) float64 {
+	d := b - a
+	if d > 180 {
+		d -= 360
+	} else if d < -180 {
+		d += 360
+	}
+	l := d * r
+	if a+l > 180 {
+		l -= 360
+	} else if a+l < -180 {
+		l += 360
+	}
+	return l
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Load reads the GPX and KML files
+func Load(files ...string) (*Track, error) {
+	t := &Track{}
+	var errs error
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		var points []Point
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".gpx":
+			points, err = ReadGPX(f)
+		case ".kml":
+			points, err = ReadKML(f)
+		default:
+			err = fmt.Errorf("%s: unsupported track file, expected .gpx or .kml", name)
+		}
+		f.Close()
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		t.Add(points...)
+	}
+	return t, errs
+}
+
+// Options are the parameters of the geotagging
+type Options struct {
+	Files      []string      // GPX or KML files
+	MaxGap     time.Duration // Maximum time between the photo and the track points
+	TimeOffset time.Duration // Added to the capture time to get the time of the track
+}
+
+// AddFlags adds the geotagging flags to the flag set
+func AddFlags(flags *pflag.FlagSet, o *Options) {
+	flags.StringSliceVar(&o.Files, "gpx", nil, "GPX or KML track files used to set the position of the photos without GPS data")
+	flags.DurationVar(&o.MaxGap, "gpx-max-gap", 10*time.Minute, "Maximum time between the photo and the track points")
+	flags.DurationVar(&o.TimeOffset, "gpx-time-offset", 0, "Offset added to the capture time to match the track time, for cameras with a wrong clock (ex: -1h)")
+}
+
+// Geotagger locates the photos with the options
+type Geotagger struct {
+	options Options
+	track   *Track
+}
+
+// NewGeotagger reads the track files
+func NewGeotagger(o Options) (*Geotagger, error) {
+	t, err := Load(o.Files...)
+	if err != nil {
+		return nil, err
+	}
+	if t.Len() == 0 {
+		return nil, errors.New("the track files have no point with a time")
+	}
+	return &Geotagger{options: o, track: t}, nil
+}
+
+// Track returns the loaded track
+func (g *Geotagger) Track() *Track {
+	return g.track
+}
+
+// Locate returns the position for a photo taken at the capture time
+func (g *Geotagger) Locate(captureDate time.Time) (Point, bool) {
+	if g == nil || captureDate.IsZero() {
+		return Point{}, false
+	}
+	return g.track.Locate(captureDate.Add(g.options.TimeOffset), g.options.MaxGap)
+}
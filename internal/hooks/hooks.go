@@ -0,0 +1,308 @@
+// Package hooks runs user's executables at given phases of the upload.
+//
+// The executable receives a JSON document on its standard input describing the asset,
+// and can answer with a JSON document on its standard output to accept the asset, skip it,
+// or override its metadata. An empty answer means "accept".
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/spf13/cobra"
+)
+
+// Phase of the upload when the hook is called
+type Phase string
+
+const (
+	BeforeUpload Phase = "before-upload" // the asset is about to be uploaded
+	AfterUpload  Phase = "after-upload"  // the asset has been uploaded
+	AfterAlbum   Phase = "after-album"   // the asset has been added to an album on the server
+)
+
+// Action requested by the hook
+type Action string
+
+const (
+	Accept   Action = "accept"   // continue the processing of the asset
+	Skip     Action = "skip"     // don't upload the asset
+	Override Action = "override" // continue with the metadata given by the hook
+)
+
+// Metadata of the asset exchanged with the hook.
+// The fields absent from the hook's answer are left unchanged, hence the pointers.
+type Metadata struct {
+	FileName    string    `json:"fileName,omitempty"`
+	FileSize    int       `json:"fileSize,omitempty"`
+	CaptureDate time.Time `json:"captureDate,omitzero"`
+	Description *string   `json:"description,omitempty"`
+	Latitude    *float64  `json:"latitude,omitempty"`
+	Longitude   *float64  `json:"longitude,omitempty"`
+	Rating      *int      `json:"rating,omitempty"`
+	Favorite    *bool     `json:"favorite,omitempty"`
+	Archived    *bool     `json:"archived,omitempty"`
+	Albums      []string  `json:"albums,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+}
+
+// Request is the JSON document sent to the hook's standard input
+type Request struct {
+	Phase    Phase    `json:"phase"`
+	File     string   `json:"file"`
+	Metadata Metadata `json:"metadata"`
+	Advice   string   `json:"advice,omitempty"`   // decision taken by immich-go before the upload
+	Album    string   `json:"album,omitempty"`    // album the asset has been added to, for the after-album hook
+	ServerID string   `json:"serverId,omitempty"` // ID of the asset on the server, when known
+	Status   string   `json:"status,omitempty"`   // status returned by the server after the upload
+}
+
+// Response is the JSON document read from the hook's standard output
+type Response struct {
+	Action   Action    `json:"action"`
+	Reason   string    `json:"reason,omitempty"`
+	Metadata *Metadata `json:"metadata,omitempty"` // new metadata when the action is override
+}
+
+// Options hold the hook's command lines and their execution parameters
+type Options struct {
+	BeforeUpload string        // command line of the before-upload hook
+	AfterUpload  string        // command line of the after-upload hook
+	AfterAlbum   string        // command line of the after-album hook
+	Timeout      time.Duration // maximum execution time of a hook
+	OnFailure    FailurePolicy // what to do when a hook fails
+}
+
+func AddHookFlags(cmd *cobra.Command, o *Options) {
+	o.OnFailure = FailureStop
+	cmd.PersistentFlags().StringVar(&o.BeforeUpload, "hook-before-upload", "", "Executable called before uploading an asset. It can accept, skip the asset or override its metadata")
+	cmd.PersistentFlags().StringVar(&o.AfterUpload, "hook-after-upload", "", "Executable called after the upload of an asset")
+	cmd.PersistentFlags().StringVar(&o.AfterAlbum, "hook-after-album", "", "Executable called after the asset is added to an album on the server")
+	cmd.PersistentFlags().DurationVar(&o.Timeout, "hook-timeout", 30*time.Second, "Maximum execution time of a hook")
+	cmd.PersistentFlags().Var(&o.OnFailure, "hook-on-failure", "Action to take when a hook fails or times out (stop|continue|skip)")
+}
+
+// IsSet returns true when a hook is given for the phase
+func (o *Options) IsSet(phase Phase) bool {
+	return o.command(phase) != ""
+}
+
+func (o *Options) command(phase Phase) string {
+	switch phase {
+	case BeforeUpload:
+		return o.BeforeUpload
+	case AfterUpload:
+		return o.AfterUpload
+	case AfterAlbum:
+		return o.AfterAlbum
+	}
+	return ""
+}
+
+// Run calls the hook of the request's phase, and returns its response.
+// When no hook is set for the phase, the response is Accept.
+func (o *Options) Run(ctx context.Context, req Request) (Response, error) {
+	command := o.command(req.Phase)
+	if command == "" {
+		return Response{Action: Accept}, nil
+	}
+	args, err := SplitCommand(command)
+	if err != nil {
+		return Response{}, fmt.Errorf("%s hook: %w", req.Phase, err)
+	}
+
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	in, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) // #nosec G204 the command is given by the user
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second // don't wait for the hook's children after the timeout
+
+	err = cmd.Run()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return Response{}, fmt.Errorf("%s hook %q: timeout after %s", req.Phase, args[0], o.Timeout)
+		}
+		return Response{}, fmt.Errorf("%s hook %q: %w: %s", req.Phase, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	resp := Response{Action: Accept}
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) == 0 {
+		return resp, nil
+	}
+	err = json.Unmarshal(out, &resp)
+	if err != nil {
+		return Response{}, fmt.Errorf("%s hook %q: can't decode the response: %w", req.Phase, args[0], err)
+	}
+	switch resp.Action {
+	case "":
+		resp.Action = Accept
+	case Accept, Skip:
+	case Override:
+		if resp.Metadata == nil {
+			return Response{}, fmt.Errorf("%s hook %q: override without metadata", req.Phase, args[0])
+		}
+	default:
+		return Response{}, fmt.Errorf("%s hook %q: unknown action %q", req.Phase, args[0], resp.Action)
+	}
+	return resp, nil
+}
+
+// NewMetadata collects the metadata of the asset to send them to the hook
+func NewMetadata(a *assets.Asset) Metadata {
+	md := Metadata{
+		FileName:    a.OriginalFileName,
+		FileSize:    a.FileSize,
+		CaptureDate: a.CaptureDate,
+		Description: ptr(a.Description),
+		Latitude:    ptr(a.Latitude),
+		Longitude:   ptr(a.Longitude),
+		Rating:      ptr(a.Rating),
+		Favorite:    ptr(a.Favorite),
+		Archived:    ptr(a.Archived),
+	}
+	for _, al := range a.Albums {
+		md.Albums = append(md.Albums, al.Title)
+	}
+	for _, t := range a.Tags {
+		md.Tags = append(md.Tags, t.Value)
+	}
+	return md
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// Apply replaces the asset's metadata by those given by the hook.
+// Only the fields given by the hook are replaced.
+func (md Metadata) Apply(a *assets.Asset) {
+	if md.FileName != "" {
+		a.OriginalFileName = md.FileName
+	}
+	if !md.CaptureDate.IsZero() {
+		a.CaptureDate = md.CaptureDate
+	}
+	if md.Description != nil {
+		a.Description = *md.Description
+	}
+	if md.Latitude != nil {
+		a.Latitude = *md.Latitude
+	}
+	if md.Longitude != nil {
+		a.Longitude = *md.Longitude
+	}
+	if md.Rating != nil {
+		a.Rating = *md.Rating
+	}
+	if md.Favorite != nil {
+		a.Favorite = *md.Favorite
+	}
+	if md.Archived != nil {
+		a.Archived = *md.Archived
+	}
+	if md.Albums != nil {
+		existing := a.Albums
+		a.Albums = nil
+	nextAlbum:
+		for _, title := range md.Albums {
+			for _, al := range existing {
+				if al.Title == title {
+					a.Albums = append(a.Albums, al)
+					continue nextAlbum
+				}
+			}
+			a.Albums = append(a.Albums, assets.NewAlbum("", title, ""))
+		}
+	}
+	if md.Tags != nil {
+		a.Tags = nil
+		for _, t := range md.Tags {
+			a.AddTag(t)
+		}
+	}
+
+	// The application's metadata are forced after the upload, keep them in sync
+	if a.FromApplication != nil {
+		fa := *a.FromApplication
+		fa.DateTaken = a.CaptureDate
+		fa.Description = a.Description
+		fa.Latitude = a.Latitude
+		fa.Longitude = a.Longitude
+		fa.Rating = byte(a.Rating)
+		fa.Favorited = a.Favorite
+		fa.Archived = a.Archived
+		fa.Albums = a.Albums
+		fa.Tags = a.Tags
+		a.FromApplication = &fa
+	}
+}
+
+// SplitCommand splits the hook's command line into the executable and its arguments.
+// The arguments are separated by spaces, and quotes (" or ') group the words containing spaces,
+// like "C:\Program Files\hook.exe" --verbose. The backslashes are kept as is.
+// A command line naming an existing file is the executable, without arguments.
+func SplitCommand(command string) ([]string, error) {
+	command = strings.TrimSpace(command)
+	if info, err := os.Stat(command); err == nil && !info.IsDir() {
+		return []string{command}, nil
+	}
+	var (
+		args   []string
+		word   strings.Builder
+		inWord bool
+		quote  rune
+	)
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", command)
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return args, nil
+}
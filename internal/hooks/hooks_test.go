@@ -0,0 +1,195 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/internal/assets"
+)
+
+// writeScript creates an executable shell script in a temporary folder
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not available on windows")
+	}
+	name := filepath.Join(t.TempDir(), "hook.sh")
+	err := os.WriteFile(name, []byte("#!/bin/sh\n"+body+"\n"), 0o700) // #nosec G306
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestRun(t *testing.T) {
+	tc := []struct {
+		name       string
+		script     string
+		timeout    time.Duration
+		wantAction Action
+		wantErr    string
+	}{
+		{
+			name:       "empty answer",
+			script:     "cat > /dev/null",
+			wantAction: Accept,
+		},
+		{
+			name:       "skip",
+			script:     `cat > /dev/null; echo '{"action":"skip","reason":"not wanted"}'`,
+			wantAction: Skip,
+		},
+		{
+			name:       "request content",
+			script:     `in=$(cat); echo "$in" | grep -q '"phase":"before-upload"' && echo "$in" | grep -q '"advice":"NotOnServer"' || echo '{"action":"skip"}'`,
+			wantAction: Accept,
+		},
+		{
+			name:    "exit code",
+			script:  "echo failure >&2; exit 3",
+			wantErr: "failure",
+		},
+		{
+			name:    "timeout",
+			script:  "sleep 5",
+			timeout: 100 * time.Millisecond,
+			wantErr: "timeout",
+		},
+		{
+			name:    "bad json",
+			script:  "echo 'not json'",
+			wantErr: "can't decode",
+		},
+		{
+			name:    "unknown action",
+			script:  `echo '{"action":"dance"}'`,
+			wantErr: "unknown action",
+		},
+		{
+			name:    "override without metadata",
+			script:  `echo '{"action":"override"}'`,
+			wantErr: "without metadata",
+		},
+	}
+
+	for _, c := range tc {
+		t.Run(c.name, func(t *testing.T) {
+			o := Options{
+				BeforeUpload: writeScript(t, c.script),
+				Timeout:      c.timeout,
+			}
+			resp, err := o.Run(context.Background(), Request{Phase: BeforeUpload, File: "photo.jpg", Advice: "NotOnServer"})
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", c.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Action != c.wantAction {
+				t.Errorf("expected action %q, got %q", c.wantAction, resp.Action)
+			}
+		})
+	}
+}
+
+func TestRunNoHook(t *testing.T) {
+	o := Options{}
+	resp, err := o.Run(context.Background(), Request{Phase: AfterUpload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Action != Accept {
+		t.Errorf("expected accept, got %q", resp.Action)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	o := Options{
+		BeforeUpload: writeScript(t, `cat > /dev/null; echo '{"action":"override","metadata":{"description":"new","captureDate":"2023-05-01T10:00:00Z","albums":["Holidays","New"],"tags":["a/b"]}}'`),
+	}
+	a := &assets.Asset{
+		OriginalFileName: "photo.jpg",
+		Description:      "old",
+		Latitude:         48.85,
+		Longitude:        2.35,
+		Rating:           4,
+		Favorite:         true,
+		Albums:           []assets.Album{{Title: "Holidays", Description: "summer"}},
+		FromApplication:  &assets.Metadata{Description: "old"},
+	}
+	resp, err := o.Run(context.Background(), Request{Phase: BeforeUpload, Metadata: NewMetadata(a)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Action != Override {
+		t.Fatalf("expected override, got %q", resp.Action)
+	}
+	resp.Metadata.Apply(a)
+
+	if a.Description != "new" || a.FromApplication.Description != "new" {
+		t.Errorf("description not overridden: %q, %q", a.Description, a.FromApplication.Description)
+	}
+	if !a.CaptureDate.Equal(time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected capture date: %s", a.CaptureDate)
+	}
+	if a.Latitude != 48.85 || a.Longitude != 2.35 || a.Rating != 4 || !a.Favorite {
+		t.Errorf("metadata absent from the answer changed: %+v", a)
+	}
+	if a.OriginalFileName != "photo.jpg" {
+		t.Errorf("unexpected file name: %s", a.OriginalFileName)
+	}
+	if len(a.Albums) != 2 || a.Albums[0].Description != "summer" || a.Albums[1].Title != "New" {
+		t.Errorf("unexpected albums: %+v", a.Albums)
+	}
+	if len(a.Tags) != 1 || a.Tags[0].Value != "a/b" || a.Tags[0].Name != "b" {
+		t.Errorf("unexpected tags: %+v", a.Tags)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "My Disk")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(dir, "hook")
+	if err := os.WriteFile(exe, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tc := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{command: "hook.sh --verbose", want: []string{"hook.sh", "--verbose"}},
+		{command: `"C:\Program Files\hook.exe" --album 'My holidays'`, want: []string{`C:\Program Files\hook.exe`, "--album", "My holidays"}},
+		{command: `/Volumes/"My Disk"/hook`, want: []string{"/Volumes/My Disk/hook"}},
+		{command: exe, want: []string{exe}},
+		{command: `"unterminated`, wantErr: true},
+		{command: "  ", wantErr: true},
+	}
+	for _, c := range tc {
+		got, err := SplitCommand(c.command)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %q", c.command, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", c.command, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("%q: got %q, want %q", c.command, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package hooks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FailurePolicy tells what to do when a hook fails or times out
+// Implement the interface pflag.Value
+type FailurePolicy int
+
+const (
+	FailureStop     FailurePolicy = iota // the failure is reported as an error
+	FailureContinue                      // the failure is logged, and the asset is processed
+	FailureSkip                          // the failure is logged, and the asset is skipped
+)
+
+func (p FailurePolicy) String() string {
+	switch p {
+	case FailureStop:
+		return "stop"
+	case FailureContinue:
+		return "continue"
+	case FailureSkip:
+		return "skip"
+	}
+	return "unknown"
+}
+
+func (p *FailurePolicy) Set(value string) error {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "stop":
+		*p = FailureStop
+	case "continue":
+		*p = FailureContinue
+	case "skip":
+		*p = FailureSkip
+	default:
+		return fmt.Errorf("invalid value for the hook failure policy: %q, expected stop, continue or skip", value)
+	}
+	return nil
+}
+
+func (p FailurePolicy) Type() string {
+	return "FailurePolicy"
+}
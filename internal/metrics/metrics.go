@@ -0,0 +1,362 @@
+// Package metrics exposes counters, gauges, and histograms in the Prometheus text format.
+// It implements only what immich-go needs, without the Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the latency histograms
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds the metrics
+type Registry struct {
+	lock    sync.Mutex
+	metrics map[string]metric
+}
+
+type metric interface {
+	writeTo(w *bufio.Writer)
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]metric{}}
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.metrics[name]; ok {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.metrics[name] = m
+}
+
+// WriteTo writes the metrics sorted by name
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	r.lock.Unlock()
+	sort.Strings(names)
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, name := range names {
+		r.lock.Lock()
+		m := r.metrics[name]
+		r.lock.Unlock()
+		m.writeTo(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler serves the metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+// desc is the description common to all metrics
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.typ)
+}
+
+// key gives the map key of the label values
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs gives {l1="v1",l2="v2"} with the extra pair appended, or an empty string without labels
+func (d *desc) labelPairs(values []string, extraName, extraValue string) string {
+	if len(d.labels) == 0 && extraName == "" {
+		return ""
+	}
+	sb := strings.Builder{}
+	sb.WriteByte('{')
+	for i, l := range d.labels {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(l)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabel(values[i]))
+		sb.WriteByte('"')
+	}
+	if extraName != "" {
+		if len(d.labels) > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(extraName)
+		sb.WriteString(`="`)
+		sb.WriteString(extraValue)
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpReplacer.Replace(s) }
+func escapeLabel(s string) string { return labelReplacer.Replace(s) }
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// series is a value with its label values
+type series struct {
+	values []string
+	value  float64
+}
+
+// sortedKeys gives the keys of a map in order, for a stable output
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CounterVec is a set of counters distinguished by their label values
+type CounterVec struct {
+	desc
+	lock   sync.Mutex
+	series map[string]*series
+}
+
+// NewCounterVec registers a counter. Without labels, the counter is used with no label values.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name: name, help: help, typ: "counter", labels: labels}, series: map[string]*series{}}
+	r.register(name, c)
+	return c
+}
+
+// Add adds v to the counter of the label values. v must not be negative.
+func (c *CounterVec) Add(v float64, values ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.name + " can't decrease")
+	}
+	k := c.key(values)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s, ok := c.series[k]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		c.series[k] = s
+	}
+	s.value += v
+}
+
+// Inc adds 1 to the counter of the label values
+func (c *CounterVec) Inc(values ...string) { c.Add(1, values...) }
+
+// Value gives the value of the counter of the label values
+func (c *CounterVec) Value(values ...string) float64 {
+	k := c.key(values)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if s, ok := c.series[k]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (c *CounterVec) writeTo(w *bufio.Writer) {
+	c.writeHeader(w)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.labels) == 0 && len(c.series) == 0 {
+		fmt.Fprintf(w, "%s 0\n", c.name)
+		return
+	}
+	for _, k := range sortedKeys(c.series) {
+		s := c.series[k]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(s.values, "", ""), formatFloat(s.value))
+	}
+}
+
+// GaugeVec is a set of gauges distinguished by their label values
+type GaugeVec struct {
+	desc
+	lock   sync.Mutex
+	series map[string]*series
+}
+
+// NewGaugeVec registers a gauge
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{desc: desc{name: name, help: help, typ: "gauge", labels: labels}, series: map[string]*series{}}
+	r.register(name, g)
+	return g
+}
+
+// Add adds v, positive or negative, to the gauge of the label values
+func (g *GaugeVec) Add(v float64, values ...string) {
+	k := g.key(values)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	s, ok := g.series[k]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		g.series[k] = s
+	}
+	s.value += v
+}
+
+// Set sets the gauge of the label values
+func (g *GaugeVec) Set(v float64, values ...string) {
+	k := g.key(values)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.series[k] = &series{values: append([]string(nil), values...), value: v}
+}
+
+// Value gives the value of the gauge of the label values
+func (g *GaugeVec) Value(values ...string) float64 {
+	k := g.key(values)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if s, ok := g.series[k]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (g *GaugeVec) writeTo(w *bufio.Writer) {
+	g.writeHeader(w)
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if len(g.labels) == 0 && len(g.series) == 0 {
+		fmt.Fprintf(w, "%s 0\n", g.name)
+		return
+	}
+	for _, k := range sortedKeys(g.series) {
+		s := g.series[k]
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labelPairs(s.values, "", ""), formatFloat(s.value))
+	}
+}
+
+// HistogramVec is a set of histograms distinguished by their label values
+type HistogramVec struct {
+	desc
+	buckets []float64
+	lock    sync.Mutex
+	series  map[string]*histogram
+}
+
+type histogram struct {
+	values []string
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec registers a histogram with the given upper bounds, DefaultBuckets when nil
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{desc: desc{name: name, help: help, typ: "histogram", labels: labels}, buckets: buckets, series: map[string]*histogram{}}
+	r.register(name, h)
+	return h
+}
+
+// Observe records the value v for the label values
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	k := h.key(values)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	s, ok := h.series[k]
+	if !ok {
+		s = &histogram{values: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// Count gives the number of observations for the label values
+func (h *HistogramVec) Count(values ...string) uint64 {
+	k := h.key(values)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if s, ok := h.series[k]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) writeTo(w *bufio.Writer) {
+	h.writeHeader(w)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, k := range sortedKeys(h.series) {
+		s := h.series[k]
+		var cumul uint64
+		for i, b := range h.buckets {
+			cumul += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(s.values, "le", formatFloat(b)), cumul)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(s.values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(s.values, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(s.values, "", ""), s.count)
+	}
+}
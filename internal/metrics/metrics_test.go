@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	events := r.NewCounterVec("test_events_total", "Events by code", "event")
+	bytes := r.NewCounterVec("test_bytes_total", "Bytes")
+	queue := r.NewGaugeVec("test_queue", "Queue depth")
+	latency := r.NewHistogramVec("test_latency_seconds", "Latency", []float64{0.1, 1}, "endpoint")
+
+	events.Inc("uploaded")
+	events.Inc("uploaded")
+	events.Inc(`a "quoted"\name`)
+	queue.Add(3)
+	queue.Add(-1)
+	latency.Observe(0.05, "upload")
+	latency.Observe(0.5, "upload")
+	latency.Observe(5, "upload")
+
+	sb := strings.Builder{}
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_bytes_total Bytes
+# TYPE test_bytes_total counter
+test_bytes_total 0
+# HELP test_events_total Events by code
+# TYPE test_events_total counter
+test_events_total{event="a \"quoted\"\\name"} 1
+test_events_total{event="uploaded"} 2
+# HELP test_latency_seconds Latency
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{endpoint="upload",le="0.1"} 1
+test_latency_seconds_bucket{endpoint="upload",le="1"} 2
+test_latency_seconds_bucket{endpoint="upload",le="+Inf"} 3
+test_latency_seconds_sum{endpoint="upload"} 5.55
+test_latency_seconds_count{endpoint="upload"} 3
+# HELP test_queue Queue depth
+# TYPE test_queue gauge
+test_queue 2
+`
+	if sb.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", sb.String(), want)
+	}
+	if bytes.Value() != 0 || events.Value("uploaded") != 2 || latency.Count("upload") != 3 {
+		t.Error("unexpected values")
+	}
+}
+
+func TestServe(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "Test").Inc()
+	s, err := Serve("127.0.0.1:0", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close(context.Background()) }()
+
+	resp, err := http.Get("http://" + s.Addr() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != ContentType {
+		t.Errorf("content type: %q", ct)
+	}
+	if !strings.Contains(string(b), "test_total 1\n") {
+		t.Errorf("unexpected body:\n%s", b)
+	}
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Server serves the metrics of a registry on /metrics
+type Server struct {
+	srv *http.Server
+	ln  net.Listener
+}
+
+// Serve listens on addr, like ":9090", and serves the registry in the background
+func Serve(addr string, r *Registry) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Handler())
+	s := &Server{
+		srv: &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		ln:  ln,
+	}
+	go func() {
+		_ = s.srv.Serve(ln)
+	}()
+	return s, nil
+}
+
+// Addr gives the address the server listens on
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops the server, and waits for the requests being served
+func (s *Server) Close(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
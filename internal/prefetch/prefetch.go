@@ -0,0 +1,205 @@
+// Package prefetch prepares the assets ahead of their upload.
+//
+// The prefetcher sits between the adapter's Browse and the upload loop. It reads the groups
+// in advance, and prepares their assets with a pool of workers: the content of zipped files
+// is copied into temporary files, and checksums are computed. The groups are delivered in
+// their original order.
+//
+// The read-ahead is bounded by a number of groups, and by the number of bytes held
+// in temporary files. The consumer must call Release when it is done with a group.
+package prefetch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/simulot/immich-go/internal/assets"
+	"golang.org/x/sync/semaphore"
+)
+
+// PrepareFn prepares an asset. It returns the number of bytes held in a temporary file.
+type PrepareFn func(ctx context.Context, a *assets.Asset) int64
+
+type Prefetcher struct {
+	workers  int   // number of concurrent preparations, 0 disables the prefetcher
+	window   int   // maximum number of groups read in advance
+	maxBytes int64 // maximum number of bytes held in temporary files
+	prepare  PrepareFn
+
+	budget   *semaphore.Weighted
+	lock     sync.Mutex
+	reserved map[*assets.Group]int64 // bytes reserved for each group in the pipe
+}
+
+type job struct {
+	g    *assets.Group
+	done chan struct{}
+}
+
+// New creates a prefetcher. The prefetcher is disabled when workers is 0.
+func New(workers int, window int, maxBytes int64, prepare PrepareFn) *Prefetcher {
+	if window < 1 {
+		window = 1
+	}
+	if maxBytes <= 0 {
+		maxBytes = 1 << 30
+	}
+	return &Prefetcher{
+		workers:  workers,
+		window:   window,
+		maxBytes: maxBytes,
+		prepare:  prepare,
+		budget:   semaphore.NewWeighted(maxBytes),
+		reserved: map[*assets.Group]int64{},
+	}
+}
+
+// Enabled returns true when the prefetcher prepares the assets
+func (p *Prefetcher) Enabled() bool {
+	return p != nil && p.workers > 0
+}
+
+// Run reads the groups from the in channel, prepares their assets, and sends them in the returned channel
+// in the same order.
+func (p *Prefetcher) Run(ctx context.Context, in chan *assets.Group) chan *assets.Group {
+	if !p.Enabled() {
+		return in
+	}
+	out := make(chan *assets.Group)
+	pending := make(chan *job, p.window)
+	work := make(chan *job)
+
+	// workers
+	wg := sync.WaitGroup{}
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range work {
+				p.prepareGroup(ctx, j.g)
+				close(j.done)
+			}
+		}()
+	}
+
+	// reader: reserve the budget in the order of the groups to avoid dead locks
+	go func() {
+		defer func() {
+			close(work)
+			close(pending)
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case g, ok := <-in:
+				if !ok {
+					return
+				}
+				if err := p.reserve(ctx, g); err != nil {
+					return
+				}
+				j := &job{g: g, done: make(chan struct{})}
+				select {
+				case pending <- j:
+				case <-ctx.Done():
+					p.Release(g)
+					return
+				}
+				select {
+				case work <- j:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	// writer: deliver the groups in order
+	go func() {
+		defer func() {
+			wg.Wait()
+			close(out)
+		}()
+		for j := range pending {
+			select {
+			case <-j.done:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case out <- j.g:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// reserve takes the budget for the files of the group before their preparation
+func (p *Prefetcher) reserve(ctx context.Context, g *assets.Group) error {
+	var n int64
+	for _, a := range g.Assets {
+		n += int64(a.FileSize)
+	}
+	n = min(n, p.maxBytes)
+	if n == 0 {
+		return nil
+	}
+	if err := p.budget.Acquire(ctx, n); err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.reserved[g] = n
+	p.lock.Unlock()
+	return nil
+}
+
+// prepareGroup prepares the group's assets, and gives back the budget not used by temporary files
+func (p *Prefetcher) prepareGroup(ctx context.Context, g *assets.Group) {
+	var used int64
+	for _, a := range g.Assets {
+		if ctx.Err() != nil {
+			break
+		}
+		used += p.prepare(ctx, a)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	r := p.reserved[g]
+	if used < r {
+		p.budget.Release(r - used)
+		p.reserved[g] = used
+	}
+}
+
+// Release gives back the budget taken by the group. It must be called once the group's assets are closed.
+func (p *Prefetcher) Release(g *assets.Group) {
+	if !p.Enabled() {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if r, ok := p.reserved[g]; ok {
+		if r > 0 {
+			p.budget.Release(r)
+		}
+		delete(p.reserved, g)
+	}
+}
+
+// HeldBytes returns the number of bytes currently reserved for prepared groups
+func (p *Prefetcher) HeldBytes() int64 {
+	if !p.Enabled() {
+		return 0
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var n int64
+	for _, r := range p.reserved {
+		n += r
+	}
+	return n
+}
@@ -0,0 +1,125 @@
+package prefetch
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/internal/assets"
+)
+
+func makeGroups(n int, size int) chan *assets.Group {
+	in := make(chan *assets.Group)
+	go func() {
+		defer close(in)
+		for i := range n {
+			in <- assets.NewGroup(assets.GroupByNone, &assets.Asset{OriginalFileName: fmt.Sprintf("%03d.jpg", i), FileSize: size})
+		}
+	}()
+	return in
+}
+
+func TestPrefetchOrder(t *testing.T) {
+	var prepared atomic.Int64
+	p := New(4, 8, 0, func(ctx context.Context, a *assets.Asset) int64 {
+		// make the preparation time random enough to mix the completion order
+		time.Sleep(time.Duration(len(a.OriginalFileName)+int(prepared.Add(1)%3)) * time.Millisecond)
+		a.Checksum = "sum-" + a.OriginalFileName
+		return 0
+	})
+
+	i := 0
+	for g := range p.Run(context.Background(), makeGroups(50, 10)) {
+		a := g.Assets[0]
+		if want := fmt.Sprintf("%03d.jpg", i); a.OriginalFileName != want {
+			t.Fatalf("unexpected order: got %s, want %s", a.OriginalFileName, want)
+		}
+		if a.Checksum == "" {
+			t.Fatalf("asset %s not prepared", a.OriginalFileName)
+		}
+		p.Release(g)
+		i++
+	}
+	if i != 50 {
+		t.Errorf("expected 50 groups, got %d", i)
+	}
+	if p.HeldBytes() != 0 {
+		t.Errorf("expected no held bytes, got %d", p.HeldBytes())
+	}
+}
+
+func TestPrefetchBudget(t *testing.T) {
+	const size = 100
+	var held, maxHeld atomic.Int64
+	p := New(4, 20, 3*size, func(ctx context.Context, a *assets.Asset) int64 {
+		h := held.Add(size)
+		for {
+			m := maxHeld.Load()
+			if h <= m || maxHeld.CompareAndSwap(m, h) {
+				break
+			}
+		}
+		return size
+	})
+
+	n := 0
+	for g := range p.Run(context.Background(), makeGroups(20, size)) {
+		time.Sleep(time.Millisecond)
+		held.Add(-size)
+		p.Release(g)
+		n++
+	}
+	if n != 20 {
+		t.Errorf("expected 20 groups, got %d", n)
+	}
+	if maxHeld.Load() > 3*size {
+		t.Errorf("the budget has been exceeded: %d bytes held", maxHeld.Load())
+	}
+}
+
+func TestPrefetchUnusedBudget(t *testing.T) {
+	// files on the local file system don't use the budget
+	p := New(2, 10, 100, func(ctx context.Context, a *assets.Asset) int64 {
+		return 0
+	})
+	var groups []*assets.Group
+	for g := range p.Run(context.Background(), makeGroups(10, 60)) {
+		// don't release the groups: the reservation must have been given back already
+		groups = append(groups, g)
+	}
+	if len(groups) != 10 {
+		t.Errorf("expected 10 groups, got %d", len(groups))
+	}
+}
+
+func TestPrefetchDisabled(t *testing.T) {
+	p := New(0, 10, 0, nil)
+	in := makeGroups(3, 10)
+	if out := p.Run(context.Background(), in); out != in {
+		t.Errorf("a disabled prefetcher must return its input")
+	}
+	for g := range in {
+		p.Release(g)
+	}
+}
+
+func TestPrefetchCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(2, 4, 0, func(ctx context.Context, a *assets.Asset) int64 { return 0 })
+	out := p.Run(ctx, makeGroups(1000, 10))
+	<-out
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the output channel isn't closed after the cancellation")
+	}
+}
@@ -0,0 +1,142 @@
+// Package serverjobs controls the job queues of the Immich server: thumbnails, metadata extraction,
+// video conversion, machine learning...
+package serverjobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// HeavyQueues are the queues using most of the server's resources during an import.
+// The metadata extraction isn't one of them, the other jobs depend on it.
+var HeavyQueues = []immich.JobID{
+	immich.ThumbnailGeneration,
+	immich.VideoConversion,
+	immich.FaceDetection,
+	immich.FacialRecognition,
+	immich.SmartSearch,
+	immich.DuplicateDetection,
+}
+
+// IsIdle returns true when the queue has no job to do
+func IsIdle(j immich.Job) bool {
+	return j.JobCounts.Active+j.JobCounts.Waiting+j.JobCounts.Delayed == 0
+}
+
+// Names returns the sorted names of the queues
+func Names(jobs map[string]immich.Job) []string {
+	names := make([]string, 0, len(jobs))
+	for n := range jobs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check returns an error when a queue is unknown by the server
+func Check(jobs map[string]immich.Job, ids ...immich.JobID) error {
+	var errs error
+	for _, id := range ids {
+		if _, ok := jobs[string(id)]; !ok {
+			errs = errors.Join(errs, fmt.Errorf("unknown job queue %q", id))
+		}
+	}
+	return errs
+}
+
+// Pause pauses the queues that are running.
+// It returns the queues actually paused, to resume them later.
+func Pause(ctx context.Context, client immich.ImmichJobInterface, ids ...immich.JobID) ([]immich.JobID, error) {
+	jobs, err := client.GetJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	paused := []immich.JobID{}
+	for _, id := range ids {
+		j, ok := jobs[string(id)]
+		if !ok || j.QueueStatus.IsPaused {
+			continue
+		}
+		_, err := client.SendJobCommand(ctx, id, immich.Pause, false)
+		if err != nil {
+			return paused, fmt.Errorf("can't pause the job queue %q: %w", id, err)
+		}
+		paused = append(paused, id)
+	}
+	return paused, nil
+}
+
+// Resume resumes the queues
+func Resume(ctx context.Context, client immich.ImmichJobInterface, ids ...immich.JobID) error {
+	var errs error
+	for _, id := range ids {
+		_, err := client.SendJobCommand(ctx, id, immich.Resume, false)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("can't resume the job queue %q: %w", id, err))
+		}
+	}
+	return errs
+}
+
+// ErrTimeout is returned by Wait when the queues are still busy at the end of the timeout
+var ErrTimeout = errors.New("timeout while waiting for the jobs")
+
+// Wait waits until the queues are idle. All queues are watched when ids is empty,
+// except the paused ones.
+// The progress function, when not nil, is called with the busy queues at each check.
+// A timeout of 0 waits without limit.
+func Wait(ctx context.Context, client immich.ImmichJobInterface, interval, timeout time.Duration, progress func(busy map[string]immich.Job), ids ...immich.JobID) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %s, it must be positive", interval)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, ErrTimeout)
+		defer cancel()
+	}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		jobs, err := client.GetJobs(ctx)
+		if err != nil {
+			if cause := context.Cause(ctx); cause != nil {
+				return cause
+			}
+			return err
+		}
+		busy := map[string]immich.Job{}
+		for n, j := range jobs {
+			if len(ids) > 0 && !slices.Contains(ids, immich.JobID(n)) {
+				continue
+			}
+			if IsIdle(j) {
+				continue
+			}
+			if j.QueueStatus.IsPaused {
+				// a paused queue never ends
+				if len(ids) > 0 {
+					return fmt.Errorf("the job queue %q is paused", n)
+				}
+				continue
+			}
+			busy[n] = j
+		}
+		if len(busy) == 0 {
+			return nil
+		}
+		if progress != nil {
+			progress(busy)
+		}
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package serverjobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/simulot/immich-go/immich"
+)
+
+// fakeJobs simulates the job queues: each call to GetJobs completes one active job
+type fakeJobs struct {
+	jobs     map[string]immich.Job
+	commands []string
+}
+
+func (f *fakeJobs) GetJobs(ctx context.Context) (map[string]immich.Job, error) {
+	r := map[string]immich.Job{}
+	for n, j := range f.jobs {
+		r[n] = j
+		if j.JobCounts.Active > 0 && !j.QueueStatus.IsPaused {
+			j.JobCounts.Active--
+			f.jobs[n] = j
+		}
+	}
+	return r, nil
+}
+
+func (f *fakeJobs) SendJobCommand(ctx context.Context, jobID immich.JobID, command immich.JobCommand, force bool) (immich.SendJobCommandResponse, error) {
+	f.commands = append(f.commands, string(command)+" "+string(jobID))
+	j := f.jobs[string(jobID)]
+	j.QueueStatus.IsPaused = command == immich.Pause
+	f.jobs[string(jobID)] = j
+	return immich.SendJobCommandResponse{}, nil
+}
+
+func (f *fakeJobs) CreateJob(ctx context.Context, name immich.JobName) error {
+	return nil
+}
+
+func job(active int, paused bool) immich.Job {
+	var j immich.Job
+	j.JobCounts.Active = active
+	j.QueueStatus.IsPaused = paused
+	return j
+}
+
+func TestPauseResume(t *testing.T) {
+	f := &fakeJobs{jobs: map[string]immich.Job{
+		string(immich.ThumbnailGeneration): job(0, false),
+		string(immich.SmartSearch):         job(0, true),
+	}}
+	paused, err := Pause(context.Background(), f, immich.ThumbnailGeneration, immich.SmartSearch, immich.FaceDetection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paused) != 1 || paused[0] != immich.ThumbnailGeneration {
+		t.Errorf("paused = %v, want only %s", paused, immich.ThumbnailGeneration)
+	}
+	if err := Resume(context.Background(), f, paused...); err != nil {
+		t.Fatal(err)
+	}
+	if !f.jobs[string(immich.SmartSearch)].QueueStatus.IsPaused || f.jobs[string(immich.ThumbnailGeneration)].QueueStatus.IsPaused {
+		t.Errorf("the queues aren't restored: %v", f.commands)
+	}
+}
+
+func TestWait(t *testing.T) {
+	f := &fakeJobs{jobs: map[string]immich.Job{
+		string(immich.MetadataExtraction): job(3, false),
+		string(immich.SmartSearch):        job(5, true),
+	}}
+	calls := 0
+	err := Wait(context.Background(), f, time.Millisecond, time.Second, func(map[string]immich.Job) { calls++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("progress called %d times, want 3", calls)
+	}
+
+	err = Wait(context.Background(), f, time.Millisecond, time.Second, nil, immich.SmartSearch)
+	if err == nil {
+		t.Error("expected an error when waiting for a paused queue")
+	}
+
+	err = Wait(context.Background(), f, 0, time.Second, nil)
+	if err == nil {
+		t.Error("expected an error with a null interval")
+	}
+
+	f.jobs[string(immich.MetadataExtraction)] = job(1000, false)
+	err = Wait(context.Background(), f, 10*time.Millisecond, 30*time.Millisecond, nil, immich.MetadataExtraction)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want %v", err, ErrTimeout)
+	}
+}
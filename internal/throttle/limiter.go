@@ -0,0 +1,113 @@
+// Package throttle limits the upload bandwidth, and restricts the uploads to time windows.
+package throttle
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket shared by all the uploads.
+// A limiter with a rate of 0 doesn't limit anything.
+type Limiter struct {
+	lock   sync.Mutex
+	rate   float64   // bytes per second
+	burst  float64   // maximum number of tokens in the bucket
+	tokens float64   // available tokens, negative when reserved in advance
+	last   time.Time // last refill of the bucket
+}
+
+const minBurst = 32 * 1024
+
+// NewLimiter creates a limiter allowing bytesPerSecond bytes per second.
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	l := &Limiter{}
+	l.SetRate(bytesPerSecond)
+	return l
+}
+
+// SetRate changes the rate of the limiter. 0 removes the limit.
+func (l *Limiter) SetRate(bytesPerSecond int64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.rate = float64(max(bytesPerSecond, 0))
+	// the bucket holds about 1/4 second of data
+	l.burst = max(l.rate/4, minBurst)
+	l.tokens = l.burst
+	l.last = time.Now()
+}
+
+// Rate returns the limit in bytes per second, 0 when unlimited.
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return int64(l.rate)
+}
+
+// WaitN blocks until n bytes can be sent, or the context is canceled.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	if l.rate == 0 {
+		l.lock.Unlock()
+		return nil
+	}
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.burst)
+	l.last = now
+	l.tokens -= float64(n)
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.lock.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Reader returns a reader limited by the limiter.
+func (l *Limiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, l: l}
+}
+
+type reader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *Limiter
+}
+
+func (r *reader) Read(b []byte) (int, error) {
+	if r.l.Rate() == 0 {
+		return r.r.Read(b)
+	}
+	// read small chunks to keep a smooth rate
+	if len(b) > minBurst {
+		b = b[:minBurst]
+	}
+	n, err := r.r.Read(b)
+	if n > 0 {
+		if werr := r.l.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
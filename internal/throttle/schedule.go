@@ -0,0 +1,150 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is a daily period of time given as HH:MM-HH:MM.
+// A window ending before its start runs over midnight.
+type Window struct {
+	Start time.Duration // offset from midnight
+	End   time.Duration // offset from midnight
+}
+
+// ParseWindow parses a window given as HH:MM-HH:MM
+func ParseWindow(s string) (Window, error) {
+	var w Window
+	start, end, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return w, fmt.Errorf("invalid time window: %q, expected HH:MM-HH:MM", s)
+	}
+	var err error
+	if w.Start, err = parseClock(start); err != nil {
+		return w, fmt.Errorf("invalid time window: %q: %w", s, err)
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return w, fmt.Errorf("invalid time window: %q: %w", s, err)
+	}
+	return w, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day: %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("%s-%s", formatClock(w.Start), formatClock(w.End))
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// Contains returns true when the time of day of t is in the window
+func (w Window) Contains(t time.Time) bool {
+	tod := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	switch {
+	case w.Start == w.End:
+		return true
+	case w.Start < w.End:
+		return tod >= w.Start && tod < w.End
+	default:
+		return tod >= w.Start || tod < w.End
+	}
+}
+
+// nextStart returns the next start of the window after t
+func (w Window) nextStart(t time.Time) time.Time {
+	h, m := int(w.Start.Hours()), int(w.Start.Minutes())%60
+	s := time.Date(t.Year(), t.Month(), t.Day(), h, m, 0, 0, t.Location())
+	if !s.After(t) {
+		s = time.Date(t.Year(), t.Month(), t.Day()+1, h, m, 0, 0, t.Location())
+	}
+	return s
+}
+
+// Schedule is a list of windows during which the uploads are allowed.
+// An empty schedule allows the uploads at any time.
+// Implement the interface pflag.Value
+type Schedule []Window
+
+func (s Schedule) String() string {
+	l := make([]string, len(s))
+	for i, w := range s {
+		l[i] = w.String()
+	}
+	return strings.Join(l, ",")
+}
+
+// Set adds the windows given as a comma separated list
+func (s *Schedule) Set(v string) error {
+	for _, p := range strings.Split(v, ",") {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		w, err := ParseWindow(p)
+		if err != nil {
+			return err
+		}
+		*s = append(*s, w)
+	}
+	return nil
+}
+
+func (s Schedule) Type() string {
+	return "windows"
+}
+
+// IsOpen returns true when the uploads are allowed at the time t
+func (s Schedule) IsOpen(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, w := range s {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpening returns the time of the next opening after t
+func (s Schedule) NextOpening(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range s {
+		n := w.nextStart(t)
+		if next.IsZero() || n.Before(next) {
+			next = n
+		}
+	}
+	return next
+}
+
+// Wait blocks until the schedule is open. The onPause function, when not nil,
+// is called with the time of the next opening when the wait begins.
+func (s Schedule) Wait(ctx context.Context, onPause func(until time.Time)) error {
+	for {
+		now := time.Now()
+		if s.IsOpen(now) {
+			return nil
+		}
+		next := s.NextOpening(now)
+		if onPause != nil {
+			onPause(next)
+		}
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
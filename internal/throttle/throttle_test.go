@@ -0,0 +1,118 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLimiterReader(t *testing.T) {
+	const rate = 512 * 1024
+	l := NewLimiter(rate)
+	data := make([]byte, rate/2)
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, l.Reader(context.Background(), bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	if n != int64(len(data)) {
+		t.Errorf("copied %d bytes, want %d", n, len(data))
+	}
+	// the burst gives 1/4 second for free
+	if elapsed < 200*time.Millisecond {
+		t.Errorf("the rate isn't limited: %d bytes read in %s", n, elapsed)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("the reader is too slow: %d bytes read in %s", n, elapsed)
+	}
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	var l *Limiter
+	if err := l.WaitN(context.Background(), 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	l = NewLimiter(0)
+	start := time.Now()
+	if err := l.WaitN(context.Background(), 1<<30); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("an unlimited limiter must not wait")
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := NewLimiter(1024)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.WaitN(ctx, 1<<20); err == nil {
+		t.Error("expected an error when the context is canceled")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	day := func(h, m int) time.Time { return time.Date(2024, 3, 10, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		window string
+		at     time.Time
+		open   bool
+	}{
+		{"01:00-06:00", day(0, 59), false},
+		{"01:00-06:00", day(1, 0), true},
+		{"01:00-06:00", day(5, 59), true},
+		{"01:00-06:00", day(6, 0), false},
+		{"22:30-06:00", day(23, 0), true},
+		{"22:30-06:00", day(3, 0), true},
+		{"22:30-06:00", day(12, 0), false},
+		{"00:00-00:00", day(12, 0), true},
+	}
+	for _, tt := range tests {
+		w, err := ParseWindow(tt.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.String() != tt.window {
+			t.Errorf("String() = %q, want %q", w.String(), tt.window)
+		}
+		if got := w.Contains(tt.at); got != tt.open {
+			t.Errorf("%s contains %s = %v, want %v", tt.window, tt.at.Format("15:04"), got, tt.open)
+		}
+	}
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	for _, s := range []string{"", "01:00", "1h-6h", "25:00-06:00", "01:00-06:61"} {
+		if _, err := ParseWindow(s); err == nil {
+			t.Errorf("ParseWindow(%q) must fail", s)
+		}
+	}
+}
+
+func TestScheduleNextOpening(t *testing.T) {
+	var s Schedule
+	if err := s.Set("01:00-06:00, 13:00-14:00"); err != nil {
+		t.Fatal(err)
+	}
+	if s.String() != "01:00-06:00,13:00-14:00" {
+		t.Errorf("unexpected schedule: %s", s)
+	}
+	at := time.Date(2024, 3, 10, 8, 0, 0, 0, time.UTC)
+	if s.IsOpen(at) {
+		t.Errorf("the schedule must be closed at %s", at)
+	}
+	if got, want := s.NextOpening(at), time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("NextOpening = %s, want %s", got, want)
+	}
+	at = time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC)
+	if got, want := s.NextOpening(at), time.Date(2024, 3, 11, 1, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("NextOpening = %s, want %s", got, want)
+	}
+	if !(Schedule{}).IsOpen(at) {
+		t.Error("an empty schedule must always be open")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 
+	"github.com/simulot/immich-go/app"
 	"github.com/simulot/immich-go/app/cmd"
 )
 
@@ -19,7 +20,7 @@ func main() {
 			err = e
 		}
 		_, _ = fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(app.ExitCode(err))
 	}
 }
 
@@ -0,0 +1,34 @@
+/*
+Package immichgo exposes the immich-go upload engine to other Go programs.
+
+The package gives access to the building blocks used by the immich-go command line:
+
+  - the [Reader] and [AssetWriter] interfaces implemented by the adapters,
+  - the [Asset] and [Group] types flowing through the pipeline,
+  - the [Grouper] pipeline and the [Filter] functions applied to the groups,
+  - an [Uploader] that runs the upload pipeline and reports its progression
+    with callbacks instead of the terminal user interface.
+
+# Compatibility
+
+Only the packages under pkg/ are part of the public API. Everything else
+(app/..., internal/..., adapters/..., immich/...) may change at any time.
+
+The public API follows the semantic versioning of immich-go releases:
+
+  - within a major version, exported identifiers of pkg/... are not removed
+    or renamed, and function signatures are not changed,
+  - new identifiers, new fields in option structures and new event codes
+    can be added in minor versions,
+  - types declared as aliases ([Asset], [Group], ...) can gain fields and methods
+    in minor versions. Don't rely on their exhaustive list of fields,
+  - while immich-go is at major version 0, breaking changes are possible
+    between minor versions. They are listed in the release notes.
+
+# Usage
+
+Create an [Uploader] with the server address and an API key, build a [Reader]
+(for example with [Uploader.FolderReader]), and call [Uploader.Upload].
+See the examples for details.
+*/
+package immichgo
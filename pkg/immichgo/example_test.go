@@ -0,0 +1,88 @@
+package immichgo_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/simulot/immich-go/pkg/immichgo"
+)
+
+// Upload a folder and print the result of each file
+func Example() {
+	ctx := context.Background()
+
+	u, err := immichgo.NewUploader(ctx, immichgo.UploaderOptions{
+		Server: "http://localhost:2283",
+		APIKey: "your-api-key",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer u.Close()
+
+	u.OnEvent = func(ctx context.Context, e immichgo.Event) {
+		switch e.Code {
+		case immichgo.EventUploaded, immichgo.EventServerDuplicate, immichgo.EventServerError:
+			fmt.Println(e.Code, e.File)
+		}
+	}
+
+	options := immichgo.DefaultFolderOptions()
+	options.FolderAsAlbum = "FOLDER"
+	r, err := u.FolderReader(ctx, options, "/home/me/Pictures/2024")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = u.Upload(ctx, r)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("uploaded:", u.Counts()[immichgo.EventUploaded])
+}
+
+// Reject the videos before the upload with a filter
+func ExampleUploaderOptions_filters() {
+	noVideos := func(g *immichgo.Group) *immichgo.Group {
+		for _, a := range append([]*immichgo.Asset{}, g.Assets...) {
+			if a.NameInfo.Type == "video" {
+				g.RemoveAsset(a, "videos are not wanted")
+			}
+		}
+		return g
+	}
+
+	_, err := immichgo.NewUploader(context.Background(), immichgo.UploaderOptions{
+		Server:  "http://localhost:2283",
+		APIKey:  "your-api-key",
+		Filters: []immichgo.Filter{noVideos},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// A minimal reader that gives a list of prepared assets to the uploader
+type sliceReader []*immichgo.Asset
+
+func (r sliceReader) Browse(ctx context.Context) chan *immichgo.Group {
+	c := make(chan *immichgo.Group)
+	go func() {
+		defer close(c)
+		for _, a := range r {
+			select {
+			case c <- immichgo.NewGroup(immichgo.GroupByNone, a):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+// Implement a custom reader
+func ExampleReader() {
+	var r immichgo.Reader = sliceReader{}
+	_ = r
+}
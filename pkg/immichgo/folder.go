@@ -0,0 +1,96 @@
+package immichgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/simulot/immich-go/adapters/folder"
+	"github.com/simulot/immich-go/internal/filenames"
+	"github.com/simulot/immich-go/internal/filters"
+	"github.com/simulot/immich-go/internal/fshelper"
+	"github.com/simulot/immich-go/internal/namematcher"
+)
+
+// FolderOptions gives the parameters of the folder reader.
+type FolderOptions struct {
+	// Recursive explores the folder and all its sub-folders.
+	Recursive bool
+
+	// IntoAlbum is the name of the album where all assets are added.
+	IntoAlbum string
+
+	// FolderAsAlbum creates albums after the folder structure: "" or "NONE", "FOLDER", "PATH".
+	FolderAsAlbum string
+
+	// AlbumPathJoiner is the string used to join folder names when FolderAsAlbum is "PATH".
+	AlbumPathJoiner string
+
+	// Tags are added to all assets. Hierarchy is supported using a / separator.
+	Tags []string
+
+	// FolderAsTags uses the folder structure as tags.
+	FolderAsTags bool
+
+	// SessionTag tags the assets with "{immich-go}/YYYY-MM-DD HH-MM-SS".
+	SessionTag bool
+
+	// DateFromName uses the date found in the file name when the metadata have no date.
+	DateFromName bool
+
+	// BanFiles lists file name patterns to exclude (case-insensitive).
+	BanFiles []string
+
+	// IgnoreSideCarFiles doesn't upload the XMP files with the photos.
+	IgnoreSideCarFiles bool
+}
+
+// DefaultFolderOptions returns the options used by default by the command line.
+func DefaultFolderOptions() FolderOptions {
+	return FolderOptions{
+		Recursive:       true,
+		AlbumPathJoiner: " / ",
+		DateFromName:    true,
+		BanFiles:        append([]string{}, folder.DefaultBannedFiles...),
+	}
+}
+
+// FolderReader returns a reader that browses the given paths. Paths can contain
+// glob patterns, and can be zip files.
+func (u *Uploader) FolderReader(ctx context.Context, options FolderOptions, paths ...string) (Reader, error) {
+	fsyss, err := fshelper.ParsePath(paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(fsyss) == 0 {
+		return nil, errors.New("no file found matching the pattern: " + strings.Join(paths, ","))
+	}
+
+	o := &folder.ImportFolderOptions{
+		UsePathAsAlbumName:     folder.FolderModeNone,
+		AlbumNamePathSeparator: options.AlbumPathJoiner,
+		ImportIntoAlbum:        options.IntoAlbum,
+		Recursive:              options.Recursive,
+		IgnoreSideCarFiles:     options.IgnoreSideCarFiles,
+		SupportedMedia:         u.app.Client().Immich.SupportedMedia(),
+		ManageHEICJPG:          filters.HeicJpgNothing,
+		ManageRawJPG:           filters.RawJPGNothing,
+		ManageBurst:            filters.BurstNothing,
+		Tags:                   options.Tags,
+		FolderAsTags:           options.FolderAsTags,
+		SessionTag:             options.SessionTag,
+		TakeDateFromFilename:   options.DateFromName,
+		TZ:                     u.app.GetTZ(),
+	}
+	if m := strings.ToUpper(options.FolderAsAlbum); m != "" && m != string(folder.FolderModeNone) {
+		if err := o.UsePathAsAlbumName.Set(m); err != nil {
+			return nil, err
+		}
+	}
+	o.BannedFiles, err = namematcher.New(options.BanFiles...)
+	if err != nil {
+		return nil, err
+	}
+	o.InfoCollector = filenames.NewInfoCollector(o.TZ, o.SupportedMedia)
+	return folder.NewLocalFiles(ctx, u.app.Jnl(), o, fsyss...)
+}
@@ -0,0 +1,96 @@
+package immichgo
+
+import (
+	"context"
+
+	"github.com/simulot/immich-go/adapters"
+	"github.com/simulot/immich-go/internal/assets"
+	"github.com/simulot/immich-go/internal/fileevent"
+	"github.com/simulot/immich-go/internal/filters"
+	"github.com/simulot/immich-go/internal/groups"
+)
+
+type (
+	// Reader is implemented by the sources of assets. Browse returns a channel of groups of assets.
+	Reader = adapters.Reader
+
+	// AssetWriter is implemented by the destinations of assets.
+	AssetWriter = adapters.AssetWriter
+
+	// Asset describes a photo or a video, with its file and its metadata.
+	Asset = assets.Asset
+
+	// Group is a set of assets processed together, like a burst or a RAW+JPEG pair.
+	Group = assets.Group
+
+	// GroupBy indicates the reason why assets are grouped.
+	GroupBy = assets.GroupBy
+
+	// Album describes an album to which assets are added.
+	Album = assets.Album
+
+	// Tag describes a tag given to assets.
+	Tag = assets.Tag
+
+	// Metadata holds the metadata collected from a sidecar file, the file itself or an application.
+	Metadata = assets.Metadata
+
+	// Grouper reads assets from the in channel, and emits either single assets in the out channel, or groups in the gOut channel.
+	Grouper = groups.Grouper
+
+	// GrouperPipeline chains groupers.
+	GrouperPipeline = groups.GrouperPipeline
+
+	// Filter removes assets from a group, or changes the group.
+	Filter = filters.Filter
+
+	// EventCode identifies the events recorded during the processing of files.
+	EventCode = fileevent.Code
+
+	// Recorder counts the events and writes them into the log.
+	Recorder = fileevent.Recorder
+)
+
+// Reasons of grouping
+const (
+	GroupByNone    = assets.GroupByNone
+	GroupByBurst   = assets.GroupByBurst
+	GroupByRawJpg  = assets.GroupByRawJpg
+	GroupByHeicJpg = assets.GroupByHeicJpg
+	GroupByOther   = assets.GroupByOther
+)
+
+// Event codes reported to the Uploader's callbacks
+const (
+	EventDiscoveredImage       = fileevent.DiscoveredImage
+	EventDiscoveredVideo       = fileevent.DiscoveredVideo
+	EventDiscoveredSidecar     = fileevent.DiscoveredSidecar
+	EventDiscoveredDiscarded   = fileevent.DiscoveredDiscarded
+	EventDiscoveredUnsupported = fileevent.DiscoveredUnsupported
+	EventLocalDuplicate        = fileevent.AnalysisLocalDuplicate
+	EventNotSelected           = fileevent.UploadNotSelected
+	EventUpgraded              = fileevent.UploadUpgraded
+	EventServerDuplicate       = fileevent.UploadServerDuplicate
+	EventServerBetter          = fileevent.UploadServerBetter
+	EventAddedToAlbum          = fileevent.UploadAddToAlbum
+	EventServerError           = fileevent.UploadServerError
+	EventUploaded              = fileevent.Uploaded
+	EventStacked               = fileevent.Stacked
+	EventTagged                = fileevent.Tagged
+	EventError                 = fileevent.Error
+)
+
+// NewGroup creates a group of assets
+func NewGroup(grouping GroupBy, a ...*Asset) *Group {
+	return assets.NewGroup(grouping, a...)
+}
+
+// NewGrouperPipeline creates a pipeline of groupers
+func NewGrouperPipeline(ctx context.Context, gs ...Grouper) *GrouperPipeline {
+	return groups.NewGrouperPipeline(ctx, gs...)
+}
+
+// ApplyFilters applies the filters to the group, in sequence
+func ApplyFilters(g *Group, fs ...Filter) *Group {
+	return filters.ApplyFilters(g, fs...)
+}
@@ -0,0 +1,141 @@
+package immichgo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/simulot/immich-go/app"
+	"github.com/simulot/immich-go/app/cmd/upload"
+)
+
+// UploaderOptions gives the parameters of the connection to the Immich server.
+type UploaderOptions struct {
+	// Server is the Immich server address (example http://your-ip:2283 or https://your-domain)
+	Server string
+
+	// APIKey is the Immich API key of the user.
+	APIKey string
+
+	// DeviceUUID identifies the device in Immich. Defaults to the host name.
+	DeviceUUID string
+
+	// SkipSSL disables the verification of the server's certificate.
+	SkipSSL bool
+
+	// ClientTimeout is the timeout of server calls. Defaults to 5 minutes.
+	ClientTimeout time.Duration
+
+	// DryRun simulates all actions without changing the server.
+	DryRun bool
+
+	// Logger receives the log messages. Defaults to a logger that discards everything.
+	Logger *slog.Logger
+
+	// Filters are applied to each group of assets before the upload, the single assets included.
+	// The assets they remove are reported with EventDiscoveredDiscarded.
+	Filters []Filter
+}
+
+// Event describes something that happened to a file during the upload.
+type Event struct {
+	Code EventCode // what happened
+	File string    // the file concerned, if any
+	Args []any     // additional information, as key/value pairs
+}
+
+// Uploader uploads assets to an Immich server. It replaces the terminal user interface by callbacks.
+type Uploader struct {
+	// OnEvent is called for each event of the upload. It must return quickly.
+	OnEvent func(ctx context.Context, e Event)
+
+	// OnIndexProgress is called while the server's assets are read.
+	OnIndexProgress func(read, total int)
+
+	options UploaderOptions
+	app     *app.Application
+}
+
+// NewUploader connects to the Immich server and returns an Uploader.
+func NewUploader(ctx context.Context, options UploaderOptions) (*Uploader, error) {
+	if options.Logger == nil {
+		options.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if options.ClientTimeout == 0 {
+		options.ClientTimeout = 5 * time.Minute
+	}
+	if options.DeviceUUID == "" {
+		options.DeviceUUID, _ = os.Hostname()
+	}
+
+	u := &Uploader{
+		options: options,
+		app:     app.NewWithLogger(options.Logger),
+	}
+
+	client := u.app.Client()
+	client.Server = options.Server
+	client.APIKey = options.APIKey
+	client.DeviceUUID = options.DeviceUUID
+	client.SkipSSL = options.SkipSSL
+	client.ClientTimeout = options.ClientTimeout
+	client.DryRun = options.DryRun
+
+	err := client.Initialize(ctx, u.app)
+	if err != nil {
+		return nil, err
+	}
+	err = client.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.app.Jnl().AddListener(func(ctx context.Context, code EventCode, file slog.LogValuer, args ...any) {
+		if u.OnEvent == nil {
+			return
+		}
+		e := Event{Code: code, Args: args}
+		if file != nil {
+			e.File = file.LogValue().String()
+		}
+		u.OnEvent(ctx, e)
+	})
+	return u, nil
+}
+
+// Upload reads the assets from the reader and uploads them to the server.
+// It returns when all assets have been processed.
+func (u *Uploader) Upload(ctx context.Context, r Reader) error {
+	if r == nil {
+		return errors.New("no reader given")
+	}
+	options := &upload.UploadOptions{
+		NoUI:    true,
+		Filters: u.options.Filters,
+	}
+	return upload.Run(ctx, u.app, options, r, u.OnIndexProgress)
+}
+
+// Journal returns the event recorder of the uploader. It must be given to the readers.
+func (u *Uploader) Journal() *Recorder {
+	return u.app.Jnl()
+}
+
+// Counts returns the number of events recorded so far, indexed by EventCode.
+func (u *Uploader) Counts() map[EventCode]int64 {
+	counts := map[EventCode]int64{}
+	for c, v := range u.app.Jnl().GetCounts() {
+		if v != 0 {
+			counts[EventCode(c)] = v
+		}
+	}
+	return counts
+}
+
+// Close releases the resources of the uploader.
+func (u *Uploader) Close() error {
+	return u.app.Client().Close()
+}
@@ -0,0 +1,101 @@
+package immichgo_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/simulot/immich-go/internal/fakeImmich/cmdtest"
+	"github.com/simulot/immich-go/pkg/immichgo"
+)
+
+func TestUploader(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"2024/IMG_20240714_100000.jpg": "first photo",
+		"2024/IMG_20240715_100000.jpg": "second photo",
+		"2024/VID_20240716_100000.mp4": "a video",
+	} {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	noVideos := func(g *immichgo.Group) *immichgo.Group {
+		for _, a := range append([]*immichgo.Asset{}, g.Assets...) {
+			if a.NameInfo.Type == "video" {
+				g.RemoveAsset(a, "videos are not wanted")
+			}
+		}
+		return g
+	}
+
+	s := cmdtest.NewServer(t)
+	ctx := context.Background()
+	u, err := immichgo.NewUploader(ctx, immichgo.UploaderOptions{
+		Server:  s.URL,
+		APIKey:  s.APIKey,
+		Filters: []immichgo.Filter{noVideos},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u.Close()
+
+	var lock sync.Mutex
+	uploaded := []string{}
+	discarded := []string{}
+	u.OnEvent = func(ctx context.Context, e immichgo.Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch e.Code {
+		case immichgo.EventUploaded:
+			uploaded = append(uploaded, filepath.Base(e.File))
+		case immichgo.EventDiscoveredDiscarded:
+			discarded = append(discarded, filepath.Base(e.File))
+		}
+	}
+
+	options := immichgo.DefaultFolderOptions()
+	options.FolderAsAlbum = "FOLDER"
+	r, err := u.FolderReader(ctx, options, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = u.Upload(ctx, r); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := u.Counts()
+	for code, want := range map[immichgo.EventCode]int64{
+		immichgo.EventDiscoveredImage:     2,
+		immichgo.EventDiscoveredVideo:     1,
+		immichgo.EventUploaded:            2,
+		immichgo.EventDiscoveredDiscarded: 1,
+		immichgo.EventAddedToAlbum:        2,
+		immichgo.EventServerError:         0,
+		immichgo.EventError:               0,
+	} {
+		if counts[code] != want {
+			t.Errorf("%s: %d, want %d", code, counts[code], want)
+		}
+	}
+
+	sort.Strings(uploaded)
+	if len(uploaded) != 2 || uploaded[0] != "IMG_20240714_100000.jpg" || uploaded[1] != "IMG_20240715_100000.jpg" {
+		t.Errorf("uploaded events: %v", uploaded)
+	}
+	if len(discarded) != 1 || discarded[0] != "VID_20240716_100000.mp4" {
+		t.Errorf("discarded events: %v", discarded)
+	}
+	if got := len(s.Albums()["2024"]); got != 2 {
+		t.Errorf("the album 2024 has %d assets, want 2", got)
+	}
+}